@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"portfolio-backend/models"
+	"portfolio-backend/scheduler"
+	"time"
+)
+
+// 推移の区切り
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// 推移の区間数の上限 (day で何年分も頼まれないように)
+const maxSeriesPoints = 366
+
+// 得意・苦手に挙げるのに必要なタグごとの投票数と、挙げる数
+const (
+	minTagVotes    = 3
+	maxTagStrength = 3
+)
+
+// 推移の期間と区切り
+type seriesRange struct {
+	Interval string
+	From, To time.Time // [From, To)、From は区間の始まりに揃えてある
+}
+
+// ?interval= / ?from= / ?to= を読む (日付は now のタイムゾーン)
+func parseSeriesRange(q url.Values, now time.Time) (seriesRange, error) {
+	sr := seriesRange{Interval: q.Get("interval")}
+	if sr.Interval == "" {
+		sr.Interval = IntervalWeek
+	}
+	if sr.Interval != IntervalDay && sr.Interval != IntervalWeek && sr.Interval != IntervalMonth {
+		return sr, fmt.Errorf("interval must be day, week or month")
+	}
+
+	last := now
+	if s := q.Get("to"); s != "" {
+		d, err := time.ParseInLocation(scheduler.DateLayout, s, now.Location())
+		if err != nil {
+			return sr, fmt.Errorf("invalid to %q (want YYYY-MM-DD)", s)
+		}
+		last = d
+	}
+	sr.To = sr.next(sr.start(last))
+
+	if s := q.Get("from"); s != "" {
+		d, err := time.ParseInLocation(scheduler.DateLayout, s, now.Location())
+		if err != nil {
+			return sr, fmt.Errorf("invalid from %q (want YYYY-MM-DD)", s)
+		}
+		sr.From = sr.start(d)
+	} else {
+		n := 12
+		if sr.Interval == IntervalDay {
+			n = 30
+		}
+		sr.From = sr.To
+		for i := 0; i < n; i++ {
+			sr.From = sr.prev(sr.From)
+		}
+	}
+
+	if !sr.From.Before(sr.To) {
+		return sr, fmt.Errorf("from must be before to")
+	}
+	if n := len(sr.starts()); n > maxSeriesPoints {
+		return sr, fmt.Errorf("too many points (%d, max %d)", n, maxSeriesPoints)
+	}
+	return sr, nil
+}
+
+// t を含む区間の始まり
+func (sr seriesRange) start(t time.Time) time.Time {
+	if from, _, ok := periodRange(sr.Interval, t); ok {
+		return from
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// 次の区間の始まり
+func (sr seriesRange) next(t time.Time) time.Time {
+	switch sr.Interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7)
+	case IntervalMonth:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// 前の区間の始まり
+func (sr seriesRange) prev(t time.Time) time.Time {
+	switch sr.Interval {
+	case IntervalWeek:
+		return t.AddDate(0, 0, -7)
+	case IntervalMonth:
+		return t.AddDate(0, -1, 0)
+	}
+	return t.AddDate(0, 0, -1)
+}
+
+// 区間の始まりの一覧
+func (sr seriesRange) starts() []time.Time {
+	var starts []time.Time
+	for t := sr.From; t.Before(sr.To) && len(starts) <= maxSeriesPoints; t = sr.next(t) {
+		starts = append(starts, t)
+	}
+	return starts
+}
+
+// 投票を区間ごとにまとめる (投票のない区間も0で返す)
+func (sr seriesRange) points(votedAt []time.Time, rated []ratedVote) []models.AnalyticsPoint {
+	starts := sr.starts()
+	points := make([]models.AnalyticsPoint, len(starts))
+	index := map[time.Time]int{}
+	for i, s := range starts {
+		points[i].Start = s
+		index[s] = i
+	}
+	find := func(t time.Time) (int, bool) {
+		t = t.In(sr.From.Location())
+		if t.Before(sr.From) || !t.Before(sr.To) {
+			return 0, false
+		}
+		i, ok := index[sr.start(t)]
+		return i, ok
+	}
+
+	for _, t := range votedAt {
+		if i, ok := find(t); ok {
+			points[i].Votes++
+		}
+	}
+	deviation := make([]float64, len(points))
+	bias := make([]float64, len(points))
+	for _, v := range rated {
+		if i, ok := find(v.VotedAt); ok {
+			points[i].RatedVotes++
+			d := float64(v.Point) - v.Consensus
+			bias[i] += d
+			deviation[i] += math.Abs(d)
+		}
+	}
+	for i := range points {
+		if n := float64(points[i].RatedVotes); n > 0 {
+			points[i].MeanDeviation = floatPtr(round1(deviation[i] / n))
+			points[i].MeanBias = floatPtr(round1(bias[i] / n))
+		}
+	}
+	return points
+}
+
+// 連続して投票した日数 (votedAt は古い順、日付は today のタイムゾーンで数える)
+func streaks(votedAt []time.Time, today time.Time) models.Streak {
+	var streak models.Streak
+	var last time.Time
+	run := 0
+	for _, t := range votedAt {
+		t = t.In(today.Location())
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		switch {
+		case run > 0 && day.Equal(last):
+			continue
+		case run > 0 && day.Equal(last.AddDate(0, 0, 1)):
+			run++
+		default:
+			run = 1
+		}
+		last = day
+		if run > streak.Longest {
+			streak.Longest = run
+		}
+	}
+	if run == 0 {
+		return streak
+	}
+	streak.LastVoteDate = last.Format(scheduler.DateLayout)
+
+	// 今日まだ投票していなくても、昨日まで続いていれば途切れていない
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	if last.Equal(day) || last.Equal(day.AddDate(0, 0, -1)) {
+		streak.Current = run
+	}
+	return streak
+}
+
+// 得意なタグと苦手なタグ (tags は近さの平均が高い順)
+// 投票が minTagVotes 以上のタグのうち、自分の平均 (overall) より高いものを高い順に、低いものを低い順に挙げる
+func strengthsAndWeaknesses(tags []models.TagPerformance, overall float64) (strengths, weaknesses []string) {
+	strengths, weaknesses = []string{}, []string{}
+	for _, t := range tags {
+		if len(strengths) < maxTagStrength && t.Votes >= minTagVotes && t.Score > overall {
+			strengths = append(strengths, t.Slug)
+		}
+	}
+	for i := len(tags) - 1; i >= 0; i-- {
+		t := tags[i]
+		if len(weaknesses) < maxTagStrength && t.Votes >= minTagVotes && t.Score < overall {
+			weaknesses = append(weaknesses, t.Slug)
+		}
+	}
+	return strengths, weaknesses
+}
+
+func floatPtr(x float64) *float64 { return &x }
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"portfolio-backend/scheduler"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ユーザーの分析を取得 (GET /users/{id}/analytics)
+// 本人か管理者だけが見られる
+// クエリパラメータ:
+//
+//	interval=  推移の区切り day / week (デフォルト) / month
+//	from=      推移の始まりの日 (例: 2026-01-01、デフォルトは day なら30日前、それ以外は12区間前)
+//	to=        推移の終わりの日 (この日を含む、デフォルトは今日)
+func GetUserAnalytics(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	// URLからユーザーIDを抽出 (/users/123/analytics -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, ok := requireUser(w, r)
+	if !ok { return }
+	if viewer.Role != "admin" && int(viewer.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	series, err := parseSeriesRange(r.URL.Query(), time.Now().In(scheduler.Location()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	analytics, err := userAnalytics(user, series)
+	if err != nil {
+		http.Error(w, "Failed to build analytics", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analytics)
+}
+
+// 合意と比べられた投票1件
+type ratedVote struct {
+	VotedAt   time.Time
+	Point     int
+	Consensus float64
+	Agreement float64
+}
+
+func userAnalytics(user models.User, series seriesRange) (models.UserAnalytics, error) {
+	analytics := models.UserAnalytics{
+		UserID:     user.ID,
+		Interval:   series.Interval,
+		From:       series.From,
+		To:         series.To,
+		Tags:       []models.TagPerformance{},
+		Strengths:  []string{},
+		Weaknesses: []string{},
+	}
+
+	// 投票した日時 (推移と連続日数に使う)
+	var votedAt []time.Time
+	err := database.DB.Model(&models.Vote{}).
+		Where("user_id = ?", user.ID).
+		Order("created_at").
+		Pluck("created_at", &votedAt).Error
+	if err != nil {
+		return analytics, err
+	}
+	analytics.TotalVotes = len(votedAt)
+
+	var rated []ratedVote
+	err = database.DB.Model(&models.RatingHistory{}).
+		Select("votes.created_at AS voted_at, rating_histories.point, rating_histories.consensus, rating_histories.agreement").
+		Joins("JOIN votes ON votes.id = rating_histories.vote_id").
+		Where("rating_histories.user_id = ?", user.ID).
+		Scan(&rated).Error
+	if err != nil {
+		return analytics, err
+	}
+	analytics.RatedVotes = len(rated)
+	if len(rated) > 0 {
+		var agreement, deviation, bias float64
+		for _, v := range rated {
+			agreement += v.Agreement
+			deviation += math.Abs(float64(v.Point) - v.Consensus)
+			bias += float64(v.Point) - v.Consensus
+		}
+		n := float64(len(rated))
+		analytics.Score = floatPtr(round1(agreement / n * 100))
+		analytics.MeanDeviation = floatPtr(round1(deviation / n))
+		analytics.MeanBias = floatPtr(round1(bias / n))
+	}
+
+	analytics.Series = series.points(votedAt, rated)
+	analytics.Streak = streaks(votedAt, time.Now().In(scheduler.Location()))
+
+	err = database.DB.Model(&models.RatingHistory{}).
+		Select(`tags.slug, tags.name, COUNT(*) AS votes,
+			AVG(rating_histories.agreement) * 100 AS score,
+			AVG(ABS(rating_histories.point - rating_histories.consensus)) AS mean_deviation,
+			AVG(rating_histories.point - rating_histories.consensus) AS mean_bias`).
+		Joins("JOIN problem_tags ON problem_tags.problem_id = rating_histories.problem_id").
+		Joins("JOIN tags ON tags.id = problem_tags.tag_id").
+		Where("rating_histories.user_id = ?", user.ID).
+		Group("tags.id, tags.slug, tags.name").
+		Order("score DESC, votes DESC, tags.slug").
+		Scan(&analytics.Tags).Error
+	if err != nil {
+		return analytics, err
+	}
+	for i := range analytics.Tags {
+		t := &analytics.Tags[i]
+		t.Score, t.MeanDeviation, t.MeanBias = round1(t.Score), round1(t.MeanDeviation), round1(t.MeanBias)
+	}
+	if analytics.Score != nil {
+		analytics.Strengths, analytics.Weaknesses = strengthsAndWeaknesses(analytics.Tags, *analytics.Score)
+	}
+
+	analytics.Community, err = communityComparison(user, analytics)
+	return analytics, err
+}
+
+// 他のユーザーとの比較
+func communityComparison(user models.User, analytics models.UserAnalytics) (models.CommunityComparison, error) {
+	var c models.CommunityComparison
+	var err error
+	if user.RatedVotes > 0 {
+		c.Rating, err = communityStanding(
+			"SELECT rating AS v FROM users WHERE deleted_at IS NULL AND rated_votes > 0", user.Rating)
+		if err != nil {
+			return c, err
+		}
+	}
+	if analytics.Score != nil {
+		c.Score, err = communityStanding(
+			"SELECT ROUND((AVG(agreement) * 100)::numeric, 1)::float8 AS v FROM rating_histories GROUP BY user_id", *analytics.Score)
+		if err != nil {
+			return c, err
+		}
+		c.Bias, err = communityStanding(
+			"SELECT ROUND(AVG(point - consensus)::numeric, 1)::float8 AS v FROM rating_histories GROUP BY user_id", *analytics.MeanBias)
+		if err != nil {
+			return c, err
+		}
+	}
+	if analytics.TotalVotes > 0 {
+		c.Votes, err = communityStanding(
+			"SELECT COUNT(*)::float8 AS v FROM votes WHERE deleted_at IS NULL AND user_id IS NOT NULL GROUP BY user_id", float64(analytics.TotalVotes))
+	}
+	return c, err
+}
+
+// values (1人1行の値を v として返す SQL) の中で value がどのあたりか
+// 自分の値は表示と同じ丸め方にしてあるので、比べる側も SQL で丸めておく
+func communityStanding(values string, value float64) (*models.CommunityStanding, error) {
+	var row struct {
+		Users        int
+		Mean         float64
+		Below, Equal int
+	}
+	err := database.DB.Raw(`SELECT COUNT(*) AS users, COALESCE(AVG(v), 0)::float8 AS mean,
+		COUNT(*) FILTER (WHERE v < ?) AS below, COUNT(*) FILTER (WHERE v = ?) AS equal
+		FROM (`+values+`) community`, value, value).Scan(&row).Error
+	if err != nil || row.Users == 0 {
+		return nil, err
+	}
+	return &models.CommunityStanding{
+		Value:      value,
+		Mean:       round1(row.Mean),
+		Percentile: round1((float64(row.Below) + float64(row.Equal)/2) / float64(row.Users) * 100),
+		Users:      row.Users,
+	}, nil
+}
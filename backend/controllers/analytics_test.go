@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"net/url"
+	"portfolio-backend/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSeriesRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 1, 7, 15, 0, 0, 0, jst) // 水曜日
+
+	sr, err := parseSeriesRange(url.Values{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 今週 (1/5 の週) までの12週
+	if want := time.Date(2026, 1, 12, 0, 0, 0, 0, jst); !sr.To.Equal(want) {
+		t.Errorf("To = %v, want %v", sr.To, want)
+	}
+	if n := len(sr.starts()); n != 12 {
+		t.Errorf("points = %d, want 12", n)
+	}
+
+	sr, err = parseSeriesRange(url.Values{"interval": {"month"}, "from": {"2025-11-20"}, "to": {"2026-01-03"}}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		time.Date(2025, 11, 1, 0, 0, 0, 0, jst),
+		time.Date(2025, 12, 1, 0, 0, 0, 0, jst),
+		time.Date(2026, 1, 1, 0, 0, 0, 0, jst),
+	}
+	if got := sr.starts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("starts = %v, want %v", got, want)
+	}
+
+	for _, q := range []url.Values{
+		{"interval": {"year"}},
+		{"from": {"2026-02-01"}, "to": {"2026-01-01"}},
+		{"interval": {"day"}, "from": {"2020-01-01"}},
+	} {
+		if _, err := parseSeriesRange(q, now); err == nil {
+			t.Errorf("parseSeriesRange(%v) should fail", q)
+		}
+	}
+}
+
+func TestSeriesPoints(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+	sr := seriesRange{Interval: IntervalDay, From: day(1, 0), To: day(4, 0)}
+
+	points := sr.points(
+		[]time.Time{day(1, 9), day(1, 20), day(3, 8), day(5, 0)}, // 1/5 は範囲外
+		[]ratedVote{
+			{VotedAt: day(1, 9), Point: 60, Consensus: 50},
+			{VotedAt: day(1, 20), Point: 30, Consensus: 50},
+		},
+	)
+	if len(points) != 3 {
+		t.Fatalf("points = %d, want 3", len(points))
+	}
+	if points[0].Votes != 2 || points[1].Votes != 0 || points[2].Votes != 1 {
+		t.Errorf("votes = %d, %d, %d; want 2, 0, 1", points[0].Votes, points[1].Votes, points[2].Votes)
+	}
+	if p := points[0]; p.RatedVotes != 2 || *p.MeanDeviation != 15 || *p.MeanBias != -5 {
+		t.Errorf("day 1 = %+v", p)
+	}
+	if points[1].MeanDeviation != nil {
+		t.Error("a day without rated votes should have no deviation")
+	}
+}
+
+func TestStreaks(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 1, d, h, 0, 0, 0, time.UTC) }
+	votes := []time.Time{day(1, 9), day(2, 9), day(2, 21), day(3, 9), day(6, 9), day(7, 9)}
+
+	tests := []struct {
+		today time.Time
+		want  models.Streak
+	}{
+		{day(7, 22), models.Streak{Current: 2, Longest: 3, LastVoteDate: "2026-01-07"}},
+		{day(8, 22), models.Streak{Current: 2, Longest: 3, LastVoteDate: "2026-01-07"}}, // 昨日までは続いている
+		{day(9, 1), models.Streak{Current: 0, Longest: 3, LastVoteDate: "2026-01-07"}},
+	}
+	for _, tt := range tests {
+		if got := streaks(votes, tt.today); got != tt.want {
+			t.Errorf("streaks(today %v) = %+v, want %+v", tt.today, got, tt.want)
+		}
+	}
+	if got := streaks(nil, day(1, 0)); got != (models.Streak{}) {
+		t.Errorf("streaks(nil) = %+v", got)
+	}
+}
+
+func TestStrengthsAndWeaknesses(t *testing.T) {
+	tags := []models.TagPerformance{
+		{Slug: "defense", Votes: 10, Score: 80},
+		{Slug: "honitsu", Votes: 2, Score: 75}, // 投票が少ないので挙げない
+		{Slug: "dealer", Votes: 5, Score: 62},
+		{Slug: "efficiency", Votes: 8, Score: 55},
+		{Slug: "value", Votes: 4, Score: 40},
+	}
+	strengths, weaknesses := strengthsAndWeaknesses(tags, 60)
+	if !reflect.DeepEqual(strengths, []string{"defense", "dealer"}) {
+		t.Errorf("strengths = %v", strengths)
+	}
+	if !reflect.DeepEqual(weaknesses, []string{"value", "efficiency"}) {
+		t.Errorf("weaknesses = %v", weaknesses)
+	}
+}
@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 監査ログのアクション名
+const (
+	AuditLoginSuccess   = "login.success"
+	AuditLoginFailure   = "login.failure"
+	AuditUserSignup     = "user.signup"
+	AuditUserDelete     = "user.delete"
+	AuditUserRestore    = "user.restore"
+	AuditUserStatus     = "user.status"
+	AuditUserRole       = "user.role"
+	AuditUserRank       = "user.rank"
+	AuditProblemCreate  = "problem.create"
+	AuditProblemUpdate  = "problem.update"
+	AuditProblemStatus  = "problem.status"
+	AuditProblemTags    = "problem.tags"
+	AuditProblemRetag   = "problem.retag"
+	AuditProblemMerge   = "problem.merge"
+	AuditProblemEval    = "problem.evaluate"
+	AuditCollection     = "collection.update"
+	AuditDailyQueue     = "daily.queue"
+	AuditDailyPublish   = "daily.publish"
+	AuditProblemDelete  = "problem.delete"
+	AuditProblemPurge   = "problem.purge"
+	AuditProblemRestore = "problem.restore"
+	AuditRatingRecalc   = "rating.recompute"
+	AuditCommentDelete  = "comment.delete"
+	AuditCommentHide    = "comment.hide"
+)
+
+// 監査ログに載せる対象
+type auditTarget struct {
+	Type string
+	ID   uint
+}
+
+func userTarget(id uint) auditTarget    { return auditTarget{Type: "user", ID: id} }
+func problemTarget(id uint) auditTarget { return auditTarget{Type: "problem", ID: id} }
+
+// 監査ログに残すユーザー情報 (パスワードは絶対に残さない)
+func userSnapshot(u models.User) models.User {
+	u.Password = ""
+	return u
+}
+
+// 監査ログを1件追記する
+// 記録に失敗しても本来の処理は止めない (サーバーログに出すだけ)
+func recordAudit(r *http.Request, actor *models.User, action string, target auditTarget, before, after interface{}, note string) {
+	entry := models.AuditLog{
+		Action:     action,
+		TargetType: target.Type,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		Note:       note,
+	}
+	if actor != nil {
+		entry.ActorID = &actor.ID
+	}
+	if target.ID != 0 {
+		id := target.ID
+		entry.TargetID = &id
+	}
+	if before != nil {
+		entry.Before, _ = json.Marshal(before)
+	}
+	if after != nil {
+		entry.After, _ = json.Marshal(after)
+	}
+
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Printf("Failed to write audit log (%s): %v", action, err)
+	}
+}
+
+// 接続元IP
+// 普段は接続してきた相手 (RemoteAddr)。X-Forwarded-For はクライアントが自由に書けるので、
+// 相手が TRUSTED_PROXIES (カンマ区切りのIPかCIDR) に入っているときだけ、右から見て最初の信頼できないIPを使う
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	trusted := trustedProxies()
+	if !isTrustedProxy(host, trusted) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if net.ParseIP(hop) == nil {
+			// 壊れた値より左は信用できない
+			return host
+		}
+		if !isTrustedProxy(hop, trusted) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+// 環境変数 TRUSTED_PROXIES の一覧 (IP は /32, /128 のネットワークにする)
+func trustedProxies() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, s := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+		if _, n, err := net.ParseCIDR(s); err == nil {
+			nets = append(nets, n)
+		}
+	}
+	return nets
+}
+
+func isTrustedProxy(host string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// 監査ログを検索 (GET /admin/audit-logs)
+// クエリパラメータ:
+//
+//	actor_id=     操作したユーザー
+//	action=       アクション名 (例: user.delete)。"user." のように . で終われば前方一致
+//	target_type=  対象の種類 (user / problem)
+//	target_id=    対象のID
+//	from= / to=   期間 (RFC3339)
+//	limit= / cursor=  ページネーション (新しい順)
+func GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	query := database.DB.Model(&models.AuditLog{})
+
+	if v := q.Get("actor_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid actor_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("actor_id = ?", id)
+	}
+	if action := q.Get("action"); strings.HasSuffix(action, ".") {
+		query = query.Where("starts_with(action, ?)", action)
+	} else if action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if v := q.Get("target_type"); v != "" {
+		query = query.Where("target_type = ?", v)
+	}
+	if v := q.Get("target_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "Invalid target_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("target_id = ?", id)
+	}
+	for param, cond := range map[string]string{"from": "created_at >= ?", "to": "created_at < ?"} {
+		if v := q.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				http.Error(w, "Invalid "+param+" (RFC3339)", http.StatusBadRequest)
+				return
+			}
+			query = query.Where(cond, t)
+		}
+	}
+
+	// IDは単調増加なので、そのままカーソルに使う
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+
+	limit := parseLimit(r, 100, 500)
+	var logs []models.AuditLog
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&logs).Error; err != nil {
+		http.Error(w, "Failed to fetch audit logs", http.StatusInternalServerError)
+		return
+	}
+
+	nextCursor := ""
+	if len(logs) > limit {
+		logs = logs[:limit]
+		nextCursor = encodeCursor("", logs[limit-1].ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setNextCursor(w, nextCursor)
+	json.NewEncoder(w).Encode(logs)
+}
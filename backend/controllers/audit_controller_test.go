@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		trusted   string
+		remote    string
+		forwarded string
+		want      string
+	}{
+		{"no proxy", "", "203.0.113.5:1234", "", "203.0.113.5"},
+		{"forged header without trusted proxies", "", "203.0.113.5:1234", "1.2.3.4", "203.0.113.5"},
+		{"untrusted remote ignores the header", "10.0.0.0/8", "203.0.113.5:1234", "1.2.3.4", "203.0.113.5"},
+		{"trusted proxy uses the right-most hop", "10.0.0.0/8", "10.0.0.2:1234", "1.2.3.4, 198.51.100.7", "198.51.100.7"},
+		{"skips trusted hops", "10.0.0.0/8", "10.0.0.2:1234", "1.2.3.4, 198.51.100.7, 10.0.0.9", "198.51.100.7"},
+		{"single trusted ip", "10.0.0.2", "10.0.0.2:1234", "198.51.100.7", "198.51.100.7"},
+		{"ipv6 remote", "", "[2001:db8::1]:443", "", "2001:db8::1"},
+		{"ipv6 proxy", "2001:db8::/32", "[2001:db8::1]:443", "2001:db8:ffff::1, 2400:cb00::5", "2400:cb00::5"},
+		{"garbage hop", "10.0.0.0/8", "10.0.0.2:1234", "1.2.3.4, nonsense", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trusted)
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"portfolio-backend/database"
 	"portfolio-backend/models"
+	"time"
 )
 
 // ユーザー登録 (Sign Up)
@@ -21,10 +22,20 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// デフォルトは "user" 権限にする
-	if user.Role == "" {
-		user.Role = "user"
-	}
+	// 登録時は必ず "user" 権限 (管理者にできるのは SetUserRole だけ)
+	user.Role = "user"
+	// 停止状態などはリクエストから受け付けない
+	user.Status = models.UserStatusActive
+	user.StatusReason = ""
+	user.StatusUntil = nil
+	// 段位 (SetUserRank で登録する) やレーティングも受け付けない (初期値から始める)
+	user.RankPlatform = ""
+	user.Rank = ""
+	user.RankVerified = false
+	user.Cohort = ""
+	user.Rating = 0
+	user.RatingDeviation = 0
+	user.RatedVotes = 0
 
 	// DBに保存
 	result := database.DB.Create(&user)
@@ -33,6 +44,8 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordAudit(r, &user, AuditUserSignup, userTarget(user.ID), nil, userSnapshot(user), "")
+
 	// パスワードを隠して返す
 	user.Password = ""
 	json.NewEncoder(w).Encode(user)
@@ -54,17 +67,38 @@ func Login(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	// メールアドレスで検索
 	if err := database.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
+		recordAudit(r, nil, AuditLoginFailure, auditTarget{}, nil, map[string]string{"email": input.Email}, "user not found")
 		http.Error(w, "User not found", http.StatusUnauthorized)
 		return
 	}
 
 	// パスワード照合 (今回は平文比較。実務ではbcrypt等を使う)
 	if user.Password != input.Password {
+		recordAudit(r, nil, AuditLoginFailure, userTarget(user.ID), nil, nil, "invalid password")
 		http.Error(w, "Invalid password", http.StatusUnauthorized)
 		return
 	}
 
-	// 成功したらユーザー情報を返す (パスワードは消す)
+	// 停止中・BANされたユーザーはログインさせない
+	if !user.IsActive(time.Now()) {
+		msg := "Account " + user.Status
+		if user.StatusUntil != nil {
+			msg += " until " + user.StatusUntil.Format(time.RFC3339)
+		}
+		if user.StatusReason != "" {
+			msg += ": " + user.StatusReason
+		}
+		recordAudit(r, nil, AuditLoginFailure, userTarget(user.ID), nil, nil, "account "+user.Status)
+		http.Error(w, msg, http.StatusForbidden)
+		return
+	}
+
+	recordAudit(r, &user, AuditLoginSuccess, userTarget(user.ID), nil, nil, "")
+
+	// 成功したらユーザー情報とAPIトークンを返す (パスワードは消す)
 	user.Password = ""
-	json.NewEncoder(w).Encode(user)
+	json.NewEncoder(w).Encode(models.LoginResponse{
+		User:  user,
+		Token: issueToken(user.ID),
+	})
 }
\ No newline at end of file
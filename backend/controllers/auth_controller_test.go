@@ -2,12 +2,14 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"portfolio-backend/database"
 	"portfolio-backend/models"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestSignup tests the Signup controller
@@ -90,3 +92,50 @@ func TestGetAllProblems(t *testing.T) {
 		t.Error("Expected at least 0 problems")
 	}
 }
+
+// 登録時に role を送っても管理者にはなれない
+func TestSignupIgnoresRole(t *testing.T) {
+	database.Connect()
+
+	email := fmt.Sprintf("signup-role-%d@example.com", time.Now().UnixNano())
+	body := fmt.Sprintf(`{"email":%q,"password":"password123","name":"x","role":"admin","status":"banned"}`, email)
+	req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	Signup(w, req)
+
+	var user models.User
+	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		t.Fatalf("user not created: %v (status %d)", err, w.Code)
+	}
+	defer database.DB.Unscoped().Delete(&user)
+	if user.Role != "user" || user.Status != models.UserStatusActive {
+		t.Errorf("role = %q, status = %q; want user, active", user.Role, user.Status)
+	}
+}
+
+// 停止中のユーザーのトークンは受け付けない
+func TestRequireUserRejectsSuspended(t *testing.T) {
+	database.Connect()
+
+	until := time.Now().Add(time.Hour)
+	user := models.User{
+		Email:       fmt.Sprintf("suspended-%d@example.com", time.Now().UnixNano()),
+		Role:        "admin",
+		Status:      models.UserStatusSuspended,
+		StatusUntil: &until,
+	}
+	if err := database.DB.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer database.DB.Unscoped().Delete(&user)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer "+issueToken(user.ID))
+	w := httptest.NewRecorder()
+	if _, ok := requireUser(w, req); ok || w.Code != http.StatusUnauthorized {
+		t.Errorf("requireUser: ok = %v, status = %d; want false, 401", ok, w.Code)
+	}
+	if isAdmin(req) {
+		t.Error("a suspended admin should not be treated as an admin")
+	}
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ログイントークンの有効期間
+const tokenTTL = 7 * 24 * time.Hour
+
+var (
+	secretOnce sync.Once
+	secretKey  []byte
+)
+
+// トークン署名用の鍵 (環境変数 JWT_SECRET)
+// 未設定の場合は起動ごとにランダムな鍵を作る (再起動するとログインし直しになる)
+func tokenSecret() []byte {
+	secretOnce.Do(func() {
+		if s := os.Getenv("JWT_SECRET"); s != "" {
+			secretKey = []byte(s)
+			return
+		}
+		log.Println("Note: JWT_SECRET is not set, using a random secret")
+		secretKey = make([]byte, 32)
+		rand.Read(secretKey)
+	})
+	return secretKey
+}
+
+func signToken(payload string) string {
+	mac := hmac.New(sha256.New, tokenSecret())
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ユーザーIDと有効期限に署名したトークンを発行する
+// 形式: "<user_id>.<expires_unix>.<signature>"
+func issueToken(userID uint) string {
+	payload := fmt.Sprintf("%d.%d", userID, time.Now().Add(tokenTTL).Unix())
+	return payload + "." + signToken(payload)
+}
+
+// トークンを検証してユーザーIDを取り出す
+func parseToken(token string) (uint, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, errors.New("malformed token")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(signToken(payload)), []byte(parts[2])) {
+		return 0, errors.New("invalid signature")
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return 0, errors.New("token expired")
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, errors.New("malformed token")
+	}
+	return uint(userID), nil
+}
+
+var errNotLoggedIn = errors.New("not logged in")
+
+// Authorization: Bearer <token> からログイン中のユーザーを取り出す
+// 削除済み・停止中のユーザーはログインしていない扱いにする
+func currentUser(r *http.Request) (*models.User, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return nil, errNotLoggedIn
+	}
+	userID, err := parseToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, errNotLoggedIn
+	}
+	if !user.IsActive(time.Now()) {
+		return nil, errors.New("account is " + user.Status)
+	}
+	return &user, nil
+}
+
+// ログイン必須のAPI用: 未ログインなら 401 を返して false
+func requireUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := currentUser(r)
+	if err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	return user, true
+}
+
+// 管理者専用のAPI用: 管理者でなければ 401/403 を返して false
+func requireAdmin(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return nil, false
+	}
+	if user.Role != "admin" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// 管理者としてログインしているか (未ログインでも使えるAPIで表示を切り替える用)
+func isAdmin(r *http.Request) bool {
+	user, err := currentUser(r)
+	return err == nil && user.Role == "admin"
+}
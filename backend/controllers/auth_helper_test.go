@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"fmt"
+	"portfolio-backend/models"
+	"testing"
+	"time"
+)
+
+func TestParseToken(t *testing.T) {
+	token := issueToken(42)
+	if id, err := parseToken(token); err != nil || id != 42 {
+		t.Fatalf("parseToken(issueToken(42)) = %d, %v", id, err)
+	}
+
+	expired := fmt.Sprintf("42.%d", time.Now().Add(-time.Minute).Unix())
+	forged := fmt.Sprintf("1.%d", time.Now().Add(time.Hour).Unix())
+	tests := map[string]string{
+		"malformed":     "42",
+		"tampered user": "1" + token[2:],
+		"expired":       expired + "." + signToken(expired),
+		"bad signature": forged + "." + signToken(forged+"x"),
+	}
+	for name, tok := range tests {
+		if _, err := parseToken(tok); err == nil {
+			t.Errorf("%s: parseToken should fail", name)
+		}
+	}
+}
+
+func TestUserIsActive(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name string
+		user models.User
+		want bool
+	}{
+		{"no status", models.User{}, true},
+		{"active", models.User{Status: models.UserStatusActive}, true},
+		{"suspended", models.User{Status: models.UserStatusSuspended, StatusUntil: &future}, false},
+		{"suspension over", models.User{Status: models.UserStatusSuspended, StatusUntil: &past}, true},
+		{"suspended without end", models.User{Status: models.UserStatusSuspended}, false},
+		{"banned", models.User{Status: models.UserStatusBanned}, false},
+		{"ban with end", models.User{Status: models.UserStatusBanned, StatusUntil: &past}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.user.IsActive(now); got != tt.want {
+			t.Errorf("%s: IsActive = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 問題集の一覧 (GET /collections) と作成 (POST /collections)
+func Collections(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		// 問題数は中身の一覧 (collectionItems) と同じく、一般ユーザーには公開中の問題だけを数える
+		query := database.DB.Model(&models.Collection{}).
+			Select("collections.*, COUNT(problems.id) AS problem_count").
+			Joins("LEFT JOIN collection_items ON collection_items.collection_id = collections.id")
+		problemJoin := "LEFT JOIN problems ON problems.id = collection_items.problem_id AND problems.deleted_at IS NULL"
+		if isAdmin(r) {
+			query = query.Joins(problemJoin)
+		} else {
+			query = joinVisibleProblems(query, problemJoin)
+		}
+
+		var collections []models.CollectionSummary
+		err := query.
+			Group("collections.id").
+			Order("collections.id").
+			Find(&collections).Error
+		if err != nil {
+			http.Error(w, "Failed to fetch collections", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(collections)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var collection models.Collection
+	if err := json.NewDecoder(r.Body).Decode(&collection); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(collection.Title) == "" {
+		http.Error(w, "title is required", http.StatusBadRequest)
+		return
+	}
+	if collection.Slug == "" {
+		collection.Slug = slugify(collection.Title)
+	}
+	collection.Slug = slugify(collection.Slug)
+	// 中身は PUT /collections/{id}/items で並び順つきで入れる
+	collection.Items = nil
+
+	if err := database.DB.Create(&collection).Error; err != nil {
+		http.Error(w, "Failed to create collection (slug already exists?)", http.StatusConflict)
+		return
+	}
+	recordAudit(r, admin, AuditCollection, collectionTarget(collection.ID), nil, collection, "create")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(collection)
+}
+
+// 問題集の個別操作
+// GET    /collections/{id}           問題集と中の問題 (並び順どおり)
+// PUT    /collections/{id}           タイトル・説明の変更
+// DELETE /collections/{id}           削除
+// PUT    /collections/{id}/items     中身の入れ替え 例: {"problem_ids": [3, 1, 2]}
+// GET    /collections/{id}/progress  ログイン中のユーザーの進み具合
+func CollectionDetail(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	// /collections/1/items -> [ "", "collections", "1", "items" ]
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	sub := ""
+	if len(pathParts) > 3 {
+		sub = pathParts[3]
+	}
+
+	var collection models.Collection
+	if err := database.DB.First(&collection, id).Error; err != nil {
+		http.Error(w, "Collection not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && sub == "":
+		getCollection(w, r, &collection)
+	case r.Method == http.MethodGet && sub == "progress":
+		getCollectionProgress(w, r, &collection)
+	case r.Method == http.MethodPut && sub == "":
+		updateCollection(w, r, &collection)
+	case r.Method == http.MethodPut && sub == "items":
+		setCollectionItems(w, r, &collection)
+	case r.Method == http.MethodDelete && sub == "":
+		admin, ok := requireAdmin(w, r)
+		if !ok {
+			return
+		}
+		if err := database.DB.Delete(&collection).Error; err != nil {
+			http.Error(w, "Failed to delete collection", http.StatusInternalServerError)
+			return
+		}
+		recordAudit(r, admin, AuditCollection, collectionTarget(collection.ID), collection, nil, "delete")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Deleted"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func collectionTarget(id uint) auditTarget { return auditTarget{Type: "collection", ID: id} }
+
+// 問題集の中身 (一般ユーザーには公開中の問題だけ)
+func collectionItems(r *http.Request, collectionID uint) ([]models.CollectionItem, error) {
+	query := database.DB.Model(&models.CollectionItem{}).
+		Joins("JOIN problems ON problems.id = collection_items.problem_id AND problems.deleted_at IS NULL").
+		Where("collection_items.collection_id = ?", collectionID)
+	if !isAdmin(r) {
+		query = visibleProblems(query)
+	}
+
+	var items []models.CollectionItem
+	err := query.Preload("Problem").Order("collection_items.position").Find(&items).Error
+	return items, err
+}
+
+func getCollection(w http.ResponseWriter, r *http.Request, collection *models.Collection) {
+	items, err := collectionItems(r, collection.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch collection", http.StatusInternalServerError)
+		return
+	}
+	collection.Items = items
+	json.NewEncoder(w).Encode(collection)
+}
+
+func updateCollection(w http.ResponseWriter, r *http.Request, collection *models.Collection) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		Title       *string `json:"title"`
+		Description *string `json:"description"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	before := *collection
+	if input.Title != nil && strings.TrimSpace(*input.Title) != "" {
+		collection.Title = *input.Title
+	}
+	if input.Description != nil {
+		collection.Description = *input.Description
+	}
+	if err := database.DB.Model(collection).Select("Title", "Description").Updates(collection).Error; err != nil {
+		http.Error(w, "Failed to update collection", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditCollection, collectionTarget(collection.ID), before, collection, "update")
+
+	json.NewEncoder(w).Encode(collection)
+}
+
+// 中身を並び順ごと入れ替える (送った順に 1, 2, 3... と番号を振る)
+func setCollectionItems(w http.ResponseWriter, r *http.Request, collection *models.Collection) {
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	var input struct {
+		ProblemIDs []uint `json:"problem_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := numberCollectionItems(collection.ID, input.ProblemIDs)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var found int64
+	database.DB.Model(&models.Problem{}).Where("id IN ?", input.ProblemIDs).Count(&found)
+	if int(found) != len(input.ProblemIDs) {
+		http.Error(w, "Some problems were not found", http.StatusBadRequest)
+		return
+	}
+
+	var before []models.CollectionItem
+	database.DB.Where("collection_id = ?", collection.ID).Order("position").Find(&before)
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("collection_id = ?", collection.ID).Delete(&models.CollectionItem{}).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			if err := tx.Omit("Problem").Create(&items[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Failed to update collection items", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditCollection, collectionTarget(collection.ID), before, input.ProblemIDs, "items")
+
+	getCollection(w, r, collection)
+}
+
+// 送られた順に 1, 2, 3... と番号を振った中身 (同じ問題が2回あればエラー)
+func numberCollectionItems(collectionID uint, problemIDs []uint) ([]models.CollectionItem, error) {
+	seen := map[uint]bool{}
+	items := make([]models.CollectionItem, 0, len(problemIDs))
+	for i, id := range problemIDs {
+		if seen[id] {
+			return nil, errors.New("Duplicate problem_id " + strconv.Itoa(int(id)))
+		}
+		seen[id] = true
+		items = append(items, models.CollectionItem{CollectionID: collectionID, ProblemID: id, Position: i + 1})
+	}
+	return items, nil
+}
+
+// ログイン中のユーザーの問題集の進み具合 (どの問題に投票済みか)
+func getCollectionProgress(w http.ResponseWriter, r *http.Request, collection *models.Collection) {
+	user, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := collectionItems(r, collection.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch collection", http.StatusInternalServerError)
+		return
+	}
+
+	// 問題ごとの最新の投票
+	problemIDs := make([]uint, len(items))
+	for i, item := range items {
+		problemIDs[i] = item.ProblemID
+	}
+	var votes []models.Vote
+	database.DB.Where("user_id = ? AND problem_id IN ?", user.ID, problemIDs).Order("created_at").Find(&votes)
+
+	json.NewEncoder(w).Encode(collectionProgress(collection.ID, items, votes))
+}
+
+// 問題集の中身 (並び順どおり) と自分の投票 (古い順) から進み具合を作る
+// 同じ問題に複数の投票があれば最新のものを使う
+func collectionProgress(collectionID uint, items []models.CollectionItem, votes []models.Vote) models.CollectionProgress {
+	latest := map[uint]models.Vote{}
+	for _, v := range votes {
+		latest[v.ProblemID] = v
+	}
+
+	progress := models.CollectionProgress{
+		CollectionID: collectionID,
+		Total:        len(items),
+		Items:        []models.CollectionProgressItem{},
+	}
+	for _, item := range items {
+		entry := models.CollectionProgressItem{ProblemID: item.ProblemID, Position: item.Position}
+		if v, ok := latest[item.ProblemID]; ok {
+			point, votedAt := v.Point, v.CreatedAt
+			entry.Voted, entry.Point, entry.VotedAt = true, &point, &votedAt
+			progress.Voted++
+		}
+		progress.Items = append(progress.Items, entry)
+	}
+	if progress.Total > 0 {
+		progress.Percent = float64(progress.Voted) * 100 / float64(progress.Total)
+	}
+	return progress
+}
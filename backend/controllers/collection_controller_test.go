@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"portfolio-backend/models"
+	"testing"
+)
+
+func TestNumberCollectionItems(t *testing.T) {
+	items, err := numberCollectionItems(7, []uint{30, 10, 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{30, 10, 20}
+	if len(items) != len(want) {
+		t.Fatalf("len = %d, want %d", len(items), len(want))
+	}
+	for i, item := range items {
+		if item.CollectionID != 7 || item.ProblemID != want[i] || item.Position != i+1 {
+			t.Errorf("items[%d] = %+v, want problem %d at position %d", i, item, want[i], i+1)
+		}
+	}
+
+	if _, err := numberCollectionItems(7, []uint{1, 2, 1}); err == nil {
+		t.Error("duplicate problem_id should be rejected")
+	}
+	if items, err := numberCollectionItems(7, nil); err != nil || len(items) != 0 {
+		t.Errorf("empty list = %v, %v; want no items", items, err)
+	}
+}
+
+func TestCollectionProgress(t *testing.T) {
+	items := []models.CollectionItem{
+		{ProblemID: 3, Position: 1},
+		{ProblemID: 1, Position: 2},
+		{ProblemID: 2, Position: 3},
+	}
+	// 古い順: 問題1 は 40 → 80 と投票し直している
+	votes := []models.Vote{
+		{ProblemID: 1, Point: 40},
+		{ProblemID: 3, Point: 60},
+		{ProblemID: 1, Point: 80},
+	}
+
+	progress := collectionProgress(5, items, votes)
+	if progress.CollectionID != 5 || progress.Total != 3 || progress.Voted != 2 {
+		t.Errorf("progress = %+v, want 2 of 3 voted", progress)
+	}
+	if want := float64(2) * 100 / 3; progress.Percent != want {
+		t.Errorf("percent = %v, want %v", progress.Percent, want)
+	}
+
+	wantPoints := []*int{intPtr(60), intPtr(80), nil}
+	for i, item := range progress.Items {
+		if item.ProblemID != items[i].ProblemID || item.Position != i+1 {
+			t.Errorf("items[%d] = problem %d at %d, want problem %d at %d", i, item.ProblemID, item.Position, items[i].ProblemID, i+1)
+		}
+		switch {
+		case wantPoints[i] == nil && (item.Voted || item.Point != nil):
+			t.Errorf("items[%d] should be unvoted, got %+v", i, item)
+		case wantPoints[i] != nil && (!item.Voted || item.Point == nil || *item.Point != *wantPoints[i]):
+			t.Errorf("items[%d] point = %v, want %d", i, item.Point, *wantPoints[i])
+		}
+	}
+
+	empty := collectionProgress(5, nil, nil)
+	if empty.Total != 0 || empty.Percent != 0 || empty.Items == nil {
+		t.Errorf("empty progress = %+v, want zero totals and an empty list", empty)
+	}
+}
+
+func intPtr(v int) *int { return &v }
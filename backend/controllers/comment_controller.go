@@ -0,0 +1,393 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// コメントの最大文字数
+const maxCommentLength = 2000
+
+// 問題の議論
+// GET  /problems/{id}/comments  投票の理由とコメントのスレッド (?sort=old (デフォルト) / new / top)
+// POST /problems/{id}/comments  コメントする 例: {"body": "...", "parent_id": 3}  parent_id を付けると返信
+// どちらも、投票するまでは 403 (管理者と、投票を締め切った問題は投票なしでよい)
+func ProblemComments(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/comments")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+	if !requireVoted(w, user, problem, "joining the discussion") {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		getDiscussion(w, r, user, problem)
+	case http.MethodPost:
+		createComment(w, r, user, problem)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func getDiscussion(w http.ResponseWriter, r *http.Request, user *models.User, problem *models.Problem) {
+	sortBy := r.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "old"
+	}
+	if sortBy != "old" && sortBy != "new" && sortBy != "top" {
+		http.Error(w, "sort must be old, new or top", http.StatusBadRequest)
+		return
+	}
+
+	reasons, err := voteReasons(problem.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch reasons", http.StatusInternalServerError)
+		return
+	}
+
+	// 削除済みも取る (返信がついていれば「削除されました」として残すため)
+	var comments []models.Comment
+	err = database.DB.Unscoped().Preload("User").
+		Where("problem_id = ?", problem.ID).
+		Order("created_at, id").
+		Find(&comments).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+	var liked []uint
+	err = database.DB.Model(&models.CommentLike{}).
+		Joins("JOIN comments ON comments.id = comment_likes.comment_id").
+		Where("comments.problem_id = ? AND comment_likes.user_id = ?", problem.ID, user.ID).
+		Pluck("comment_likes.comment_id", &liked).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch likes", http.StatusInternalServerError)
+		return
+	}
+
+	tree, total := commentTree(comments, liked, user.Role == "admin", sortBy)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.ProblemDiscussion{Reasons: reasons, Comments: tree, Total: total})
+}
+
+// 理由つきの投票 (集計対象の票のうち、ユーザーごとに最新のもの。匿名化された票はそれぞれ1件)
+func voteReasons(problemID uint) ([]models.VoteReason, error) {
+	var rows []models.VoteReason
+	err := countedVotes(int(problemID)).
+		Select("votes.id AS vote_id, votes.user_id, COALESCE(users.name, '') AS name, votes.point, votes.reason, votes.revision, votes.created_at").
+		Where("votes.reason <> ''").
+		Order("votes.created_at DESC, votes.id DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	reasons := []models.VoteReason{}
+	seen := map[uint]bool{}
+	for _, row := range rows {
+		if row.UserID != nil {
+			if seen[*row.UserID] {
+				continue
+			}
+			seen[*row.UserID] = true
+		}
+		reasons = append(reasons, row)
+	}
+	return reasons, nil
+}
+
+// コメントをスレッドの形にする
+// 削除されたコメントは、返信が残っていれば本文なしで残し、なければ消す
+// 非表示のコメントは管理者以外には本文なしで返す
+// スレッドの最初のコメントは sortBy (old / new / top) の順、返信は古い順。表示するコメントの数も返す
+func commentTree(comments []models.Comment, liked []uint, admin bool, sortBy string) ([]models.CommentResponse, int) {
+	likedSet := map[uint]bool{}
+	for _, id := range liked {
+		likedSet[id] = true
+	}
+	children := map[uint][]models.Comment{}
+	var roots []models.Comment
+	for _, c := range comments {
+		if c.ParentID == nil {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	total := 0
+	var build func(c models.Comment) (models.CommentResponse, bool)
+	build = func(c models.Comment) (models.CommentResponse, bool) {
+		node := models.CommentResponse{
+			ID:        c.ID,
+			ParentID:  c.ParentID,
+			CreatedAt: c.CreatedAt,
+			Deleted:   c.DeletedAt.Valid,
+			Hidden:    c.Hidden,
+			LikeCount: c.LikeCount,
+			LikedByMe: likedSet[c.ID],
+			Replies:   []models.CommentResponse{},
+		}
+		for _, child := range children[c.ID] {
+			if reply, ok := build(child); ok {
+				node.Replies = append(node.Replies, reply)
+			}
+		}
+		if node.Deleted && len(node.Replies) == 0 {
+			return node, false
+		}
+		total++
+
+		switch {
+		case node.Deleted:
+			// 本文も書いた人も出さない
+		case node.Hidden && !admin:
+			node.UserID = c.UserID
+		default:
+			node.UserID, node.Body, node.EditedAt = c.UserID, c.Body, c.EditedAt
+			if node.Hidden {
+				node.HiddenReason = c.HiddenReason
+			}
+		}
+		if !node.Deleted && c.User != nil && !c.User.DeletedAt.Valid {
+			node.Name = c.User.Name
+		}
+		return node, true
+	}
+
+	tree := []models.CommentResponse{}
+	for _, c := range roots {
+		if node, ok := build(c); ok {
+			tree = append(tree, node)
+		}
+	}
+	switch sortBy {
+	case "new":
+		sort.SliceStable(tree, func(i, j int) bool { return tree[i].CreatedAt.After(tree[j].CreatedAt) })
+	case "top":
+		sort.SliceStable(tree, func(i, j int) bool { return tree[i].LikeCount > tree[j].LikeCount })
+	}
+	return tree, total
+}
+
+// コメントの本文を確かめる (前後の空白は落とす)
+func commentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", errors.New("body is required")
+	}
+	if utf8.RuneCountInString(body) > maxCommentLength {
+		return "", fmt.Errorf("body must be at most %d characters", maxCommentLength)
+	}
+	return body, nil
+}
+
+func createComment(w http.ResponseWriter, r *http.Request, user *models.User, problem *models.Problem) {
+	var input struct {
+		Body     string `json:"body"`
+		ParentID *uint  `json:"parent_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, err := commentBody(input.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.ParentID != nil {
+		// 返信先は同じ問題の、削除されていないコメントだけ
+		var parent models.Comment
+		if err := database.DB.Where("id = ? AND problem_id = ?", *input.ParentID, problem.ID).First(&parent).Error; err != nil {
+			http.Error(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment := models.Comment{ProblemID: problem.ID, UserID: &user.ID, ParentID: input.ParentID, Body: body}
+	if err := database.DB.Create(&comment).Error; err != nil {
+		http.Error(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(comment)
+}
+
+// コメントの個別操作
+// PATCH  /comments/{id}       本文の編集 (書いた本人だけ) 例: {"body": "..."}
+// DELETE /comments/{id}       削除 (書いた本人か管理者)
+// POST   /comments/{id}/like  いいね (自分のコメントにはできない)
+// DELETE /comments/{id}/like  いいねを取り消す
+// POST   /comments/{id}/hide  管理者が非表示にする 例: {"hidden": true, "reason": "暴言"}  false で戻す
+func CommentDetail(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	// /comments/1/like -> [ "", "comments", "1", "like" ]
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	sub := ""
+	if len(pathParts) > 3 {
+		sub = pathParts[3]
+	}
+
+	var comment models.Comment
+	if err := database.DB.First(&comment, id).Error; err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	// 問題が見られない・まだ投票していない人には、コメントがあることも見せない
+	problem, ok := findVisibleProblem(w, r, strconv.Itoa(int(comment.ProblemID)))
+	if !ok { return }
+	if !requireVoted(w, user, problem, "joining the discussion") {
+		return
+	}
+
+	author := comment.UserID != nil && *comment.UserID == user.ID
+	admin := user.Role == "admin"
+	switch {
+	case r.Method == http.MethodPatch && sub == "":
+		if !author {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		updateComment(w, r, &comment)
+	case r.Method == http.MethodDelete && sub == "":
+		if !author && !admin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err := database.DB.Delete(&comment).Error; err != nil {
+			http.Error(w, "Failed to delete comment", http.StatusInternalServerError)
+			return
+		}
+		if !author {
+			recordAudit(r, user, AuditCommentDelete, commentTarget(comment.ID), comment, nil, "")
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": "Deleted"})
+	case (r.Method == http.MethodPost || r.Method == http.MethodDelete) && sub == "like":
+		if author {
+			http.Error(w, "Cannot like your own comment", http.StatusBadRequest)
+			return
+		}
+		likeComment(w, &comment, user, r.Method == http.MethodPost)
+	case r.Method == http.MethodPost && sub == "hide":
+		if !admin {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		hideComment(w, r, user, &comment)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func commentTarget(id uint) auditTarget { return auditTarget{Type: "comment", ID: id} }
+
+func updateComment(w http.ResponseWriter, r *http.Request, comment *models.Comment) {
+	// 非表示にされたコメントは、書き直して表示させることはできない
+	if comment.Hidden {
+		http.Error(w, "Comment is hidden by a moderator", http.StatusForbidden)
+		return
+	}
+	var input struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body, err := commentBody(input.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	comment.Body, comment.EditedAt = body, &now
+	if err := database.DB.Model(comment).Select("Body", "EditedAt").Updates(comment).Error; err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(comment)
+}
+
+// いいねを付ける (like=false なら取り消す)。同じ人が何度押しても1回分
+func likeComment(w http.ResponseWriter, comment *models.Comment, user *models.User, like bool) {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var result *gorm.DB
+		delta := 1
+		if like {
+			result = tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&models.CommentLike{CommentID: comment.ID, UserID: user.ID})
+		} else {
+			result = tx.Where("comment_id = ? AND user_id = ?", comment.ID, user.ID).Delete(&models.CommentLike{})
+			delta = -1
+		}
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(comment).UpdateColumn("like_count", gorm.Expr("like_count + ?", delta)).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to update like", http.StatusInternalServerError)
+		return
+	}
+	if err := database.DB.Select("like_count").First(comment, comment.ID).Error; err != nil {
+		http.Error(w, "Failed to update like", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"like_count": comment.LikeCount, "liked_by_me": like})
+}
+
+func hideComment(w http.ResponseWriter, r *http.Request, admin *models.User, comment *models.Comment) {
+	var input struct {
+		Hidden bool   `json:"hidden"`
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !input.Hidden {
+		input.Reason = ""
+	}
+
+	before := *comment
+	comment.Hidden, comment.HiddenReason = input.Hidden, strings.TrimSpace(input.Reason)
+	if err := database.DB.Model(comment).Select("Hidden", "HiddenReason").Updates(comment).Error; err != nil {
+		http.Error(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditCommentHide, commentTarget(comment.ID), before, *comment, comment.HiddenReason)
+	json.NewEncoder(w).Encode(comment)
+}
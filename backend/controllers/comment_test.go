@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"portfolio-backend/models"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestCommentTree(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	alice := &models.User{Name: "alice"}
+	uid := func(id uint) *uint { return &id }
+	comment := func(id uint, parent *uint, minutes int, likes int) models.Comment {
+		c := models.Comment{UserID: uid(10), User: alice, ParentID: parent, Body: "body", LikeCount: likes}
+		c.ID, c.CreatedAt = id, base.Add(time.Duration(minutes)*time.Minute)
+		return c
+	}
+
+	deletedWithReply := comment(1, nil, 0, 0)
+	deletedWithReply.DeletedAt = gorm.DeletedAt{Time: base, Valid: true}
+	deletedLeaf := comment(4, nil, 3, 9)
+	deletedLeaf.DeletedAt = gorm.DeletedAt{Time: base, Valid: true}
+	hidden := comment(5, uid(2), 4, 0)
+	hidden.Hidden, hidden.HiddenReason = true, "spam"
+
+	comments := []models.Comment{
+		deletedWithReply,
+		comment(2, uid(1), 1, 0),
+		comment(3, nil, 2, 5),
+		deletedLeaf,
+		hidden,
+	}
+
+	tree, total := commentTree(comments, []uint{3}, false, "old")
+	if total != 4 || len(tree) != 2 {
+		t.Fatalf("total = %d, roots = %d; want 4, 2", total, len(tree))
+	}
+	root := tree[0]
+	if root.ID != 1 || !root.Deleted || root.Body != "" || root.Name != "" || root.UserID != nil {
+		t.Errorf("deleted root = %+v, want a placeholder", root)
+	}
+	reply := root.Replies[0]
+	if reply.ID != 2 || reply.Body != "body" || reply.Name != "alice" {
+		t.Errorf("reply = %+v", reply)
+	}
+	if h := reply.Replies[0]; !h.Hidden || h.Body != "" || h.HiddenReason != "" {
+		t.Errorf("hidden reply for a user = %+v, want no body", h)
+	}
+	if !tree[1].LikedByMe || tree[0].LikedByMe {
+		t.Error("liked_by_me is wrong")
+	}
+
+	// 管理者には非表示のコメントの本文と理由も返す
+	tree, _ = commentTree(comments, nil, true, "old")
+	if h := tree[0].Replies[0].Replies[0]; h.Body != "body" || h.HiddenReason != "spam" {
+		t.Errorf("hidden reply for an admin = %+v", h)
+	}
+
+	tree, _ = commentTree(comments, nil, false, "new")
+	if tree[0].ID != 3 {
+		t.Errorf("newest root = %d, want 3", tree[0].ID)
+	}
+	tree, _ = commentTree(comments, nil, false, "top")
+	if tree[0].ID != 3 {
+		t.Errorf("most liked root = %d, want 3", tree[0].ID)
+	}
+}
+
+func TestCommentBody(t *testing.T) {
+	if got, err := commentBody("  両面待ちが多い  "); err != nil || got != "両面待ちが多い" {
+		t.Errorf("commentBody = %q, %v", got, err)
+	}
+	if _, err := commentBody("   "); err == nil {
+		t.Error("empty body should fail")
+	}
+	if _, err := commentBody(strings.Repeat("あ", maxCommentLength)); err != nil {
+		t.Errorf("body of max length should pass: %v", err)
+	}
+	if _, err := commentBody(strings.Repeat("あ", maxCommentLength+1)); err == nil {
+		t.Error("too long body should fail")
+	}
+}
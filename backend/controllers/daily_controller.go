@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"portfolio-backend/scheduler"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 今日の一問 (GET /problems/today)
+// 公開時刻前は前日の一問を返す (最新の「今日以前の一問」)
+func GetTodayProblem(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	var daily models.DailyProblem
+	err := dailyProblems(r).
+		Where("date <= ?", scheduler.Today()).
+		Order("date DESC").
+		First(&daily).Error
+	if err != nil {
+		http.Error(w, "No daily problem yet", http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(daily)
+}
+
+// 問題つきの今日の一問のクエリ
+// 問題が削除された日は除く。管理者以外には、非公開に戻された問題の日も見せない
+func dailyProblems(r *http.Request) *gorm.DB {
+	query := database.DB.Preload("Problem").
+		Joins("JOIN problems ON problems.id = daily_problems.problem_id AND problems.deleted_at IS NULL")
+	if !isAdmin(r) {
+		query = visibleProblems(query)
+	}
+	return query
+}
+
+// 過去の一問 (GET /problems/archive, GET /problems/archive/{YYYY-MM-DD})
+// 日付なしなら新しい順の一覧 (limit / cursor でページ送り)
+func GetDailyArchive(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	today := scheduler.Today()
+	date := strings.Trim(strings.TrimPrefix(r.URL.Path, "/problems/archive"), "/")
+
+	// 日付指定: その日の一問
+	if date != "" {
+		if _, err := time.Parse(scheduler.DateLayout, date); err != nil {
+			http.Error(w, "Invalid date (YYYY-MM-DD)", http.StatusBadRequest)
+			return
+		}
+		var daily models.DailyProblem
+		// 未来の日付はまだ公開していない扱い
+		if date > today || dailyProblems(r).Where("date = ?", date).First(&daily).Error != nil {
+			http.Error(w, "No daily problem for "+date, http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(daily)
+		return
+	}
+
+	// 一覧
+	query := dailyProblems(r).Where("date <= ?", today)
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("date < ?", cursor.Value)
+	}
+
+	limit := parseLimit(r, 30, 100)
+	var archive []models.DailyProblem
+	if err := query.Order("date DESC").Limit(limit + 1).Find(&archive).Error; err != nil {
+		http.Error(w, "Failed to fetch archive", http.StatusInternalServerError)
+		return
+	}
+
+	nextCursor := ""
+	if len(archive) > limit {
+		archive = archive[:limit]
+		last := archive[limit-1]
+		nextCursor = encodeCursor(last.Date, last.ID)
+	}
+
+	setNextCursor(w, nextCursor)
+	json.NewEncoder(w).Encode(archive)
+}
+
+// 公開待ちキューの一覧 (GET /admin/daily-queue)
+// 公開待ちキューへの追加 (POST /admin/daily-queue) 例: {"problem_id": 12, "approve": true}
+func DailyQueue(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		var entries []models.DailyQueueEntry
+		if err := database.DB.Preload("Problem").Order("position, id").Find(&entries).Error; err != nil {
+			http.Error(w, "Failed to fetch queue", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(entries)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var input struct {
+		ProblemID uint `json:"problem_id"`
+		Approve   bool `json:"approve"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, input.ProblemID).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	// 既に公開済みの問題は「今日の一問」として改めて出さない
+	if problem.IsPublished(time.Now()) {
+		http.Error(w, "Problem is already published", http.StatusConflict)
+		return
+	}
+
+	// 末尾に追加
+	var last struct{ Max int }
+	database.DB.Model(&models.DailyQueueEntry{}).Select("COALESCE(MAX(position), 0) AS max").Scan(&last)
+	entry := models.DailyQueueEntry{ProblemID: problem.ID, Position: last.Max + 1}
+	if input.Approve {
+		now := time.Now()
+		entry.ApprovedAt, entry.ApprovedBy = &now, &admin.ID
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		http.Error(w, "Problem is already in the queue", http.StatusConflict)
+		return
+	}
+	recordAudit(r, admin, AuditDailyQueue, problemTarget(problem.ID), nil, entry, "")
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(entry)
+}
+
+// キューの個別操作
+// POST   /admin/daily-queue/{id}/approve  承認 (承認済みのものだけが公開される)
+// DELETE /admin/daily-queue/{id}          キューから外す
+func UpdateDailyQueueEntry(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	// /admin/daily-queue/3/approve -> [ "", "admin", "daily-queue", "3", "approve" ]
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 4 {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(pathParts[3])
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var entry models.DailyQueueEntry
+	if err := database.DB.First(&entry, id).Error; err != nil {
+		http.Error(w, "Queue entry not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/approve"):
+		before := entry
+		now := time.Now()
+		entry.ApprovedAt, entry.ApprovedBy = &now, &admin.ID
+		if err := database.DB.Model(&entry).Select("ApprovedAt", "ApprovedBy").Updates(&entry).Error; err != nil {
+			http.Error(w, "Failed to approve", http.StatusInternalServerError)
+			return
+		}
+		recordAudit(r, admin, AuditDailyQueue, problemTarget(entry.ProblemID), before, entry, "approve")
+		json.NewEncoder(w).Encode(entry)
+	case r.Method == http.MethodDelete:
+		if err := database.DB.Delete(&entry).Error; err != nil {
+			http.Error(w, "Failed to remove", http.StatusInternalServerError)
+			return
+		}
+		recordAudit(r, admin, AuditDailyQueue, problemTarget(entry.ProblemID), entry, nil, "remove")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Removed from queue"})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// 指定日の一問を今すぐ公開する (POST /admin/daily-queue/publish)
+// スケジューラーを待たずに手動で進めたいとき用。日付省略時は今日
+func PublishDailyNow(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = scheduler.Today()
+	} else if _, err := time.Parse(scheduler.DateLayout, date); err != nil {
+		http.Error(w, "Invalid date (YYYY-MM-DD)", http.StatusBadRequest)
+		return
+	}
+
+	daily, err := scheduler.PublishNext(date)
+	if errors.Is(err, scheduler.ErrQueueEmpty) {
+		http.Error(w, "Queue is empty", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to publish", http.StatusInternalServerError)
+		return
+	}
+	if daily == nil {
+		http.Error(w, "Daily problem for "+date+" already exists", http.StatusConflict)
+		return
+	}
+	recordAudit(r, admin, AuditDailyPublish, problemTarget(daily.ProblemID), nil, daily, date)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(daily)
+}
@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/models"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 同じ問題 (指紋が一致する問題) が作られそうなときの扱い (環境変数 DUPLICATE_POLICY で切り替え)
+const (
+	DuplicatePolicyWarn   = "warn"   // 作成はして X-Duplicate-Of ヘッダーで知らせる
+	DuplicatePolicyReject = "reject" // 409 で断る (?allow_duplicate=true で強制的に作成できる)
+)
+
+// 現在の重複の扱いポリシーを返す (未設定や不正な値なら warn)
+func duplicatePolicy() string {
+	if os.Getenv("DUPLICATE_POLICY") == DuplicatePolicyReject {
+		return DuplicatePolicyReject
+	}
+	return DuplicatePolicyWarn
+}
+
+// 指紋が一致する既存の問題を探す (下書きも含む)
+func findDuplicate(fingerprint string, excludeID uint) (*models.Problem, error) {
+	var dup models.Problem
+	err := database.DB.Where("fingerprint = ? AND id <> ?", fingerprint, excludeID).Order("id").First(&dup).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &dup, nil
+}
+
+// 重複した問題のIDをヘッダーで知らせる
+func setDuplicateOf(w http.ResponseWriter, id uint) {
+	w.Header().Set("Access-Control-Expose-Headers", "X-Duplicate-Of")
+	w.Header().Set("X-Duplicate-Of", strconv.FormatUint(uint64(id), 10))
+}
+
+// 似ている問題を探すときに DB から読む候補の上限 (手牌の比較は Go 側で行うので、読みすぎないようにする)
+const maxDuplicateScan = 2000
+
+// 似ている問題の一覧で返す上限
+const maxDuplicateCandidates = 50
+
+// 手牌の枚数を数える SQL 式 (hand_tiles は "[0,1,2]" の形の JSON 配列)
+const handTileCountExpr = "LENGTH(problems.hand_tiles) - LENGTH(REPLACE(problems.hand_tiles, ',', '')) + 1"
+
+// 似ている問題の一覧 (GET /problems/{id}/duplicates?max_distance=2)
+// 色の入れ替え・1↔9 の反転をならした上で、牌の違いが max_distance 枚以内の問題を近い順に返す (最大 maxDuplicateCandidates 件)
+// 自風・局が同じ問題だけが対象で、手牌の枚数の差が max_distance を超える問題は DB 側で除く
+func GetNearDuplicates(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	if _, ok := requireAdmin(w, r); !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/duplicates")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	maxDistance := 2
+	if s := r.URL.Query().Get("max_distance"); s != "" {
+		maxDistance, err = strconv.Atoi(s)
+		if err != nil || maxDistance < 0 || maxDistance > 14 {
+			http.Error(w, "max_distance must be 0-14", http.StatusBadRequest)
+			return
+		}
+	}
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	hand, errHand := mahjong.ParseTiles(problem.HandTiles)
+	dora, errDora := mahjong.ParseTiles(problem.DoraTiles)
+	if errHand != nil || errDora != nil {
+		http.Error(w, "Problem has invalid tiles", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// 投票数は問題一覧と同じく1回の集計で一緒に取る
+	var others []struct {
+		models.Problem
+		VoteCount int64
+	}
+	err = database.DB.Model(&models.Problem{}).
+		Select("problems.*, COALESCE(vs.vote_count, 0) AS vote_count").
+		Joins(voteStatsJoin).
+		Where("problems.id <> ?", problem.ID).
+		Where("LOWER(problems.wind) = LOWER(?) AND LOWER(problems.round) = LOWER(?)", problem.Wind, problem.Round).
+		Where("ABS(("+handTileCountExpr+") - ?) <= ?", len(hand), maxDistance).
+		Order("problems.id DESC").
+		Limit(maxDuplicateScan).
+		Scan(&others).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch problems", http.StatusInternalServerError)
+		return
+	}
+
+	candidates := []models.DuplicateCandidate{}
+	for _, other := range others {
+		otherHand, errHand := mahjong.ParseTiles(other.HandTiles)
+		otherDora, errDora := mahjong.ParseTiles(other.DoraTiles)
+		if errHand != nil || errDora != nil {
+			continue
+		}
+		d := mahjong.Distance(hand, dora, otherHand, otherDora)
+		if d > maxDistance {
+			continue
+		}
+		c := models.DuplicateCandidate{
+			ID:        other.ID,
+			HandTiles: other.HandTiles,
+			DoraTiles: other.DoraTiles,
+			Wind:      other.Wind,
+			Round:     other.Round,
+			Score:     other.Score,
+			Status:    other.Status,
+			Distance:  d,
+			Exact:     problem.Fingerprint != "" && other.Fingerprint == problem.Fingerprint,
+			VoteCount: other.VoteCount,
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Distance != candidates[j].Distance {
+			return candidates[i].Distance < candidates[j].Distance
+		}
+		return candidates[i].ID < candidates[j].ID
+	})
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+
+	json.NewEncoder(w).Encode(candidates)
+}
+
+// 重複した問題をまとめる (POST /problems/{id}/merge)
+// {id} の投票・手動タグ・問題集・今日の一問・議論のコメントを into の問題に移し、{id} は論理削除する
+// 両方に投票していたユーザーは into 側の投票を残す
+func MergeProblem(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/merge")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+	var input models.ProblemMergeRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Into == 0 {
+		http.Error(w, "into is required", http.StatusBadRequest)
+		return
+	}
+	if input.Into == uint(id) {
+		http.Error(w, "Cannot merge a problem into itself", http.StatusBadRequest)
+		return
+	}
+
+	var source, target models.Problem
+	result := models.ProblemMergeResult{MergedInto: input.Into}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 2行まとめて ID 順にロックする (同時に逆向きのマージが来てもデッドロックしない)
+		var locked []models.Problem
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", []uint{uint(id), input.Into}).
+			Order("id").
+			Find(&locked).Error
+		if err != nil {
+			return err
+		}
+		for _, p := range locked {
+			if p.ID == uint(id) {
+				source = p
+			} else {
+				target = p
+			}
+		}
+		if source.ID == 0 || target.ID == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// 両方に投票しているユーザーの票は into 側を残す
+		dropped := tx.Where("problem_id = ? AND user_id IN (?)", source.ID,
+			tx.Model(&models.Vote{}).Select("user_id").Where("problem_id = ? AND user_id IS NOT NULL", target.ID),
+		).Delete(&models.Vote{})
+		if dropped.Error != nil {
+			return dropped.Error
+		}
+		result.DroppedVotes = dropped.RowsAffected
+
+		// 残りの投票は into の今の版に対する投票として移す
+		moved := tx.Model(&models.Vote{}).Where("problem_id = ?", source.ID).
+			Updates(map[string]interface{}{"problem_id": target.ID, "revision": target.Revision})
+		if moved.Error != nil {
+			return moved.Error
+		}
+		result.MovedVotes = moved.RowsAffected
+
+		// 手動のタグを引き継ぐ (自動タグは into 側の解析結果のまま)
+		err = tx.Exec(`INSERT INTO problem_tags (problem_id, tag_id, source, created_at)
+			SELECT ?, tag_id, source, created_at FROM problem_tags WHERE problem_id = ? AND source = ?
+			ON CONFLICT DO NOTHING`, target.ID, source.ID, models.TagSourceManual).Error
+		if err != nil {
+			return err
+		}
+
+		// 問題集: into が入っていない問題集だけ差し替え、入っていれば外す
+		err = tx.Where("problem_id = ? AND collection_id IN (?)", source.ID,
+			tx.Model(&models.CollectionItem{}).Select("collection_id").Where("problem_id = ?", target.ID),
+		).Delete(&models.CollectionItem{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&models.CollectionItem{}).Where("problem_id = ?", source.ID).Update("problem_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// 公開待ちキュー: into が待っていなければ差し替え、待っていれば外す
+		var queued int64
+		tx.Model(&models.DailyQueueEntry{}).Where("problem_id = ?", target.ID).Count(&queued)
+		queue := tx.Where("problem_id = ?", source.ID)
+		if queued > 0 {
+			err = queue.Delete(&models.DailyQueueEntry{}).Error
+		} else {
+			err = queue.Model(&models.DailyQueueEntry{}).Update("problem_id", target.ID).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		// 過去の今日の一問は into を指すようにする (アーカイブから辿れるように)
+		if err := tx.Model(&models.DailyProblem{}).Where("problem_id = ?", source.ID).Update("problem_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// 議論のコメントは into に移す (いいねはコメントに付いているので一緒に移る)
+		comments := tx.Model(&models.Comment{}).Where("problem_id = ?", source.ID).Update("problem_id", target.ID)
+		if comments.Error != nil {
+			return comments.Error
+		}
+		result.MovedComments = comments.RowsAffected
+
+		// AIの評価とレーティングの履歴は移さない
+		// 評価は元の問題の局面に対するもので、レーティングの履歴は元の問題で決まった合意の記録なので、
+		// どちらも元の問題に残す (問題を戻せばそのまま使える)
+
+		return tx.Delete(&source).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to merge problems", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditProblemMerge, problemTarget(source.ID), source, result, "")
+
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMergeProblem(t *testing.T) {
+	database.Connect()
+
+	suffix := time.Now().UnixNano()
+	admin := models.User{Email: fmt.Sprintf("merge-admin-%d@example.com", suffix), Name: "admin", Role: "admin"}
+	voter := models.User{Email: fmt.Sprintf("merge-voter-%d@example.com", suffix), Name: "voter", Role: "user"}
+	for _, u := range []*models.User{&admin, &voter} {
+		if err := database.DB.Create(u).Error; err != nil {
+			t.Fatal(err)
+		}
+		defer database.DB.Unscoped().Delete(u)
+	}
+
+	hand := "[0,1,2,3,4,5,6,7,8,9,10,11,12,13]"
+	source := models.Problem{HandTiles: hand, DoraTiles: "[27]", Wind: "East", Round: "East-1"}
+	target := models.Problem{HandTiles: hand, DoraTiles: "[27]", Wind: "East", Round: "East-1"}
+	for _, p := range []*models.Problem{&source, &target} {
+		if err := database.DB.Create(p).Error; err != nil {
+			t.Fatal(err)
+		}
+		defer database.DB.Unscoped().Delete(p)
+	}
+	if err := database.DB.Create(&models.Vote{ProblemID: source.ID, UserID: &voter.ID, Point: 60}).Error; err != nil {
+		t.Fatal(err)
+	}
+	comment := models.Comment{ProblemID: source.ID, UserID: &voter.ID, Body: "両面を残したい"}
+	if err := database.DB.Create(&comment).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	body := fmt.Sprintf(`{"into": %d}`, target.ID)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/problems/%d/merge", source.ID), strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+issueToken(admin.ID))
+	w := httptest.NewRecorder()
+	MergeProblem(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+	var result models.ProblemMergeResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.MergedInto != target.ID || result.MovedVotes != 1 || result.MovedComments != 1 {
+		t.Errorf("result = %+v, want 1 vote and 1 comment moved into %d", result, target.ID)
+	}
+
+	var moved int64
+	database.DB.Model(&models.Vote{}).Where("problem_id = ?", target.ID).Count(&moved)
+	if moved != 1 {
+		t.Errorf("votes on target = %d, want 1", moved)
+	}
+	database.DB.First(&comment, comment.ID)
+	if comment.ProblemID != target.ID {
+		t.Errorf("comment problem_id = %d, want %d", comment.ProblemID, target.ID)
+	}
+	if err := database.DB.First(&models.Problem{}, source.ID).Error; err == nil {
+		t.Error("source problem should be deleted")
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/evaluator"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/models"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// 外部の麻雀AIに問題を評価させて保存する (POST /problems/{id}/evaluate)
+// 同じエンジンの評価は上書きする
+func EvaluateProblem(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/evaluate")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+
+	engine, err := evaluator.FromEnv()
+	if errors.Is(err, evaluator.ErrNotConfigured) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	events, err := problemEvents(problem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	result, err := engine.Evaluate(r.Context(), events)
+	if err != nil {
+		http.Error(w, "Evaluator failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	// 手牌にない牌を切ると言ってきたら評価として使えない
+	hand, _ := mahjong.ParseTiles(problem.HandTiles)
+	counts := mahjong.CountTiles(hand)
+	for _, c := range result.Candidates {
+		if c.Tile < 0 || c.Tile >= len(counts) || counts[c.Tile] == 0 {
+			http.Error(w, "Evaluator proposed a tile that is not in the hand", http.StatusBadGateway)
+			return
+		}
+	}
+
+	candidates, _ := json.Marshal(result.Candidates)
+	evaluation := models.EngineEvaluation{
+		ProblemID:       problem.ID,
+		Engine:          result.Engine,
+		ProblemRevision: problem.Revision,
+		BestTile:        result.Candidates[0].Tile,
+		Candidates:      candidates,
+	}
+	err = database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "problem_id"}, {Name: "engine"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "problem_revision", "best_tile", "candidates"}),
+	}).Create(&evaluation).Error
+	if err != nil {
+		http.Error(w, "Failed to save evaluation", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditProblemEval, problemTarget(problem.ID), nil, evaluation, "")
+
+	json.NewEncoder(w).Encode(evaluation)
+}
+
+// 問題に保存されているAIの評価 (GET /problems/{id}/evaluations)
+// 投票するまでは 403 (管理者と、投票を締め切った問題は投票なしでよい)
+func GetProblemEvaluations(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	// 未公開の問題は管理者だけ
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/evaluations")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+	// AIの答えも結果と同じく、投票してから
+	if !requireVoted(w, user, problem, "seeing engine evaluations") {
+		return
+	}
+
+	evaluations := []models.EngineEvaluation{}
+	if err := database.DB.Where("problem_id = ?", problem.ID).Order("engine").Find(&evaluations).Error; err != nil {
+		http.Error(w, "Failed to fetch evaluations", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(evaluations)
+}
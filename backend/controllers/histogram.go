@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+)
+
+// ヒストグラムの区間の数の上限
+const maxHistogramBins = 100
+
+// ヒストグラムの区切り方
+//
+// 区間は左閉 [a, b) で、最後の区間だけ [a, Max] とする (右閉なら (a, b] で、最初の区間だけ [Min, b])。
+// Min から Width ずつ区切り、Max を超える境界は Max で止める。
+type histogramSpec struct {
+	Min, Max    float64
+	Width       float64
+	Count       int
+	RightClosed bool
+}
+
+// 指定がないときの区切り方 (10点刻みで、100点だけ別の区間)
+var defaultHistogram = histogramSpec{Min: 0, Max: 100, Width: 10, Count: 11}
+
+// ?bins= / ?width= などで指定された区切り方
+// 自動の規則 (Freedman–Diaconis / Sturges) は投票の集計が済んでから区間の数を決める
+type histogramParams struct {
+	Default     bool    // 何も指定されていない
+	Rule        string  // "fd" / "sturges" (自動で決める場合)
+	Bins        int     // 区間の数
+	Width       float64 // 区間の幅
+	Min, Max    float64
+	RightClosed bool
+}
+
+// ヒストグラムのクエリパラメータを読む
+//
+//	bins=   区間の数 (1-100) または自動の規則 (fd / sturges / auto)
+//	width=  区間の幅 (bins とは同時に指定できない)
+//	min= / max=  範囲 (既定は 0-100、範囲外の票は数えない)
+//	closed= left (既定) / right
+func parseHistogramParams(q url.Values) (histogramParams, error) {
+	p := histogramParams{Min: 0, Max: 100}
+	if q.Get("bins") == "" && q.Get("width") == "" && q.Get("min") == "" && q.Get("max") == "" && q.Get("closed") == "" {
+		p.Default = true
+		return p, nil
+	}
+
+	if q.Get("bins") != "" && q.Get("width") != "" {
+		return p, errors.New("bins and width cannot be used together")
+	}
+	switch bins := q.Get("bins"); bins {
+	case "":
+	case "fd", "auto":
+		p.Rule = "fd"
+	case "sturges":
+		p.Rule = "sturges"
+	default:
+		n, err := strconv.Atoi(bins)
+		if err != nil || n < 1 || n > maxHistogramBins {
+			return p, fmt.Errorf("bins must be 1-%d, fd, sturges or auto", maxHistogramBins)
+		}
+		p.Bins = n
+	}
+	if s := q.Get("width"); s != "" {
+		width, err := strconv.ParseFloat(s, 64)
+		if err != nil || width <= 0 || math.IsInf(width, 0) {
+			return p, errors.New("width must be a positive number")
+		}
+		p.Width = width
+	}
+	for _, f := range []struct {
+		param string
+		dst   *float64
+	}{{"min", &p.Min}, {"max", &p.Max}} {
+		if s := q.Get(f.param); s != "" {
+			v, err := strconv.ParseFloat(s, 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return p, errors.New("invalid " + f.param)
+			}
+			*f.dst = v
+		}
+	}
+	if p.Min >= p.Max {
+		return p, errors.New("min must be less than max")
+	}
+	switch q.Get("closed") {
+	case "", "left":
+	case "right":
+		p.RightClosed = true
+	default:
+		return p, errors.New("closed must be left or right")
+	}
+	if p.Width > 0 && math.Ceil((p.Max-p.Min)/p.Width) > maxHistogramBins {
+		return p, fmt.Errorf("width is too small (at most %d bins)", maxHistogramBins)
+	}
+	return p, nil
+}
+
+// 投票の集計 (件数・四分位数) を使って区切り方を決める
+func (p histogramParams) spec(s voteSummary) histogramSpec {
+	if p.Default {
+		return defaultHistogram
+	}
+	spec := histogramSpec{Min: p.Min, Max: p.Max, RightClosed: p.RightClosed}
+	span := p.Max - p.Min
+
+	count := p.Bins
+	switch {
+	case p.Width > 0:
+		spec.Width = p.Width
+		spec.Count = int(math.Ceil(span/p.Width - 1e-9))
+		if spec.Count < 1 {
+			spec.Count = 1
+		}
+		return spec
+	case p.Rule == "fd":
+		// Freedman–Diaconis: 幅 = 2 * IQR / n^(1/3) (IQR が 0 なら Sturges にする)
+		if width := 2 * (s.Q3 - s.Q1) / math.Cbrt(float64(s.Count)); width > 0 {
+			count = int(math.Ceil(span / width))
+		} else {
+			count = sturgesBins(s.Count)
+		}
+	case p.Rule == "sturges":
+		count = sturgesBins(s.Count)
+	case count == 0:
+		// 範囲だけ指定されたときは10区間にする
+		count = 10
+	}
+	if count < 1 {
+		count = 1
+	}
+	if count > maxHistogramBins {
+		count = maxHistogramBins
+	}
+	spec.Count = count
+	spec.Width = span / float64(count)
+	return spec
+}
+
+// Sturges の公式: ceil(log2 n) + 1
+func sturgesBins(n int) int {
+	if n < 2 {
+		return 1
+	}
+	return int(math.Ceil(math.Log2(float64(n)))) + 1
+}
+
+// 区間の境界 (Count+1 個、Max を超える境界は Max で止める)
+func (s histogramSpec) edges() []float64 {
+	edges := make([]float64, s.Count+1)
+	for i := range edges {
+		edges[i] = math.Min(s.Min+float64(i)*s.Width, s.Max)
+	}
+	return edges
+}
+
+// 点数から区間の番号を求める SQL (範囲外の票は呼び出し側の WHERE で除く)
+func (s histogramSpec) binExpr() (string, []interface{}) {
+	index := "FLOOR((votes.point - ?::float8) / ?::float8)"
+	if s.RightClosed {
+		index = "CEIL((votes.point - ?::float8) / ?::float8) - 1"
+	}
+	return fmt.Sprintf("LEAST(GREATEST(%s, 0), ?)::int", index), []interface{}{s.Min, s.Width, s.Count - 1}
+}
+
+// 区間のラベル
+// 境界が全て整数なら、区間に入る点数の範囲 (例: "0-9"、1点だけなら "100") にする
+func (s histogramSpec) labels() []string {
+	edges := s.edges()
+	integral := true
+	for _, e := range edges {
+		if e != math.Trunc(e) {
+			integral = false
+		}
+	}
+
+	labels := make([]string, s.Count)
+	for i := range labels {
+		lo, hi := edges[i], edges[i+1]
+		if !integral {
+			labels[i] = formatEdge(lo) + "-" + formatEdge(hi)
+			continue
+		}
+		last, first := i == s.Count-1, i == 0
+		switch {
+		case !s.RightClosed && !last:
+			hi--
+		case s.RightClosed && !first:
+			lo++
+		}
+		if lo >= hi {
+			labels[i] = formatEdge(lo)
+		} else {
+			labels[i] = formatEdge(lo) + "-" + formatEdge(hi)
+		}
+	}
+	return labels
+}
+
+// 境界の数値をラベル用に小数点第2位までで書く
+func formatEdge(x float64) string {
+	return strconv.FormatFloat(math.Round(x*100)/100, 'f', -1, 64)
+}
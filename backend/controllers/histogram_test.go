@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseHistogramParams(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{"", false},
+		{"bins=20", false},
+		{"width=5&min=20&max=80", false},
+		{"bins=fd", false},
+		{"bins=sturges&closed=right", false},
+		{"bins=20&width=5", true},
+		{"bins=0", true},
+		{"bins=101", true},
+		{"width=-1", true},
+		{"width=0.01", true},
+		{"min=50&max=50", true},
+		{"closed=both", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			_, err := parseHistogramParams(q)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHistogramSpec(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		stats  voteSummary
+		labels []string
+		edges  []float64
+	}{
+		{
+			name:   "default keeps the eleven 10-point bins",
+			query:  "",
+			labels: []string{"0-9", "10-19", "20-29", "30-39", "40-49", "50-59", "60-69", "70-79", "80-89", "90-99", "100"},
+			edges:  []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 100},
+		},
+		{
+			name:   "bins with the maximum in the last bin",
+			query:  "bins=4",
+			labels: []string{"0-24", "25-49", "50-74", "75-100"},
+			edges:  []float64{0, 25, 50, 75, 100},
+		},
+		{
+			name:   "right closed",
+			query:  "width=25&closed=right",
+			labels: []string{"0-25", "26-50", "51-75", "76-100"},
+			edges:  []float64{0, 25, 50, 75, 100},
+		},
+		{
+			name:   "width that does not divide the range",
+			query:  "width=30&min=10&max=90",
+			labels: []string{"10-39", "40-69", "70-90"},
+			edges:  []float64{10, 40, 70, 90},
+		},
+		{
+			name:   "fractional edges",
+			query:  "bins=4&max=10",
+			labels: []string{"0-2.5", "2.5-5", "5-7.5", "7.5-10"},
+			edges:  []float64{0, 2.5, 5, 7.5, 10},
+		},
+		{
+			// 8票なら ceil(log2 8) + 1 = 4
+			name:   "sturges",
+			query:  "bins=sturges",
+			stats:  voteSummary{Count: 8},
+			labels: []string{"0-24", "25-49", "50-74", "75-100"},
+			edges:  []float64{0, 25, 50, 75, 100},
+		},
+		{
+			// 幅 = 2 * 40 / 8^(1/3) = 40 -> ceil(100 / 40) = 3 区間
+			name:   "freedman-diaconis",
+			query:  "bins=fd",
+			stats:  voteSummary{Count: 8, Q1: 30, Q3: 70},
+			labels: []string{"0-33.33", "33.33-66.67", "66.67-100"},
+			edges:  []float64{0, 100.0 / 3, 200.0 / 3, 100},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			params, err := parseHistogramParams(q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec := params.spec(tt.stats)
+			if got := spec.labels(); !reflect.DeepEqual(got, tt.labels) {
+				t.Errorf("labels = %v, want %v", got, tt.labels)
+			}
+			if got := spec.edges(); !reflect.DeepEqual(got, tt.edges) {
+				t.Errorf("edges = %v, want %v", got, tt.edges)
+			}
+		})
+	}
+}
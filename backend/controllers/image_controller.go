@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"portfolio-backend/render"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 問題を画像で返す (GET /problems/{id}/image.svg, GET /problems/{id}/image.png)
+// チャットや SNS に貼る用。編集されたら ETag が変わる
+func GetProblemImage(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	path := strings.TrimPrefix(r.URL.Path, "/problems/")
+	format := "png"
+	if strings.HasSuffix(path, "/image.svg") {
+		format = "svg"
+	}
+	idStr := strings.TrimSuffix(strings.TrimSuffix(path, "/image.svg"), "/image.png")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+
+	scene, err := problemScene(problem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	etag := fmt.Sprintf(`"problem-%d-r%d-%s"`, problem.ID, problem.Revision, format)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", problemCacheControl(problem))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(render.SVG(scene))
+		return
+	}
+	// 途中で失敗したときに壊れた画像を返さないよう、描き終わってから書き出す
+	var buf bytes.Buffer
+	if err := render.PNG(&buf, scene); err != nil {
+		http.Error(w, "Failed to render image", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(buf.Bytes())
+}
+
+// 共有用のページ (GET /problems/{id}/share)
+// Open Graph のメタタグで問題の画像を指定し、ブラウザで開いたらフロントエンドの問題ページに移動する
+//
+//	PUBLIC_API_URL  このAPIの公開URL (画像のURLに使う。省略時は相対パスで、SNS のプレビューは出ない)
+//	FRONTEND_URL    フロントエンドの公開URL (省略時は移動しない)
+func GetProblemSharePage(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/share")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+
+	page := sharePage{
+		Title:       fmt.Sprintf("何切る問題 #%d", problem.ID),
+		Description: fmt.Sprintf("%s局 / %s家 / %d点", problem.Round, problem.Wind, problem.Score),
+		Image:       fmt.Sprintf("%s/problems/%d/image.png?r=%d", publicAPIURL(), problem.ID, problem.Revision),
+	}
+	if frontend := strings.TrimSuffix(os.Getenv("FRONTEND_URL"), "/"); frontend != "" {
+		page.URL = fmt.Sprintf("%s/problems/%d", frontend, problem.ID)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", problemCacheControl(problem))
+	sharePageTemplate.Execute(w, page)
+}
+
+type sharePage struct {
+	Title, Description, Image, URL string
+}
+
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html lang="ja">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="1200">
+<meta property="og:image:height" content="630">
+<meta name="twitter:card" content="summary_large_image">
+{{if .URL}}<meta property="og:url" content="{{.URL}}">
+<meta http-equiv="refresh" content="0; url={{.URL}}">{{end}}
+</head>
+<body>
+<img src="{{.Image}}" alt="{{.Title}}" width="1200" height="630">
+{{if .URL}}<p><a href="{{.URL}}">問題を開く</a></p>{{end}}
+</body>
+</html>
+`))
+
+// 画像のURLに使うこのAPIの公開URL (PUBLIC_API_URL、未設定なら空で相対パスになる)
+// リクエストの Host や X-Forwarded-Proto は偽装できるので使わない
+func publicAPIURL() string {
+	return strings.TrimSuffix(os.Getenv("PUBLIC_API_URL"), "/")
+}
+
+// 問題の画像・共有ページのキャッシュ
+// 未公開の問題は管理者にだけ見せているので、共有キャッシュにも残さない
+func problemCacheControl(problem *models.Problem) string {
+	if !problem.IsPublished(time.Now()) {
+		return "private, no-store"
+	}
+	return "public, max-age=3600"
+}
+
+// IDの問題を取得する (未公開の問題は管理者だけ)
+// 見つからなければエラーを返して false
+func findVisibleProblem(w http.ResponseWriter, r *http.Request, idStr string) (*models.Problem, bool) {
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	var problem models.Problem
+	if err := database.DB.First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return nil, false
+	}
+	if !problem.IsPublished(time.Now()) && !isAdmin(r) {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return nil, false
+	}
+	return &problem, true
+}
+
+// 問題から描く局面を作る
+func problemScene(problem *models.Problem) (render.Scene, error) {
+	pos, err := problem.Position()
+	if err != nil {
+		return render.Scene{}, err
+	}
+	return render.Scene{
+		Hand:     pos.Hand,
+		Melds:    pos.Melds[0],
+		Dora:     pos.Dora,
+		Wind:     pos.Wind,
+		Number:   pos.Number,
+		SeatWind: pos.SeatWind,
+		Score:    problem.Score,
+		Riichi:   problem.OpponentRiichi,
+	}, nil
+}
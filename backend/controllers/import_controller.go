@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"portfolio-backend/mahjong/mjai"
+	"portfolio-backend/mahjong/tenhou"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+)
+
+// 取り込める牌譜ファイルの上限サイズ
+const maxImportSize = 10 << 20
+
+// 天鳳の牌譜から問題を作る (POST /admin/problems/import/tenhou)
+// multipart/form-data の file に牌譜 (JSON / mjlog) を付けて送る
+//
+//	kyoku / seat / turn を省略: 局の一覧 (models.ImportRound) を返す
+//	kyoku= 何局目か (0始まり), seat= 座席 (0-3, 起家が0), turn= 何回目の打牌か (1始まり)
+//	                → その打牌の直前の局面で問題を作る (status を省略したら下書き)
+func ImportTenhou(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	kyokus, err := tenhou.Parse(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	importKyoku(w, r, admin, kyokus, "tenhou import")
+}
+
+// MJAI の牌譜 (1行1イベントの JSON) から問題を作る (POST /admin/problems/import/mjai)
+// パラメータは天鳳の取り込みと同じ。seat は MJAI の actor
+func ImportMJAI(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := mjai.Read(bytes.NewReader(data))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	kyokus, err := mjai.Decode(events)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	importKyoku(w, r, admin, kyokus, "mjai import")
+}
+
+// 問題を MJAI のイベント列で返す (GET /problems/{id}/mjai)
+// 自分を actor 0 とし、打牌を考えるところ (最後のツモ) までを1行1イベントで返す
+func ExportMJAI(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	// 未公開の問題は管理者だけ
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/mjai")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+
+	events, err := problemEvents(problem)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	mjai.Write(w, events)
+}
+
+// 問題の局面を MJAI のイベント列にする (start_game の id は自分 = 0)
+func problemEvents(problem *models.Problem) ([]mjai.Event, error) {
+	pos, err := problem.Position()
+	if err != nil {
+		return nil, err
+	}
+	k, err := pos.Kyoku()
+	if err != nil {
+		return nil, err
+	}
+	self := 0
+	start := mjai.Event{Type: mjai.TypeStartGame, ID: &self, Names: []string{"", "", "", ""}}
+	return append([]mjai.Event{start}, mjai.EncodeKyoku(k)...), nil
+}
+
+// アップロードされた牌譜を読む (multipart の file、なければリクエストボディそのもの)
+func readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err == nil {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file is required")
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, errors.New("failed to read game log")
+	}
+	return data, nil
+}
+
+// 読み込んだ牌譜から、指定された局面で問題を作る (指定がなければ局の一覧を返す)
+func importKyoku(w http.ResponseWriter, r *http.Request, admin *models.User, kyokus []gamelog.Kyoku, note string) {
+	if r.FormValue("kyoku") == "" && r.FormValue("seat") == "" && r.FormValue("turn") == "" {
+		rounds := make([]models.ImportRound, len(kyokus))
+		for i := range kyokus {
+			k := &kyokus[i]
+			rounds[i] = models.ImportRound{Index: i, Name: k.Name(), Honba: k.Honba, Dealer: k.Dealer, Turns: k.Turns()}
+		}
+		json.NewEncoder(w).Encode(rounds)
+		return
+	}
+
+	index, err1 := strconv.Atoi(r.FormValue("kyoku"))
+	seat, err2 := strconv.Atoi(r.FormValue("seat"))
+	turn, err3 := strconv.Atoi(r.FormValue("turn"))
+	if err1 != nil || err2 != nil || err3 != nil {
+		http.Error(w, "kyoku, seat and turn must be numbers", http.StatusBadRequest)
+		return
+	}
+	if index < 0 || index >= len(kyokus) {
+		http.Error(w, fmt.Sprintf("kyoku must be 0-%d", len(kyokus)-1), http.StatusBadRequest)
+		return
+	}
+	state, err := gamelog.Replay(&kyokus[index], seat, turn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, t := range state.Players[seat].Hand {
+		if t == gamelog.Unknown {
+			http.Error(w, fmt.Sprintf("the hand of seat %d is hidden in this log", seat), http.StatusBadRequest)
+			return
+		}
+	}
+
+	problem := problemFromState(state)
+	problem.Status = r.FormValue("status")
+	if problem.Status == "" {
+		// 取り込んだ問題は確認してから公開する
+		problem.Status = models.ProblemStatusDraft
+	}
+	createProblem(w, r, admin, &problem, fmt.Sprintf("%s: kyoku %d seat %d turn %d", note, index, seat, turn))
+}
+
+// 局面を問題にする (打牌を考えている人から見た形)
+func problemFromState(s *gamelog.State) models.Problem {
+	rivers, melds, scores := s.View(s.Seat)
+	scoresJSON, _ := json.Marshal(scores)
+
+	riichi := false
+	for seat, p := range s.Players {
+		if seat != s.Seat && p.Riichi {
+			riichi = true
+		}
+	}
+
+	return models.Problem{
+		HandTiles:      mahjong.FormatTiles(s.Players[s.Seat].Hand),
+		DoraTiles:      mahjong.FormatTiles(s.DoraIndicators),
+		Wind:           gamelog.WindName(s.Kyoku.SeatWind(s.Seat)),
+		Round:          s.Kyoku.Name(),
+		Score:          scores[0],
+		OpponentRiichi: riichi,
+		Rivers:         rivers.String(),
+		Melds:          melds.String(),
+		Scores:         string(scoresJSON),
+	}
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"portfolio-backend/scheduler"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ランキングの期間
+const (
+	LeaderboardWeek  = "week"  // 月曜始まりの1週間 (アプリのタイムゾーン)
+	LeaderboardMonth = "month" // 暦月
+	LeaderboardAll   = "all"   // 全期間
+)
+
+// 期間ごとの、ランキングに載るのに必要な投票数 (少ない票でたまたま上位になるのを防ぐ)
+var leaderboardMinVotes = map[string]int{
+	LeaderboardWeek:  3,
+	LeaderboardMonth: 5,
+	LeaderboardAll:   10,
+}
+
+// ランキングの集計結果をキャッシュする時間のデフォルト (環境変数 LEADERBOARD_CACHE_TTL で変更可)
+const defaultLeaderboardCacheTTL = 5 * time.Minute
+
+// キャッシュしておく条件の数の上限 (超えたら期限の近いものから捨てる)
+// 条件はクエリパラメータで自由に変えられるので、上限がないといくらでも増えてしまう
+const leaderboardCacheSize = 256
+
+// ランキングの条件 (クエリパラメータから読む)
+type leaderboardQuery struct {
+	Period     string
+	From, To   *time.Time
+	Tags       string
+	Collection uint
+}
+
+// ?period= / ?date= / ?tags= / ?collection= を読む
+func parseLeaderboardQuery(q url.Values, now time.Time) (leaderboardQuery, error) {
+	lq := leaderboardQuery{Period: q.Get("period"), Tags: normalizeTagsParam(q.Get("tags"))}
+	if lq.Period == "" {
+		lq.Period = LeaderboardWeek
+	}
+	if _, ok := leaderboardMinVotes[lq.Period]; !ok {
+		return lq, fmt.Errorf("period must be week, month or all")
+	}
+
+	at := now.In(scheduler.Location())
+	if s := q.Get("date"); s != "" {
+		d, err := time.ParseInLocation(scheduler.DateLayout, s, scheduler.Location())
+		if err != nil {
+			return lq, fmt.Errorf("invalid date %q (want YYYY-MM-DD)", s)
+		}
+		at = d
+	}
+	if from, to, ok := periodRange(lq.Period, at); ok {
+		lq.From, lq.To = &from, &to
+	}
+
+	if s := q.Get("collection"); s != "" {
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return lq, fmt.Errorf("invalid collection %q", s)
+		}
+		lq.Collection = uint(id)
+	}
+	return lq, nil
+}
+
+// ?tags= をスラッグに直し、重複を除いて並べ替える
+// (書き方が違うだけの同じ条件が、別々にキャッシュされないように)
+func normalizeTagsParam(param string) string {
+	seen := map[string]bool{}
+	slugs := []string{}
+	for _, s := range strings.Split(param, ",") {
+		if slug := slugify(s); slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	sort.Strings(slugs)
+	return strings.Join(slugs, ",")
+}
+
+// at を含む期間 [from, to) (at のタイムゾーンで区切る)。全期間なら false
+func periodRange(period string, at time.Time) (from, to time.Time, ok bool) {
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	switch period {
+	case LeaderboardWeek:
+		// 月曜日まで戻す (time.Sunday = 0)
+		from = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return from, from.AddDate(0, 0, 7), true
+	case LeaderboardMonth:
+		from = time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, at.Location())
+		return from, from.AddDate(0, 1, 0), true
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// キャッシュのキー
+func (lq leaderboardQuery) key() string {
+	from := ""
+	if lq.From != nil {
+		from = lq.From.Format(time.RFC3339)
+	}
+	return strings.Join([]string{lq.Period, from, lq.Tags, strconv.FormatUint(uint64(lq.Collection), 10)}, "|")
+}
+
+func (lq leaderboardQuery) minVotes() int {
+	return leaderboardMinVotes[lq.Period]
+}
+
+// 期間中にレーティングに反映された投票を、ユーザーごとに集計する (投票数が足りない人も含む)
+// 退会したユーザー、停止中・BAN 中のユーザー (User.IsActive と同じ判定) と、削除された問題は除く
+func (lq leaderboardQuery) fetch() ([]models.LeaderboardEntry, error) {
+	query := database.DB.Model(&models.RatingHistory{}).
+		Select(`rating_histories.user_id, users.name,
+			AVG(rating_histories.agreement) * 100 AS score,
+			COUNT(*) AS votes,
+			SUM(rating_histories.change) AS rating_change,
+			MAX(rating_histories.settled_at) AS last_settled`).
+		Joins(`JOIN users ON users.id = rating_histories.user_id AND users.deleted_at IS NULL
+			AND (users.status IN ? OR users.status_until < ?)`, []string{"", models.UserStatusActive}, time.Now()).
+		Joins("JOIN problems ON problems.id = rating_histories.problem_id AND problems.deleted_at IS NULL").
+		Group("rating_histories.user_id, users.name")
+	if lq.From != nil {
+		query = query.Where("rating_histories.settled_at >= ? AND rating_histories.settled_at < ?", *lq.From, *lq.To)
+	}
+	if lq.Tags != "" {
+		query = withAllTags(query, lq.Tags)
+	}
+	if lq.Collection != 0 {
+		query = query.Where("problems.id IN (SELECT problem_id FROM collection_items WHERE collection_id = ?)", lq.Collection)
+	}
+
+	var entries []models.LeaderboardEntry
+	err := query.Scan(&entries).Error
+	return entries, err
+}
+
+// 並べて順位を付ける。同点は
+//  1. 投票数が多い方
+//  2. 先にその成績に達した方 (最後に反映された問題の締め切りが早い方)
+//  3. ユーザーIDが小さい方
+//
+// の順に上にするので、同じ順位の人はいない
+func rankEntries(entries []models.LeaderboardEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case a.Score != b.Score:
+			return a.Score > b.Score
+		case a.Votes != b.Votes:
+			return a.Votes > b.Votes
+		case !a.LastSettled.Equal(b.LastSettled):
+			return a.LastSettled.Before(b.LastSettled)
+		}
+		return a.UserID < b.UserID
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+}
+
+// 集計済みのランキング
+type leaderboardData struct {
+	ranked  []models.LeaderboardEntry // 投票数が足りている人 (順位付き)
+	votes   map[uint]int              // 全員の投票数 (足りない人も)
+	expires time.Time
+}
+
+// ランキングのキャッシュ (プロセス内、条件ごと)
+// レーティングは締め切られた問題をまとめて反映するときにしか変わらないので、数分古くても困らない
+var leaderboardCache = struct {
+	sync.Mutex
+	data map[string]*leaderboardData
+}{data: map[string]*leaderboardData{}}
+
+func leaderboardCacheTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("LEADERBOARD_CACHE_TTL")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultLeaderboardCacheTTL
+}
+
+// キャッシュを捨てる (レーティングを計算し直したとき)
+func clearLeaderboardCache() {
+	leaderboardCache.Lock()
+	defer leaderboardCache.Unlock()
+	leaderboardCache.data = map[string]*leaderboardData{}
+}
+
+// ランキングを返す (キャッシュが古ければ集計し直す)
+func (lq leaderboardQuery) load(now time.Time) (*leaderboardData, error) {
+	key := lq.key()
+	leaderboardCache.Lock()
+	data, ok := leaderboardCache.data[key]
+	leaderboardCache.Unlock()
+	if ok && now.Before(data.expires) {
+		return data, nil
+	}
+
+	entries, err := lq.fetch()
+	if err != nil {
+		return nil, err
+	}
+	data = &leaderboardData{votes: map[uint]int{}, expires: now.Add(leaderboardCacheTTL())}
+	for _, e := range entries {
+		data.votes[e.UserID] = e.Votes
+		if e.Votes >= lq.minVotes() {
+			// 表示と同じ小数1桁に丸めてから並べる (見た目が同点なら投票数などで決める)
+			e.Score = round1(e.Score)
+			e.RatingChange = round1(e.RatingChange)
+			data.ranked = append(data.ranked, e)
+		}
+	}
+	rankEntries(data.ranked)
+
+	leaderboardCache.Lock()
+	storeLeaderboard(leaderboardCache.data, key, data, now)
+	leaderboardCache.Unlock()
+	return data, nil
+}
+
+// キャッシュに入れる。期限切れのものはついでに捨て、それでも上限を超えるなら期限の近いものから捨てる
+func storeLeaderboard(cache map[string]*leaderboardData, key string, data *leaderboardData, now time.Time) {
+	for k, d := range cache {
+		if !now.Before(d.expires) {
+			delete(cache, k)
+		}
+	}
+	delete(cache, key)
+	for len(cache) >= leaderboardCacheSize {
+		oldest := ""
+		for k, d := range cache {
+			if oldest == "" || d.expires.Before(cache[oldest].expires) {
+				oldest = k
+			}
+		}
+		delete(cache, oldest)
+	}
+	cache[key] = data
+}
+
+// レスポンスの共通部分
+func (lq leaderboardQuery) board(data *leaderboardData, entries []models.LeaderboardEntry) models.Leaderboard {
+	if entries == nil {
+		entries = []models.LeaderboardEntry{}
+	}
+	return models.Leaderboard{
+		Period:     lq.Period,
+		From:       lq.From,
+		To:         lq.To,
+		Tags:       lq.Tags,
+		Collection: lq.Collection,
+		MinVotes:   lq.minVotes(),
+		Total:      len(data.ranked),
+		Entries:    entries,
+	}
+}
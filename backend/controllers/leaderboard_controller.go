@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"portfolio-backend/models"
+	"time"
+)
+
+// 自分の順位の前後に何人ずつ返すか (GET /leaderboards/me)
+const leaderboardNeighbors = 2
+
+// ランキングを取得 (GET /leaderboards)
+// 合意との近さの平均 (レーティングに反映された投票のみ) が高い順。ログインしなくても見られる
+// クエリパラメータ:
+//
+//	period=      week (デフォルト) / month / all
+//	date=        その日を含む週・月 (例: 2026-01-05、デフォルトは今日)
+//	tags=        タグで絞る (カンマ区切り、全て付いている問題だけ)
+//	collection=  問題集のIDで絞る
+//	limit=       上位何人まで (デフォルト50, 最大100)
+func GetLeaderboard(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	now := time.Now()
+	lq, err := parseLeaderboardQuery(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := lq.load(now)
+	if err != nil {
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	entries := data.ranked
+	if limit := parseLimit(r, 50, 100); len(entries) > limit {
+		entries = entries[:limit]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lq.board(data, entries))
+}
+
+// ログイン中のユーザーの順位 (GET /leaderboards/me)
+// 上位に入っていなくても順位を返す。entries は自分の前後の人
+// クエリパラメータは GET /leaderboards と同じ (limit は使わない)
+func GetMyLeaderboardRank(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	now := time.Now()
+	lq, err := parseLeaderboardQuery(r.URL.Query(), now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := lq.load(now)
+	if err != nil {
+		http.Error(w, "Failed to fetch leaderboard", http.StatusInternalServerError)
+		return
+	}
+
+	standing := models.LeaderboardStanding{MyVotes: data.votes[user.ID]}
+	if standing.MyVotes < lq.minVotes() {
+		standing.VotesLeft = lq.minVotes() - standing.MyVotes
+	}
+	var neighbors []models.LeaderboardEntry
+	for i, e := range data.ranked {
+		if e.UserID != user.ID {
+			continue
+		}
+		me := e
+		standing.Me = &me
+		from, to := i-leaderboardNeighbors, i+leaderboardNeighbors+1
+		if from < 0 {
+			from = 0
+		}
+		if to > len(data.ranked) {
+			to = len(data.ranked)
+		}
+		neighbors = data.ranked[from:to]
+		break
+	}
+	standing.Leaderboard = lq.board(data, neighbors)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(standing)
+}
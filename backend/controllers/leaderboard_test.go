@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/url"
+	"portfolio-backend/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestPeriodRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	tests := []struct {
+		name     string
+		period   string
+		at       time.Time
+		from, to time.Time
+	}{
+		{"week from wednesday", LeaderboardWeek,
+			time.Date(2026, 1, 7, 15, 0, 0, 0, jst),
+			time.Date(2026, 1, 5, 0, 0, 0, 0, jst), time.Date(2026, 1, 12, 0, 0, 0, 0, jst)},
+		{"week from sunday", LeaderboardWeek,
+			time.Date(2026, 1, 11, 23, 59, 0, 0, jst),
+			time.Date(2026, 1, 5, 0, 0, 0, 0, jst), time.Date(2026, 1, 12, 0, 0, 0, 0, jst)},
+		{"week from monday", LeaderboardWeek,
+			time.Date(2026, 1, 12, 0, 0, 0, 0, jst),
+			time.Date(2026, 1, 12, 0, 0, 0, 0, jst), time.Date(2026, 1, 19, 0, 0, 0, 0, jst)},
+		{"month", LeaderboardMonth,
+			time.Date(2026, 12, 31, 12, 0, 0, 0, jst),
+			time.Date(2026, 12, 1, 0, 0, 0, 0, jst), time.Date(2027, 1, 1, 0, 0, 0, 0, jst)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, ok := periodRange(tt.period, tt.at)
+			if !ok || !from.Equal(tt.from) || !to.Equal(tt.to) {
+				t.Errorf("periodRange = %v - %v (%v), want %v - %v", from, to, ok, tt.from, tt.to)
+			}
+		})
+	}
+	if _, _, ok := periodRange(LeaderboardAll, time.Now()); ok {
+		t.Error("all-time leaderboard should have no range")
+	}
+}
+
+func TestRankEntries(t *testing.T) {
+	early := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	entries := []models.LeaderboardEntry{
+		{UserID: 1, Score: 70, Votes: 5, LastSettled: early},
+		{UserID: 2, Score: 80, Votes: 5, LastSettled: early},
+		{UserID: 3, Score: 80, Votes: 8, LastSettled: late},  // 同点なら投票数が多い方
+		{UserID: 4, Score: 70, Votes: 5, LastSettled: late},  // 同点・同数なら先に達した方
+		{UserID: 5, Score: 70, Votes: 5, LastSettled: early}, // それも同じならIDが小さい方
+	}
+	rankEntries(entries)
+
+	want := []uint{3, 2, 1, 5, 4}
+	for i, e := range entries {
+		if e.UserID != want[i] || e.Rank != i+1 {
+			t.Errorf("rank %d: user %d (rank %d), want user %d", i+1, e.UserID, e.Rank, want[i])
+		}
+	}
+}
+
+func TestParseLeaderboardQuery(t *testing.T) {
+	now := time.Now()
+	lq, err := parseLeaderboardQuery(url.Values{}, now)
+	if err != nil || lq.Period != LeaderboardWeek || lq.From == nil {
+		t.Errorf("default query = %+v, %v; want this week", lq, err)
+	}
+
+	lq, err = parseLeaderboardQuery(url.Values{"period": {"all"}, "collection": {"3"}}, now)
+	if err != nil || lq.From != nil || lq.Collection != 3 {
+		t.Errorf("all-time query = %+v, %v", lq, err)
+	}
+
+	// 書き方だけが違うタグ指定は同じ条件 (同じキャッシュキー) になる
+	a, _ := parseLeaderboardQuery(url.Values{"tags": {"Early Riichi, defense,defense"}}, now)
+	b, _ := parseLeaderboardQuery(url.Values{"tags": {"defense,early-riichi"}}, now)
+	if a.Tags != "defense,early-riichi" || a.key() != b.key() {
+		t.Errorf("tags = %q / %q, want both normalized to defense,early-riichi", a.Tags, b.Tags)
+	}
+
+	for _, q := range []url.Values{
+		{"period": {"year"}},
+		{"date": {"2026/01/01"}},
+		{"collection": {"abc"}},
+	} {
+		if _, err := parseLeaderboardQuery(q, now); err == nil {
+			t.Errorf("parseLeaderboardQuery(%v) should fail", q)
+		}
+	}
+}
+
+func TestStoreLeaderboard(t *testing.T) {
+	now := time.Now()
+	cache := map[string]*leaderboardData{
+		"expired": {expires: now.Add(-time.Second)},
+	}
+	for i := 0; i < leaderboardCacheSize+10; i++ {
+		storeLeaderboard(cache, strconv.Itoa(i), &leaderboardData{expires: now.Add(time.Duration(i+1) * time.Second)}, now)
+	}
+
+	if len(cache) != leaderboardCacheSize {
+		t.Errorf("cache size = %d, want %d", len(cache), leaderboardCacheSize)
+	}
+	if _, ok := cache["expired"]; ok {
+		t.Error("expired entry should be swept")
+	}
+	// 期限の近い (古い) ものから捨てられ、新しいものは残る
+	if _, ok := cache["0"]; ok {
+		t.Error("oldest entry should be evicted")
+	}
+	if _, ok := cache[strconv.Itoa(leaderboardCacheSize+9)]; !ok {
+		t.Error("newest entry should be kept")
+	}
+
+	// 同じキーを入れ直しても数は増えない
+	storeLeaderboard(cache, "20", &leaderboardData{expires: now.Add(time.Hour)}, now)
+	if len(cache) != leaderboardCacheSize || !cache["20"].expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("re-storing a key: size = %d", len(cache))
+	}
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// カーソルページネーション用の位置情報
+// 並び順のキー (Value) とタイブレーク用のID を持つ
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// カーソルをクライアントに渡す不透明な文字列にする
+func encodeCursor(value string, id uint) string {
+	b, _ := json.Marshal(pageCursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var c pageCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// ?limit= を読む (未指定なら def、上限は max)
+func parseLimit(r *http.Request, def, max int) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// 次ページのカーソルはレスポンスヘッダーで返す
+// (一覧のボディは従来どおり配列のままにしておく)
+func setNextCursor(w http.ResponseWriter, cursor string) {
+	w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+	if cursor != "" {
+		w.Header().Set("X-Next-Cursor", cursor)
+	}
+}
+
+// ILIKE 検索用に % と _ をエスケープした部分一致パターンを作る
+func likePattern(q string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(q) + "%"
+}
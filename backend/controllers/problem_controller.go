@@ -2,35 +2,166 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
 	"net/http"
 	"portfolio-backend/database"
+	"portfolio-backend/mahjong"
 	"portfolio-backend/models"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CORS設定などの共通ヘッダー
 func SetupResponse(w *http.ResponseWriter) {
 	(*w).Header().Set("Access-Control-Allow-Origin", "*")
-	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+	(*w).Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 	(*w).Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 }
 
-// 1. 全件取得 (一覧ページ用)
+// 1. 一覧取得 (一覧ページ用)
+// 一覧用の軽いデータ (models.ProblemSummary) を返す。牌や投票の中身は詳細APIで取る
+// クエリパラメータ:
+//   wind= / round=          自風・局で絞り込み
+//   shanten=                向聴数で絞り込み
+//   tags=                   タグ (カンマ区切り、全て付いているもの)
+//   min_votes= / max_votes= 投票数の範囲
+//   has_voted=true|false    自分が投票済みか (ログイン必須)
+//   status=                 公開状態 (管理者のみ)
+//   sort=                   newest (デフォルト) / most_voted / controversial (評価が割れている順)
+//   limit= / cursor=        ページネーション (次ページは X-Next-Cursor ヘッダー)
 func GetAllProblems(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions {
 		return
 	}
 
-	var problems []models.Problem
-	// ID順に全て取得
-	result := database.DB.Find(&problems)
+	q := r.URL.Query()
+	viewer, _ := currentUser(r)
+	var viewerID uint
+	if viewer != nil {
+		viewerID = viewer.ID
+	}
+
+	query := database.DB.Model(&models.Problem{}).
+		Select(`problems.*,
+			COALESCE(vs.vote_count, 0) AS vote_count,
+			COALESCE(vs.vote_std_dev, 0) AS vote_std_dev,
+			EXISTS (SELECT 1 FROM votes mv WHERE mv.problem_id = problems.id AND mv.user_id = ? AND mv.deleted_at IS NULL) AS has_voted`, viewerID).
+		Joins(voteStatsJoin)
+
+	// 一般ユーザーには公開中の問題だけを見せる
+	// 管理者は下書き・予約公開も含めて全て見られる (?status= で絞り込み可)
+	if viewer == nil || viewer.Role != "admin" {
+		query = visibleProblems(query)
+	} else if status := q.Get("status"); status != "" {
+		query = query.Where("problems.status = ?", status)
+	}
+
+	if wind := q.Get("wind"); wind != "" {
+		query = query.Where("problems.wind = ?", wind)
+	}
+	if round := q.Get("round"); round != "" {
+		query = query.Where("problems.round = ?", round)
+	}
+	if tags := q.Get("tags"); tags != "" {
+		query = withAllTags(query, tags)
+	}
+	intFilters := []struct{ param, cond string }{
+		{"shanten", "problems.shanten = ?"},
+		{"min_votes", "COALESCE(vs.vote_count, 0) >= ?"},
+		{"max_votes", "COALESCE(vs.vote_count, 0) <= ?"},
+	}
+	for _, f := range intFilters {
+		if v := q.Get(f.param); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid "+f.param, http.StatusBadRequest)
+				return
+			}
+			query = query.Where(f.cond, n)
+		}
+	}
+	if v := q.Get("has_voted"); v != "" {
+		if viewer == nil {
+			http.Error(w, "Login required for has_voted", http.StatusUnauthorized)
+			return
+		}
+		voted := "EXISTS (SELECT 1 FROM votes mv WHERE mv.problem_id = problems.id AND mv.user_id = ? AND mv.deleted_at IS NULL)"
+		if v == "true" {
+			query = query.Where(voted, viewer.ID)
+		} else {
+			query = query.Where("NOT "+voted, viewer.ID)
+		}
+	}
+
+	// 並び順 (同じ値のときはIDの新しい順)
+	sortKey := ""
+	switch q.Get("sort") {
+	case "", "newest":
+	case "most_voted":
+		sortKey = "COALESCE(vs.vote_count, 0)"
+	case "controversial":
+		sortKey = "COALESCE(vs.vote_std_dev, 0)"
+	default:
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		if sortKey == "" {
+			query = query.Where("problems.id < ?", cursor.ID)
+		} else {
+			value, err := strconv.ParseFloat(cursor.Value, 64)
+			if err != nil {
+				http.Error(w, "Invalid cursor", http.StatusBadRequest)
+				return
+			}
+			query = query.Where(
+				fmt.Sprintf("(%s < ?) OR (%s = ? AND problems.id < ?)", sortKey, sortKey),
+				value, value, cursor.ID,
+			)
+		}
+	}
+	if sortKey != "" {
+		query = query.Order(sortKey + " DESC")
+	}
+
+	limit := parseLimit(r, 30, 100)
+	var problems []models.ProblemSummary
+	result := query.Order("problems.id DESC").Limit(limit + 1).Find(&problems)
 	if result.Error != nil {
 		http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	// 1件多く取って、次のページがあるか判定する
+	nextCursor := ""
+	if len(problems) > limit {
+		problems = problems[:limit]
+		last := problems[limit-1]
+		switch q.Get("sort") {
+		case "most_voted":
+			nextCursor = encodeCursor(strconv.FormatInt(last.VoteCount, 10), last.ID)
+		case "controversial":
+			nextCursor = encodeCursor(strconv.FormatFloat(last.VoteStdDev, 'g', -1, 64), last.ID)
+		default:
+			nextCursor = encodeCursor("", last.ID)
+		}
+	}
+
+	setNextCursor(w, nextCursor)
 	json.NewEncoder(w).Encode(problems)
 }
 
@@ -51,11 +182,16 @@ func GetProblemByID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var problem models.Problem
-	result := database.DB.First(&problem, id)
+	result := database.DB.Preload("Tags").First(&problem, id)
 	if result.Error != nil {
 		http.Error(w, "Problem not found", http.StatusNotFound)
 		return
 	}
+	// 未公開の問題は管理者のプレビューでだけ見られる
+	if !problem.IsPublished(time.Now()) && !isAdmin(r) {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
 
 	json.NewEncoder(w).Encode(problem)
 }
@@ -64,6 +200,8 @@ func GetProblemByID(w http.ResponseWriter, r *http.Request) {
 func CreateProblem(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
 
 	var problem models.Problem
 	// フロントから送られてくるJSONを解析
@@ -71,21 +209,79 @@ func CreateProblem(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	createProblem(w, r, admin, &problem, "")
+}
+
+// 問題を1件作成してレスポンスを書く (POST /problems と牌譜の取り込みで共通)
+// 牌のチェック・公開状態の既定値・重複チェック・自動タグ・監査ログまでを行う
+func createProblem(w http.ResponseWriter, r *http.Request, admin *models.User, problem *models.Problem, note string) {
+	if err := prepareNewProblem(problem); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 色を入れ替えただけの同じ問題がないか (DUPLICATE_POLICY)
+	dup, err := findDuplicate(problem.Fingerprint, 0)
+	if err != nil {
+		http.Error(w, "Failed to check duplicates", http.StatusInternalServerError)
+		return
+	}
+	if dup != nil {
+		setDuplicateOf(w, dup.ID)
+		if duplicatePolicy() == DuplicatePolicyReject && r.URL.Query().Get("allow_duplicate") != "true" {
+			http.Error(w, fmt.Sprintf("Duplicate of problem #%d", dup.ID), http.StatusConflict)
+			return
+		}
+	}
 
-	// DBに保存
-	if err := database.DB.Create(&problem).Error; err != nil {
+	// タグは PUT /problems/{id}/tags で付ける
+	problem.Tags = nil
+
+	// DBに保存 (自動タグも一緒に付ける)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(problem).Error; err != nil {
+			return err
+		}
+		return syncAutoTags(tx, problem)
+	})
+	if err != nil {
 		http.Error(w, "Failed to create problem", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(r, admin, AuditProblemCreate, problemTarget(problem.ID), nil, problem, note)
 
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(problem)
 }
 
+// 作成する問題をチェックして、保存前の項目を埋める
+// 牌のチェック・向聴数などの計算と、公開状態の既定値
+func prepareNewProblem(problem *models.Problem) error {
+	if err := problem.Analyze(); err != nil {
+		return err
+	}
+
+	// 公開状態の指定がなければ即公開 (従来どおり)
+	if problem.Status == "" {
+		problem.Status = models.ProblemStatusPublished
+	}
+	if err := validateSchedule(problem.Status, problem.PublishAt, problem.VotingClosesAt); err != nil {
+		return err
+	}
+	if problem.Status == models.ProblemStatusPublished && problem.PublishAt == nil {
+		now := time.Now()
+		problem.PublishAt = &now
+	}
+	problem.Revision, problem.MaterialRevision = 1, 1
+	return nil
+}
+
 // 4. 問題の削除 (DELETE /problems/{id})
 func DeleteProblem(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
 
 	idStr := strings.TrimPrefix(r.URL.Path, "/problems/")
 	id, err := strconv.Atoi(idStr)
@@ -94,13 +290,314 @@ func DeleteProblem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 物理削除 (本当に消す)
-	result := database.DB.Unscoped().Delete(&models.Problem{}, id)
+	// 削除前の状態を監査ログ用に取っておく
+	var problem models.Problem
+	if err := database.DB.Unscoped().First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+
+	// 通常は論理削除 (投票ごと復元できるようにする)
+	// ?purge=true の場合だけ物理削除 (投票も外部キーの CASCADE で消える)
+	query, action := database.DB, AuditProblemDelete
+	if r.URL.Query().Get("purge") == "true" {
+		query, action = query.Unscoped(), AuditProblemPurge
+	}
+	result := query.Delete(&models.Problem{}, id)
 	if result.Error != nil {
 		http.Error(w, "Failed to delete", http.StatusInternalServerError)
 		return
 	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	recordAudit(r, admin, action, problemTarget(problem.ID), problem, nil, "")
 
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Deleted"})
-}
\ No newline at end of file
+}
+
+// 5. 論理削除した問題の復元 (POST /problems/{id}/restore)
+func RestoreProblem(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/restore")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var problem models.Problem
+	if err := database.DB.Unscoped().First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	if !problem.DeletedAt.Valid {
+		http.Error(w, "Problem is not deleted", http.StatusConflict)
+		return
+	}
+
+	// 投票は問題の削除時に触っていないので、問題を戻せば集計にも戻る
+	if err := database.DB.Unscoped().Model(&problem).Update("deleted_at", nil).Error; err != nil {
+		http.Error(w, "Failed to restore", http.StatusInternalServerError)
+		return
+	}
+
+	before := problem
+	problem.DeletedAt = gorm.DeletedAt{}
+	recordAudit(r, admin, AuditProblemRestore, problemTarget(problem.ID), before, problem, "")
+
+	json.NewEncoder(w).Encode(problem)
+}
+
+// 6. 問題の編集 (PUT/PATCH /problems/{id})
+// 編集前の内容は ProblemRevision に残し、投票は消さずに引き継ぐ
+// PUT は全項目の指定が必須、PATCH は送った項目だけを変更する
+func UpdateProblem(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimPrefix(r.URL.Path, "/problems/")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var input models.ProblemUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodPut && (input.HandTiles == nil || input.DoraTiles == nil ||
+		input.Wind == nil || input.Round == nil || input.Score == nil) {
+		http.Error(w, "PUT requires hand_tiles, dora_tiles, wind, round and score", http.StatusBadRequest)
+		return
+	}
+
+	var before, problem models.Problem
+	errNotFound := errors.New("not found")
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 同時編集で版番号が重複しないよう行ロックを取る
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&problem, id).Error; err != nil {
+			return errNotFound
+		}
+		before = problem
+
+		next := problem
+		if input.HandTiles != nil { next.HandTiles = *input.HandTiles }
+		if input.DoraTiles != nil { next.DoraTiles = *input.DoraTiles }
+		if input.Wind != nil { next.Wind = *input.Wind }
+		if input.Round != nil { next.Round = *input.Round }
+		if input.Score != nil { next.Score = *input.Score }
+		if input.OpponentRiichi != nil { next.OpponentRiichi = *input.OpponentRiichi }
+		if input.Rivers != nil { next.Rivers = *input.Rivers }
+		if input.Melds != nil { next.Melds = *input.Melds }
+		if input.Scores != nil { next.Scores = *input.Scores }
+
+		if err := next.Analyze(); err != nil {
+			return invalidInput{err}
+		}
+
+		material := isMaterialChange(problem, next)
+		if input.Material != nil {
+			material = *input.Material
+		}
+
+		// 編集前の版を保存
+		revision := models.ProblemRevision{
+			ProblemID:      problem.ID,
+			Revision:       problem.Revision,
+			HandTiles:      problem.HandTiles,
+			DoraTiles:      problem.DoraTiles,
+			Wind:           problem.Wind,
+			Round:          problem.Round,
+			Score:          problem.Score,
+			OpponentRiichi: problem.OpponentRiichi,
+			Rivers:         problem.Rivers,
+			Melds:          problem.Melds,
+			Scores:         problem.Scores,
+			MaterialChange: material,
+			EditorID:       &admin.ID,
+		}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		next.Revision = problem.Revision + 1
+		if material {
+			next.MaterialRevision = next.Revision
+		}
+		problem = next
+		err := tx.Model(&problem).Select("HandTiles", "DoraTiles", "Wind", "Round", "Score", "OpponentRiichi", "Rivers", "Melds", "Scores", "Revision", "MaterialRevision", "Shanten", "Fingerprint").Updates(&problem).Error
+		if err != nil {
+			return err
+		}
+		return syncAutoTags(tx, &problem)
+	})
+	if errors.Is(err, errNotFound) {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	var invalid invalidInput
+	if errors.As(err, &invalid) {
+		http.Error(w, invalid.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to update problem", http.StatusInternalServerError)
+		return
+	}
+
+	recordAudit(r, admin, AuditProblemUpdate, problemTarget(problem.ID), before, problem, "")
+	// 編集の結果ほかの問題と同じになった場合は知らせる (まとめるかは管理者が決める)
+	if dup, _ := findDuplicate(problem.Fingerprint, problem.ID); dup != nil {
+		setDuplicateOf(w, dup.ID)
+	}
+	json.NewEncoder(w).Encode(problem)
+}
+
+// 7. 問題の編集履歴 (GET /problems/{id}/revisions)
+func GetProblemRevisions(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	// 未公開の問題の履歴は管理者だけ
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/revisions")
+	problem, ok := findVisibleProblem(w, r, idStr)
+	if !ok { return }
+
+	// 新しい版から順に返す
+	var revisions []models.ProblemRevision
+	if err := database.DB.Where("problem_id = ?", problem.ID).Order("revision DESC").Find(&revisions).Error; err != nil {
+		http.Error(w, "Failed to fetch revisions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(revisions)
+}
+
+// 8. 公開状態の変更 (POST /problems/{id}/status)
+// 例: {"status": "scheduled", "publish_at": "2026-01-01T09:00:00+09:00", "voting_closes_at": "2026-01-08T09:00:00+09:00"}
+func SetProblemStatus(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/status")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var input models.ProblemStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateSchedule(input.Status, input.PublishAt, input.VotingClosesAt); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var problem models.Problem
+	if err := database.DB.First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+
+	before := problem
+	problem.Status = input.Status
+	problem.PublishAt = input.PublishAt
+	problem.VotingClosesAt = input.VotingClosesAt
+	// 今すぐ公開する場合は公開日時を記録しておく (既に公開済みなら元の日時を残す)
+	if problem.Status == models.ProblemStatusPublished && problem.PublishAt == nil {
+		if before.IsPublished(time.Now()) && before.PublishAt != nil {
+			problem.PublishAt = before.PublishAt
+		} else {
+			now := time.Now()
+			problem.PublishAt = &now
+		}
+	}
+
+	if err := database.DB.Model(&problem).Select("Status", "PublishAt", "VotingClosesAt").Updates(&problem).Error; err != nil {
+		http.Error(w, "Failed to update status", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditProblemStatus, problemTarget(problem.ID), before, problem, "")
+
+	json.NewEncoder(w).Encode(problem)
+}
+
+// トランザクション内で見つかった入力エラー (400 で返す)
+type invalidInput struct{ error }
+
+// 手牌の評価が変わる変更かどうか
+// 牌の並び順や表記ゆれだけの修正は該当しない。自分の持ち点 (Score) だけの修正も該当しない扱いにする
+// (4人の点数 Scores は点差が分かり押し引きが変わるので該当する)
+func isMaterialChange(before, after models.Problem) bool {
+	return !sameTiles(before.HandTiles, after.HandTiles) ||
+		!sameTiles(before.DoraTiles, after.DoraTiles) ||
+		before.Wind != after.Wind ||
+		before.Round != after.Round ||
+		before.OpponentRiichi != after.OpponentRiichi ||
+		!sameTable(before, after)
+}
+
+// 河・副露・4人の点数が同じか (空文字と空の配列は同じとみなす)
+// 他家の河や副露、点差が変われば押し引きの判断も変わる
+func sameTable(before, after models.Problem) bool {
+	rb, errB := mahjong.ParseRivers(before.Rivers)
+	ra, errA := mahjong.ParseRivers(after.Rivers)
+	if errB != nil || errA != nil {
+		return before.Rivers == after.Rivers
+	}
+	mb, errB := mahjong.ParseMelds(before.Melds)
+	ma, errA := mahjong.ParseMelds(after.Melds)
+	if errB != nil || errA != nil {
+		return before.Melds == after.Melds
+	}
+	for seat := range rb {
+		if !slices.Equal(rb[seat], ra[seat]) || len(mb[seat]) != len(ma[seat]) {
+			return false
+		}
+		for i := range mb[seat] {
+			b, a := mb[seat][i], ma[seat][i]
+			if b.Type != a.Type || b.Called != a.Called || b.From != a.From || !slices.Equal(b.Tiles, a.Tiles) {
+				return false
+			}
+		}
+	}
+	var sb, sa []int
+	json.Unmarshal([]byte(before.Scores), &sb)
+	json.Unmarshal([]byte(after.Scores), &sa)
+	return slices.Equal(sb, sa)
+}
+
+// 2つの牌リスト (JSON配列の文字列) が同じ牌の組み合わせか
+func sameTiles(a, b string) bool {
+	var x, y []int
+	if json.Unmarshal([]byte(a), &x) != nil || json.Unmarshal([]byte(b), &y) != nil {
+		return a == b
+	}
+	if len(x) != len(y) {
+		return false
+	}
+	sort.Ints(x)
+	sort.Ints(y)
+	for i := range x {
+		if x[i] != y[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"portfolio-backend/models"
+	"testing"
+)
+
+func TestIsMaterialChange(t *testing.T) {
+	base := models.Problem{
+		HandTiles: "[0,1,2,9,10,11,18,19,20,27,27,31,31,32]",
+		DoraTiles: "[28]",
+		Wind:      "East",
+		Round:     "East-1",
+		Score:     25000,
+		Rivers:    `[[{"tile":33}],[],[],[]]`,
+		Melds:     `[[],[],[{"type":"pon","tiles":[27,27,27],"called":27,"from":1}],[]]`,
+		Scores:    "[25000,25000,25000,25000]",
+	}
+	tests := []struct {
+		name     string
+		edit     func(p *models.Problem)
+		material bool
+	}{
+		{"tile order only", func(p *models.Problem) { p.HandTiles = "[32,0,1,2,9,10,11,18,19,20,27,27,31,31]" }, false},
+		{"own score only", func(p *models.Problem) { p.Score = 30000 }, false},
+		{"table cleared", func(p *models.Problem) { p.Rivers, p.Melds = "", "" }, true},
+		{"hand", func(p *models.Problem) { p.HandTiles = "[0,1,2,9,10,11,18,19,20,27,27,31,31,33]" }, true},
+		{"river", func(p *models.Problem) { p.Rivers = `[[{"tile":33,"tsumogiri":true}],[],[],[]]` }, true},
+		{"meld", func(p *models.Problem) { p.Melds = `[[],[],[{"type":"pon","tiles":[27,27,27],"called":27,"from":3}],[]]` }, true},
+		{"scores", func(p *models.Problem) { p.Scores = "[30000,20000,25000,25000]" }, true},
+	}
+	for _, tt := range tests {
+		after := base
+		tt.edit(&after)
+		if got := isMaterialChange(base, after); got != tt.material {
+			t.Errorf("%s: isMaterialChange = %v, want %v", tt.name, got, tt.material)
+		}
+	}
+
+	// 河・副露なしは空文字でも空の配列でも同じ
+	empty := models.Problem{HandTiles: base.HandTiles, DoraTiles: base.DoraTiles}
+	emptyArrays := empty
+	emptyArrays.Rivers, emptyArrays.Melds, emptyArrays.Scores = "[[],[],[],[]]", "[[],[],[],[]]", "[]"
+	if isMaterialChange(empty, emptyArrays) {
+		t.Error("an empty table should not count as a material change")
+	}
+}
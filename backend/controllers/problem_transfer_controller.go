@@ -0,0 +1,410 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 一括取り込みの1件 (読み込めなかった行は err に理由が入る)
+type importRow struct {
+	row models.ProblemTransferRow
+	err error
+}
+
+// 問題をまとめて取り込む (POST /admin/problems/import)
+// JSON 配列か CSV (見出し付き、列は models.ProblemTransferColumns) を送る
+//
+//	?format=json|csv  省略時は Content-Type と中身から判定
+//	?dry_run=true     検証だけして保存しない
+//	?atomic=true      1件でもエラーがあれば何も保存しない (全件を1つのトランザクションで保存)
+//
+// 行ごとのエラーは結果の rows に入る。重複の扱いは DUPLICATE_POLICY に従う
+func ImportProblems(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := readUpload(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var rows []importRow
+	switch transferFormat(r, data) {
+	case "csv":
+		rows, err = decodeTransferCSV(data)
+	default:
+		rows, err = decodeTransferJSON(data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(rows) == 0 {
+		http.Error(w, "No problems to import", http.StatusBadRequest)
+		return
+	}
+
+	result := models.ProblemImportResult{
+		DryRun: r.URL.Query().Get("dry_run") == "true",
+		Atomic: r.URL.Query().Get("atomic") == "true",
+		Total:  len(rows),
+		Rows:   make([]models.ProblemImportRowResult, len(rows)),
+	}
+	allowDuplicate := duplicatePolicy() != DuplicatePolicyReject || r.URL.Query().Get("allow_duplicate") == "true"
+
+	// 全件を検証する
+	problems := make([]*models.Problem, len(rows))
+	seen := map[string]int{} // 指紋 -> 最初に出てきた行
+	for i, in := range rows {
+		res := &result.Rows[i]
+		res.Row = i + 1
+		problem, err := validateImportRow(in)
+		if err != nil {
+			res.Error = err.Error()
+			continue
+		}
+
+		if first, ok := seen[problem.Fingerprint]; ok {
+			res.DuplicateOfRow = first
+		} else {
+			seen[problem.Fingerprint] = res.Row
+		}
+		dup, err := findDuplicate(problem.Fingerprint, 0)
+		if err != nil {
+			http.Error(w, "Failed to check duplicates", http.StatusInternalServerError)
+			return
+		}
+		if dup != nil {
+			res.DuplicateOf = &dup.ID
+		}
+		if !allowDuplicate && (res.DuplicateOf != nil || res.DuplicateOfRow != 0) {
+			res.Error = "duplicate problem"
+			continue
+		}
+		problems[i] = problem
+		result.Valid++
+	}
+
+	if result.DryRun {
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+	if result.Atomic && result.Valid < result.Total {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	// 保存する (atomic なら全件で1つのトランザクション、そうでなければ1件ずつ)
+	save := func(tx *gorm.DB, i int) error {
+		if err := tx.Create(problems[i]).Error; err != nil {
+			return err
+		}
+		if err := syncAutoTags(tx, problems[i]); err != nil {
+			return err
+		}
+		return replaceManualTags(tx, problems[i].ID, rows[i].row.Tags)
+	}
+	var created []int
+	if result.Atomic {
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			for i := range problems {
+				if err := save(tx, i); err != nil {
+					return fmt.Errorf("row %d: %w", i+1, err)
+				}
+				created = append(created, i)
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(w, "Failed to import problems: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		for i := range problems {
+			if problems[i] == nil {
+				continue
+			}
+			err := database.DB.Transaction(func(tx *gorm.DB) error { return save(tx, i) })
+			if err != nil {
+				result.Rows[i].Error = "failed to save problem"
+				continue
+			}
+			created = append(created, i)
+		}
+	}
+
+	for _, i := range created {
+		result.Rows[i].ID = problems[i].ID
+		recordAudit(r, admin, AuditProblemCreate, problemTarget(problems[i].ID), nil, problems[i], fmt.Sprintf("bulk import row %d", i+1))
+	}
+	result.Created = len(created)
+
+	if result.Created > 0 {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// 読み込んだ1件を問題にして、作成前のチェックをする
+func validateImportRow(in importRow) (*models.Problem, error) {
+	if in.err != nil {
+		return nil, in.err
+	}
+	problem, err := in.row.Problem()
+	if err != nil {
+		return nil, err
+	}
+	if err := prepareNewProblem(problem); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
+
+// 取り込み・書き出しの形式 (?format=、なければ Content-Type、それもなければ中身の先頭で判定)
+func transferFormat(r *http.Request, data []byte) string {
+	switch format := strings.ToLower(r.URL.Query().Get("format")); format {
+	case "json", "csv":
+		return format
+	}
+	if strings.Contains(r.Header.Get("Content-Type"), "csv") {
+		return "csv"
+	}
+	if data != nil && !bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")) {
+		return "csv"
+	}
+	return "json"
+}
+
+// JSON 配列を読む (要素ごとに読めなければその行のエラーにする)
+func decodeTransferJSON(data []byte) ([]importRow, error) {
+	var raws []json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, errors.New("body must be a JSON array of problems")
+	}
+	rows := make([]importRow, len(raws))
+	for i, raw := range raws {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		rows[i].err = dec.Decode(&rows[i].row)
+	}
+	return rows, nil
+}
+
+// CSV を読む (1行目は見出し。未知の列や hand のない見出しはファイル全体のエラー)
+func decodeTransferCSV(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV needs a header row")
+	}
+
+	header := records[0]
+	known := map[string]bool{}
+	for _, name := range models.ProblemTransferColumns {
+		known[name] = true
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return nil, fmt.Errorf("unknown CSV column %q", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["hand"]; !ok {
+		return nil, errors.New("CSV needs a hand column")
+	}
+
+	var rows []importRow
+	for _, record := range records[1:] {
+		// 空行は飛ばす
+		if len(record) == 1 && strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		if len(record) != len(header) {
+			rows = append(rows, importRow{err: fmt.Errorf("expected %d columns, got %d", len(header), len(record))})
+			continue
+		}
+		row, err := parseTransferRecord(record, columns)
+		rows = append(rows, importRow{row: row, err: err})
+	}
+	return rows, nil
+}
+
+// CSV の1行を読む
+func parseTransferRecord(record []string, columns map[string]int) (models.ProblemTransferRow, error) {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row := models.ProblemTransferRow{
+		Hand:   get("hand"),
+		Dora:   get("dora"),
+		Wind:   get("wind"),
+		Round:  get("round"),
+		Status: get("status"),
+		Rivers: get("rivers"),
+		Melds:  get("melds"),
+		Scores: get("scores"),
+	}
+	var err error
+	if s := get("score"); s != "" {
+		if row.Score, err = strconv.Atoi(s); err != nil {
+			return row, errors.New("score must be a number")
+		}
+	}
+	if s := get("opponent_riichi"); s != "" {
+		if row.OpponentRiichi, err = strconv.ParseBool(s); err != nil {
+			return row, errors.New("opponent_riichi must be true or false")
+		}
+	}
+	if row.PublishAt, err = parseTransferTime(get("publish_at")); err != nil {
+		return row, errors.New("publish_at must be RFC 3339")
+	}
+	if row.VotingClosesAt, err = parseTransferTime(get("voting_closes_at")); err != nil {
+		return row, errors.New("voting_closes_at must be RFC 3339")
+	}
+	for _, tag := range strings.Split(get("tags"), "|") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			row.Tags = append(row.Tags, tag)
+		}
+	}
+	return row, nil
+}
+
+func parseTransferTime(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func formatTransferTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// 問題をまとめて書き出す (GET /admin/problems/export)
+// 取り込みと同じ形式なので、そのまま別の環境に取り込める
+//
+//	?format=json|csv   既定は json
+//	?status=draft など 公開状態で絞り込む
+//	?collection=1      問題集の問題だけ (問題集の並び順)
+func ExportProblems(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	if _, ok := requireAdmin(w, r); !ok { return }
+
+	query := database.DB.Model(&models.Problem{})
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("problems.status = ?", status)
+	}
+	if s := r.URL.Query().Get("collection"); s != "" {
+		collectionID, err := strconv.Atoi(s)
+		if err != nil {
+			http.Error(w, "Invalid collection", http.StatusBadRequest)
+			return
+		}
+		query = query.Joins("JOIN collection_items ON collection_items.problem_id = problems.id").
+			Where("collection_items.collection_id = ?", collectionID).
+			Order("collection_items.position")
+	}
+	var problems []models.Problem
+	if err := query.Order("problems.id").Find(&problems).Error; err != nil {
+		http.Error(w, "Failed to fetch problems", http.StatusInternalServerError)
+		return
+	}
+
+	// 手動のタグ (自動タグは取り込むときに計算し直す)
+	ids := make([]uint, len(problems))
+	for i, p := range problems {
+		ids[i] = p.ID
+	}
+	var links []struct {
+		ProblemID uint
+		Name      string
+	}
+	err := database.DB.Table("problem_tags").
+		Select("problem_tags.problem_id, tags.name").
+		Joins("JOIN tags ON tags.id = problem_tags.tag_id").
+		Where("problem_tags.problem_id IN ? AND problem_tags.source = ?", ids, models.TagSourceManual).
+		Order("tags.name").
+		Scan(&links).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+	tags := map[uint][]string{}
+	for _, l := range links {
+		tags[l.ProblemID] = append(tags[l.ProblemID], l.Name)
+	}
+
+	rows := make([]models.ProblemTransferRow, len(problems))
+	for i := range problems {
+		rows[i] = models.NewProblemTransferRow(&problems[i], tags[problems[i].ID])
+	}
+
+	filename := "problems-" + time.Now().Format("20060102")
+	if transferFormat(r, nil) == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		writeTransferCSV(w, rows)
+		return
+	}
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	json.NewEncoder(w).Encode(rows)
+}
+
+// 書き出す問題を CSV にする (列は models.ProblemTransferColumns の順)
+func writeTransferCSV(w http.ResponseWriter, rows []models.ProblemTransferRow) {
+	out := csv.NewWriter(w)
+	out.Write(models.ProblemTransferColumns)
+	for _, row := range rows {
+		out.Write([]string{
+			row.Hand,
+			row.Dora,
+			row.Wind,
+			row.Round,
+			strconv.Itoa(row.Score),
+			strconv.FormatBool(row.OpponentRiichi),
+			row.Status,
+			formatTransferTime(row.PublishAt),
+			formatTransferTime(row.VotingClosesAt),
+			strings.Join(row.Tags, "|"),
+			row.Rivers,
+			row.Melds,
+			row.Scores,
+		})
+	}
+	out.Flush()
+}
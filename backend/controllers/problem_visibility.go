@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"errors"
+	"portfolio-backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const visibleProblemCond = "(problems.status = ? OR (problems.status = ? AND problems.publish_at <= ?))"
+
+// 一般ユーザーに見える問題 (公開中、または予約公開の時刻を過ぎたもの) だけに絞り込む
+func visibleProblems(db *gorm.DB) *gorm.DB {
+	return db.Where(visibleProblemCond, visibleProblemArgs()...)
+}
+
+// LEFT JOIN problems の ON 句に公開中の条件を足す
+// (WHERE で絞ると問題が0件のタグや問題集が一覧から消えてしまうため ON 句に入れる)
+func joinVisibleProblems(db *gorm.DB, join string) *gorm.DB {
+	return db.Joins(join+" AND "+visibleProblemCond, visibleProblemArgs()...)
+}
+
+func visibleProblemArgs() []interface{} {
+	return []interface{}{models.ProblemStatusPublished, models.ProblemStatusScheduled, time.Now()}
+}
+
+// 公開状態と日時の組み合わせをチェックする
+func validateSchedule(status string, publishAt, closesAt *time.Time) error {
+	switch status {
+	case models.ProblemStatusDraft, models.ProblemStatusPublished:
+	case models.ProblemStatusScheduled:
+		if publishAt == nil {
+			return errors.New("publish_at is required for scheduled problems")
+		}
+	default:
+		return errors.New("invalid status")
+	}
+	if closesAt != nil && publishAt != nil && !closesAt.After(*publishAt) {
+		return errors.New("voting_closes_at must be after publish_at")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"portfolio-backend/models"
+	"testing"
+	"time"
+)
+
+func TestProblemPublishedAndVotingOpen(t *testing.T) {
+	now := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	before, after := now.Add(-time.Minute), now.Add(time.Minute)
+	tests := []struct {
+		name      string
+		problem   models.Problem
+		published bool
+		open      bool
+	}{
+		{"draft", models.Problem{Status: models.ProblemStatusDraft}, false, false},
+		{"published", models.Problem{Status: models.ProblemStatusPublished}, true, true},
+		{"scheduled, not yet", models.Problem{Status: models.ProblemStatusScheduled, PublishAt: &after}, false, false},
+		{"scheduled, exactly now", models.Problem{Status: models.ProblemStatusScheduled, PublishAt: &now}, true, true},
+		{"scheduled without publish_at", models.Problem{Status: models.ProblemStatusScheduled}, false, false},
+		{"voting closes later", models.Problem{Status: models.ProblemStatusPublished, VotingClosesAt: &after}, true, true},
+		{"voting closes exactly now", models.Problem{Status: models.ProblemStatusPublished, VotingClosesAt: &now}, true, false},
+		{"voting closed", models.Problem{Status: models.ProblemStatusPublished, VotingClosesAt: &before}, true, false},
+		{"unknown status", models.Problem{Status: "archived"}, false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.problem.IsPublished(now); got != tt.published {
+			t.Errorf("%s: IsPublished = %v, want %v", tt.name, got, tt.published)
+		}
+		if got := tt.problem.IsVotingOpen(now); got != tt.open {
+			t.Errorf("%s: IsVotingOpen = %v, want %v", tt.name, got, tt.open)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	publish := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	closes := publish.Add(24 * time.Hour)
+	tests := []struct {
+		name      string
+		status    string
+		publishAt *time.Time
+		closesAt  *time.Time
+		ok        bool
+	}{
+		{"draft", models.ProblemStatusDraft, nil, nil, true},
+		{"published with deadline", models.ProblemStatusPublished, &publish, &closes, true},
+		{"scheduled", models.ProblemStatusScheduled, &publish, nil, true},
+		{"scheduled without publish_at", models.ProblemStatusScheduled, nil, nil, false},
+		{"closes before publishing", models.ProblemStatusScheduled, &closes, &publish, false},
+		{"closes when publishing", models.ProblemStatusScheduled, &publish, &publish, false},
+		{"unknown status", "archived", nil, nil, false},
+	}
+	for _, tt := range tests {
+		if err := validateSchedule(tt.status, tt.publishAt, tt.closesAt); (err == nil) != tt.ok {
+			t.Errorf("%s: validateSchedule = %v, want ok = %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"portfolio-backend/rating"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ユーザーのレーティングと変動履歴を取得 (GET /users/{id}/rating)
+// 履歴は新しい順。本人か管理者だけが見られる (履歴から各問題の投票が分かるので)
+//
+//	limit=   1ページの件数 (デフォルト50, 最大200)
+//	cursor=  前のレスポンスの X-Next-Cursor ヘッダーの値
+func GetUserRating(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+
+	// URLからユーザーIDを抽出 (/users/123/rating -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	viewer, ok := requireUser(w, r)
+	if !ok { return }
+	if viewer.Role != "admin" && int(viewer.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	limit := parseLimit(r, 50, 200)
+	query := database.DB.Where("user_id = ?", user.ID)
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		settledAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("(settled_at < ?) OR (settled_at = ? AND id < ?)", settledAt, settledAt, cursor.ID)
+	}
+
+	history := []models.RatingHistory{}
+	if err := query.Order("settled_at DESC, id DESC").Limit(limit + 1).Find(&history).Error; err != nil {
+		http.Error(w, "Failed to fetch rating history", http.StatusInternalServerError)
+		return
+	}
+
+	// 1件多く取って、次のページがあるか判定する
+	nextCursor := ""
+	if len(history) > limit {
+		history = history[:limit]
+		last := history[limit-1]
+		nextCursor = encodeCursor(last.SettledAt.Format(time.RFC3339Nano), last.ID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	setNextCursor(w, nextCursor)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":          user.ID,
+		"rating":           user.Rating,
+		"rating_deviation": user.RatingDeviation,
+		"rated_votes":      user.RatedVotes,
+		"history":          history,
+	})
+}
+
+// 全員のレーティングを計算し直す (POST /admin/ratings/recompute)
+// 計算方法や RATING_* の設定を変えたとき用。合意が決まった全ての問題を決まった順に反映し直す
+func RecomputeRatings(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	admin, ok := requireAdmin(w, r)
+	if !ok { return }
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	problems, votes, err := rating.Recompute(time.Now())
+	if err != nil {
+		http.Error(w, "Failed to recompute ratings", http.StatusInternalServerError)
+		return
+	}
+	clearLeaderboardCache()
+
+	result := map[string]int{"problems": problems, "votes": votes}
+	recordAudit(r, admin, AuditRatingRecalc, auditTarget{Type: "rating"}, nil, result, "")
+	json.NewEncoder(w).Encode(result)
+}
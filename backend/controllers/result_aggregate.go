@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"math"
+	"portfolio-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// DBで集計した投票の統計
+type voteAggregate struct {
+	voteSummary
+	Spec      histogramSpec // ヒストグラムの区切り方
+	Histogram []int         // 区間ごとの人数
+}
+
+// 1回の集計クエリで求める値
+type voteSummary struct {
+	Count        int
+	Mean         float64
+	StdDev       float64 // 母標準偏差
+	SampleStdDev float64 // 標本標準偏差 (信頼区間に使う)
+
+	// 四分位数 (PERCENTILE_CONT なので区間の間は線形補間)
+	Q1, Median, Q3 float64
+
+	// 点数の2乗・3乗・4乗の平均 (歪度・尖度を求めるのに使う)
+	Raw2, Raw3, Raw4 float64
+}
+
+// 投票の統計を SQL で集計する
+// votes は集計対象の投票を返すクエリ (countedVotes など)。集計ごとに新しく作るので関数で受け取る
+// bins はヒストグラムの区切り方
+func aggregateVotes(votes func() *gorm.DB, bins histogramParams) (voteAggregate, error) {
+	var agg voteAggregate
+
+	err := votes().Select(`COUNT(*) AS count,
+		COALESCE(AVG(votes.point), 0)::float8 AS mean,
+		COALESCE(STDDEV_POP(votes.point), 0)::float8 AS std_dev,
+		COALESCE(STDDEV_SAMP(votes.point), 0)::float8 AS sample_std_dev,
+		COALESCE(PERCENTILE_CONT(0.25) WITHIN GROUP (ORDER BY votes.point), 0)::float8 AS q1,
+		COALESCE(PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY votes.point), 0)::float8 AS median,
+		COALESCE(PERCENTILE_CONT(0.75) WITHIN GROUP (ORDER BY votes.point), 0)::float8 AS q3,
+		COALESCE(AVG(POWER(votes.point, 2)), 0)::float8 AS raw2,
+		COALESCE(AVG(POWER(votes.point, 3)), 0)::float8 AS raw3,
+		COALESCE(AVG(POWER(votes.point, 4)), 0)::float8 AS raw4`).
+		Scan(&agg.voteSummary).Error
+	if err != nil {
+		return agg, err
+	}
+	// 区間の数を自動で決めるときは件数・四分位数を使うので、集計が済んでから決める
+	agg.Spec = bins.spec(agg.voteSummary)
+	agg.Histogram = make([]int, agg.Spec.Count)
+	if agg.Count == 0 {
+		return agg, nil
+	}
+
+	// 範囲外の票はヒストグラムには数えない
+	binExpr, args := agg.Spec.binExpr()
+	var rows []struct {
+		Bin   int
+		Count int
+	}
+	err = votes().Select(binExpr+" AS bin, COUNT(*) AS count", args...).
+		Where("votes.point BETWEEN ? AND ?", agg.Spec.Min, agg.Spec.Max).
+		Group("bin").
+		Scan(&rows).Error
+	if err != nil {
+		return agg, err
+	}
+	for _, b := range rows {
+		agg.Histogram[b.Bin] = b.Count
+	}
+	return agg, nil
+}
+
+// ある点数より低い票・同じ点数の票の数
+type scoreRank struct {
+	Below, Equal int
+}
+
+// 点数のパーセンタイル順位を求めるために、その点数の前後の票を数える
+func rankScore(votes func() *gorm.DB, score int) (scoreRank, error) {
+	var rank scoreRank
+	err := votes().Select(`COUNT(*) FILTER (WHERE votes.point < ?) AS below,
+		COUNT(*) FILTER (WHERE votes.point = ?) AS equal`, score, score).
+		Scan(&rank).Error
+	return rank, err
+}
+
+// 点数が全体の中でどのあたりか (偏差値とパーセンタイル順位)
+func scoreStanding(agg voteAggregate, score int, rank scoreRank) models.ScoreStanding {
+	standing := models.ScoreStanding{Score: score}
+	if agg.Count == 0 {
+		return standing
+	}
+
+	// 偏差値 (T-score) = (得点 - 平均) / 標準偏差 * 10 + 50
+	var deviationValue float64 = 50
+	if agg.StdDev > 0 {
+		deviationValue = 50 + 10*(float64(score)-agg.Mean)/agg.StdDev
+	}
+	percentile := (float64(rank.Below) + float64(rank.Equal)/2) / float64(agg.Count) * 100
+
+	standing.Dev = round1(deviationValue)
+	standing.Percentile = round1(percentile)
+	return standing
+}
+
+// 集計結果をレスポンスの形にする (あなたの点数は呼び出し側で入れる)
+func resultResponse(agg voteAggregate) models.ResultResponse {
+	if agg.Count == 0 {
+		return models.ResultResponse{}
+	}
+
+	var histogramData []models.HistogramBin
+	labels, edges := agg.Spec.labels(), agg.Spec.edges()
+	for i, c := range agg.Histogram {
+		histogramData = append(histogramData, models.HistogramBin{
+			Range: labels[i],
+			From:  edges[i],
+			To:    edges[i+1],
+			Count: c,
+		})
+	}
+
+	return models.ResultResponse{
+		Average:    round1(agg.Mean), // 小数点第1位まで
+		StdDev:     round1(agg.StdDev),
+		VoteCount:  agg.Count,
+		Median:     round1(agg.Median),
+		Q1:         round1(agg.Q1),
+		Q3:         round1(agg.Q3),
+		MeanCI:     meanConfidenceInterval(agg),
+		Bimodality: bimodality(agg),
+		Histogram:  histogramData,
+	}
+}
+
+func round1(x float64) float64 { return math.Round(x*10) / 10 }
+
+// 平均の95%信頼区間 (t分布による区間推定)
+func meanConfidenceInterval(agg voteAggregate) *models.ConfidenceInterval {
+	if agg.Count < 2 {
+		return nil
+	}
+	margin := tCritical95(agg.Count-1) * agg.SampleStdDev / math.Sqrt(float64(agg.Count))
+	return &models.ConfidenceInterval{
+		Level: 0.95,
+		Low:   round1(agg.Mean - margin),
+		High:  round1(agg.Mean + margin),
+	}
+}
+
+// t分布の両側95%点 (自由度30まで。それより大きければ正規分布の1.96で近似する)
+var tTable95 = []float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+func tCritical95(df int) float64 {
+	if df <= len(tTable95) {
+		return tTable95[df-1]
+	}
+	return 1.96
+}
+
+// 二峰性の判定に使う境界 (一様分布の bimodality coefficient)
+const bimodalThreshold = 5.0 / 9.0
+
+// Sarle の bimodality coefficient
+// BC = (歪度^2 + 1) / (尖度 + 3(n-1)^2 / ((n-2)(n-3)))  (歪度・尖度は標本の補正をしたもの)
+func bimodality(agg voteAggregate) *models.Bimodality {
+	n := float64(agg.Count)
+	if agg.Count < 4 {
+		return nil
+	}
+	// 原点まわりのモーメントから平均まわりのモーメントを求める
+	mean := agg.Mean
+	m2 := agg.Raw2 - mean*mean
+	m3 := agg.Raw3 - 3*mean*agg.Raw2 + 2*math.Pow(mean, 3)
+	m4 := agg.Raw4 - 4*mean*agg.Raw3 + 6*mean*mean*agg.Raw2 - 3*math.Pow(mean, 4)
+	if m2 <= 1e-9 {
+		return nil
+	}
+
+	skewness := math.Sqrt(n*(n-1)) / (n - 2) * m3 / math.Pow(m2, 1.5)
+	g2 := m4/(m2*m2) - 3
+	kurtosis := (n - 1) / ((n - 2) * (n - 3)) * ((n+1)*g2 + 6)
+	coefficient := (skewness*skewness + 1) / (kurtosis + 3*(n-1)*(n-1)/((n-2)*(n-3)))
+
+	return &models.Bimodality{
+		Coefficient: math.Round(coefficient*1000) / 1000,
+		Bimodal:     coefficient > bimodalThreshold,
+	}
+}
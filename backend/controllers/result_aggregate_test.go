@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"math"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"reflect"
+	"sort"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// 以前の実装 (投票を全部読み込んで Go で集計する) と同じ計算
+// SQL での集計が同じ結果になるかを比べるのに使う
+func resultInMemory(points []int, myScore int) models.ResultResponse {
+	if len(points) == 0 {
+		return models.ResultResponse{}
+	}
+	var sum float64
+	histogramCounts := make([]int, 11)
+	for _, p := range points {
+		sum += float64(p)
+		idx := p / 10
+		if idx > 10 {
+			idx = 10
+		}
+		histogramCounts[idx]++
+	}
+	count := float64(len(points))
+	average := sum / count
+	var varianceSum float64
+	for _, p := range points {
+		varianceSum += math.Pow(float64(p)-average, 2)
+	}
+	stdDev := math.Sqrt(varianceSum / count)
+	var deviationValue float64 = 50
+	if stdDev > 0 {
+		deviationValue = 50 + 10*(float64(myScore)-average)/stdDev
+	}
+	labels := []string{"0-9", "10-19", "20-29", "30-39", "40-49", "50-59", "60-69", "70-79", "80-89", "90-99", "100"}
+	var histogram []models.HistogramBin
+	for i, c := range histogramCounts {
+		histogram = append(histogram, models.HistogramBin{
+			Range: labels[i],
+			From:  float64(i * 10),
+			To:    math.Min(float64(i*10+10), 100),
+			Count: c,
+		})
+	}
+
+	// 四分位数 (PERCENTILE_CONT と同じ線形補間)
+	sorted := append([]int(nil), points...)
+	sort.Ints(sorted)
+	quantile := func(p float64) float64 {
+		h := p * float64(len(sorted)-1)
+		lo := int(h)
+		if lo+1 >= len(sorted) {
+			return float64(sorted[lo])
+		}
+		return float64(sorted[lo]) + (h-float64(lo))*float64(sorted[lo+1]-sorted[lo])
+	}
+	var below, equal float64
+	for _, p := range points {
+		if p < myScore {
+			below++
+		} else if p == myScore {
+			equal++
+		}
+	}
+
+	res := models.ResultResponse{
+		Average:        math.Round(average*10) / 10,
+		StdDev:         math.Round(stdDev*10) / 10,
+		UserScore:      myScore,
+		UserDev:        math.Round(deviationValue*10) / 10,
+		VoteCount:      len(points),
+		Median:         round1(quantile(0.5)),
+		Q1:             round1(quantile(0.25)),
+		Q3:             round1(quantile(0.75)),
+		UserPercentile: round1((below + equal/2) / count * 100),
+		Histogram:      histogram,
+	}
+	if n := count; n >= 2 {
+		margin := tCritical95(len(points)-1) * math.Sqrt(varianceSum/(n-1)) / math.Sqrt(n)
+		res.MeanCI = &models.ConfidenceInterval{Level: 0.95, Low: round1(average - margin), High: round1(average + margin)}
+	}
+	// 平均まわりのモーメントを直接求める
+	if n := count; n >= 4 && stdDev > 0 {
+		var m2, m3, m4 float64
+		for _, p := range points {
+			d := float64(p) - average
+			m2 += d * d / n
+			m3 += d * d * d / n
+			m4 += d * d * d * d / n
+		}
+		skewness := math.Sqrt(n*(n-1)) / (n - 2) * m3 / math.Pow(m2, 1.5)
+		kurtosis := (n - 1) / ((n - 2) * (n - 3)) * ((n+1)*(m4/(m2*m2)-3) + 6)
+		bc := (skewness*skewness + 1) / (kurtosis + 3*(n-1)*(n-1)/((n-2)*(n-3)))
+		res.Bimodality = &models.Bimodality{Coefficient: math.Round(bc*1000) / 1000, Bimodal: bc > 5.0/9.0}
+	}
+	return res
+}
+
+func TestAggregateVotesMatchesInMemory(t *testing.T) {
+	database.Connect()
+
+	tests := []struct {
+		name    string
+		points  []int
+		myScore int
+	}{
+		{"no votes", nil, 50},
+		{"single vote", []int{70}, 80},
+		{"all the same", []int{40, 40, 40}, 10},
+		{"spread with 100", []int{0, 5, 9, 10, 55, 99, 100, 100}, 100},
+		{"bimodal", []int{10, 12, 15, 18, 85, 88, 90, 93, 95}, 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := models.Problem{HandTiles: "[0]", DoraTiles: "[1]", Wind: "East", Round: "East-1", Score: 25000}
+			if err := database.DB.Create(&problem).Error; err != nil {
+				t.Fatalf("Failed to create problem: %v", err)
+			}
+			defer database.DB.Unscoped().Delete(&problem)
+			for _, p := range tt.points {
+				if err := database.DB.Create(&models.Vote{ProblemID: problem.ID, Point: p}).Error; err != nil {
+					t.Fatalf("Failed to create vote: %v", err)
+				}
+			}
+
+			votes := func() *gorm.DB { return countedVotes(int(problem.ID)) }
+			agg, err := aggregateVotes(votes, histogramParams{Default: true})
+			if err != nil {
+				t.Fatalf("aggregateVotes: %v", err)
+			}
+			rank, err := rankScore(votes, tt.myScore)
+			if err != nil {
+				t.Fatalf("rankScore: %v", err)
+			}
+			got := resultResponse(agg)
+			if len(tt.points) > 0 {
+				s := scoreStanding(agg, tt.myScore, rank)
+				got.UserScore, got.UserDev, got.UserPercentile = s.Score, s.Dev, s.Percentile
+			}
+			want := resultInMemory(tt.points, tt.myScore)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("SQL result = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// 点数の一覧から SQL で集計するのと同じ値を作る (DBなしで確かめる用)
+func summaryOf(points []int) voteAggregate {
+	agg := voteAggregate{Spec: defaultHistogram, Histogram: make([]int, defaultHistogram.Count)}
+	agg.Count = len(points)
+	for _, p := range points {
+		x := float64(p)
+		agg.Mean += x / float64(len(points))
+		agg.Raw2 += x * x / float64(len(points))
+		agg.Raw3 += x * x * x / float64(len(points))
+		agg.Raw4 += x * x * x * x / float64(len(points))
+	}
+	agg.StdDev = math.Sqrt(math.Max(agg.Raw2-agg.Mean*agg.Mean, 0))
+	return agg
+}
+
+func TestBimodality(t *testing.T) {
+	tests := []struct {
+		name    string
+		points  []int
+		bimodal bool
+	}{
+		{"split between 10 and 90", []int{10, 10, 10, 10, 10, 10, 90, 90, 90, 90}, true},
+		{"single peak", []int{45, 48, 50, 50, 50, 52, 55, 60, 40, 50}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bimodality(summaryOf(tt.points))
+			if b == nil || b.Bimodal != tt.bimodal {
+				t.Errorf("bimodality = %+v, want bimodal %v", b, tt.bimodal)
+			}
+		})
+	}
+	if b := bimodality(summaryOf([]int{50, 50, 50, 50})); b != nil {
+		t.Errorf("bimodality of identical votes = %+v, want nil", b)
+	}
+}
+
+func TestScoreStanding(t *testing.T) {
+	// 50点より下が2票、50点が2票 -> (2 + 2/2) / 5
+	s := scoreStanding(summaryOf([]int{10, 20, 50, 50, 90}), 50, scoreRank{Below: 2, Equal: 2})
+	if s.Percentile != 60 {
+		t.Errorf("percentile = %v, want 60", s.Percentile)
+	}
+	// 平均 44 点、標準偏差 28 -> 偏差値 52.1
+	if s.Dev != 52.1 {
+		t.Errorf("dev = %v, want 52.1", s.Dev)
+	}
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"os"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/models"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 層ごとの集計を出すのに必要な最低票数のデフォルト
+// これより少ない層は個人の投票が推測できてしまうので、件数も含めて出さない
+const defaultCohortMinVotes = 5
+
+// 層ごとの集計を出すのに必要な最低票数 (環境変数 COHORT_MIN_VOTES、デフォルト5)
+func cohortMinVotes() int {
+	if n, err := strconv.Atoi(os.Getenv("COHORT_MIN_VOTES")); err == nil && n > 0 {
+		return n
+	}
+	return defaultCohortMinVotes
+}
+
+// 投票者の層を表す SQL 式 (段位の登録がなかった人の票は unranked)
+// 層は投票したときのもの (votes.cohort)。今の段位を使うと、段位を変えて結果を見比べれば
+// 少人数の層の個々の点数が分かってしまうので使わない
+// verifiedOnly なら管理者が確認した段位だけを使い、それ以外も unranked にする
+func cohortExpr(verifiedOnly bool) (string, []interface{}) {
+	return "CASE WHEN votes.cohort <> '' AND (votes.cohort_verified OR NOT ?) THEN votes.cohort ELSE ? END",
+		[]interface{}{verifiedOnly, mahjong.CohortUnranked}
+}
+
+// 中身を出さない層を決める
+// 票数が最低票数に満たない層に加えて、出さない層が1つだけだと全体の集計から他の層を引けば
+// その層の中身が分かってしまうので、票が一番少ない層も一緒に出さない (補完秘匿)
+// 出さない層が1つだけでも票が0なら、引き算で分かるのは「0票」だけなのでそのままにする
+func suppressedCohorts(counts map[string]int, min int) map[string]bool {
+	suppressed := map[string]bool{}
+	var hidden string
+	for _, cohort := range mahjong.Cohorts {
+		if counts[cohort] < min {
+			suppressed[cohort] = true
+			hidden = cohort
+		}
+	}
+	if len(suppressed) != 1 || counts[hidden] == 0 {
+		return suppressed
+	}
+	smallest := ""
+	for _, cohort := range mahjong.Cohorts {
+		if !suppressed[cohort] && (smallest == "" || counts[cohort] < counts[smallest]) {
+			smallest = cohort
+		}
+	}
+	if smallest != "" {
+		suppressed[smallest] = true
+	}
+	return suppressed
+}
+
+// 層ごとに集計する (?by=cohort)
+// 票数が最低票数に満たない層 (と、引き算で中身が分かってしまう層) は Suppressed にして中身を返さない
+func cohortResults(votes func() *gorm.DB, bins histogramParams, verifiedOnly bool) ([]models.CohortResult, error) {
+	expr, args := cohortExpr(verifiedOnly)
+	var rows []struct {
+		Cohort string
+		Count  int
+	}
+	err := votes().Select(expr+" AS cohort, COUNT(*) AS count", args...).
+		Group("1").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, row := range rows {
+		counts[row.Cohort] = row.Count
+	}
+
+	suppressed := suppressedCohorts(counts, cohortMinVotes())
+	results := make([]models.CohortResult, 0, len(mahjong.Cohorts))
+	for _, cohort := range mahjong.Cohorts {
+		result := models.CohortResult{Cohort: cohort}
+		if suppressed[cohort] {
+			result.Suppressed = true
+			results = append(results, result)
+			continue
+		}
+		cohort := cohort
+		agg, err := aggregateVotes(func() *gorm.DB {
+			return votes().Where(expr+" = ?", append(args, cohort)...)
+		}, bins)
+		if err != nil {
+			return nil, err
+		}
+		response := resultResponse(agg)
+		result.Result = &response
+		results = append(results, result)
+	}
+	return results, nil
+}
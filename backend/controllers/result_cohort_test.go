@@ -0,0 +1,33 @@
+package controllers
+
+import (
+	"portfolio-backend/mahjong"
+	"reflect"
+	"testing"
+)
+
+func TestSuppressedCohorts(t *testing.T) {
+	tests := []struct {
+		name   string
+		counts map[string]int
+		want   map[string]bool
+	}{
+		{"all large enough",
+			map[string]int{mahjong.CohortBeginner: 5, mahjong.CohortIntermediate: 6, mahjong.CohortAdvanced: 7, mahjong.CohortExpert: 8, mahjong.CohortUnranked: 9},
+			map[string]bool{}},
+		{"one small cohort hides the next smallest too",
+			map[string]int{mahjong.CohortBeginner: 2, mahjong.CohortIntermediate: 6, mahjong.CohortAdvanced: 7, mahjong.CohortExpert: 8, mahjong.CohortUnranked: 9},
+			map[string]bool{mahjong.CohortBeginner: true, mahjong.CohortIntermediate: true}},
+		{"one empty cohort reveals nothing",
+			map[string]int{mahjong.CohortIntermediate: 6, mahjong.CohortAdvanced: 7, mahjong.CohortExpert: 8, mahjong.CohortUnranked: 9},
+			map[string]bool{mahjong.CohortBeginner: true}},
+		{"two small cohorts cover each other",
+			map[string]int{mahjong.CohortBeginner: 2, mahjong.CohortIntermediate: 6, mahjong.CohortAdvanced: 7, mahjong.CohortExpert: 1, mahjong.CohortUnranked: 9},
+			map[string]bool{mahjong.CohortBeginner: true, mahjong.CohortExpert: true}},
+	}
+	for _, tt := range tests {
+		if got := suppressedCohorts(tt.counts, 5); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: suppressedCohorts = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"portfolio-backend/realtime"
+	"strings"
+	"time"
+)
+
+// 集計結果を送り直す間隔の最短 (この間に来た投票はまとめて1回にする)
+const resultStreamInterval = time.Second
+
+// 接続が切られないように送るコメント行の間隔
+const resultStreamHeartbeat = 25 * time.Second
+
+// 集計結果をリアルタイムに送る (GET /problems/{id}/result/stream, Server-Sent Events)
+// 接続したときと、投票が入るたびに "result" イベントで GET /problems/{id}/result と同じ内容を送る
+// クエリパラメータ・見られる条件も GET /problems/{id}/result と同じ
+// ブラウザの EventSource はヘッダーを付けられないので、トークンは ?access_token= でも渡せる
+func StreamProblemResult(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/result/stream")
+	q, ok := prepareResult(w, r, user, idStr)
+	if !ok { return }
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// 最初の送信より前に購読しておき、その間に入った投票も取りこぼさない
+	sub := realtime.Results.Subscribe(q.problem.ID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx などのプロキシでバッファさせない
+
+	send := func() error {
+		response, err := q.build()
+		if err != nil {
+			return err
+		}
+		data, _ := json.Marshal(response)
+		if _, err := fmt.Fprintf(w, "event: result\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := send(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(resultStreamHeartbeat)
+	defer heartbeat.Stop()
+	var due <-chan time.Time // 次に送る時刻 (送る予定がなければ nil)
+	for {
+		select {
+		case <-r.Context().Done():
+			// クライアントが切断した
+			return
+		case <-sub.C:
+			if due == nil {
+				due = time.After(resultStreamInterval)
+			}
+		case <-due:
+			due = nil
+			if err := send(); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
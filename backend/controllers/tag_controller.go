@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// タグ一覧 (GET /tags)
+// 各タグが付いている問題の数も返す (一般ユーザーには公開中の問題だけの数)
+func GetTags(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	// 一般ユーザーには公開中の問題だけを数える
+	query := database.DB.Model(&models.Tag{}).
+		Select("tags.*, COUNT(problems.id) AS problem_count").
+		Joins("LEFT JOIN problem_tags ON problem_tags.tag_id = tags.id")
+	problemJoin := "LEFT JOIN problems ON problems.id = problem_tags.problem_id AND problems.deleted_at IS NULL"
+	if isAdmin(r) {
+		query = query.Joins(problemJoin)
+	} else {
+		query = joinVisibleProblems(query, problemJoin)
+	}
+
+	var tags []models.TagSummary
+	err := query.
+		Group("tags.id").
+		Order("tags.name").
+		Find(&tags).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch tags", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tags)
+}
+
+// 問題のタグを付け替える (PUT /problems/{id}/tags)
+// 例: {"tags": ["Defense", "Early Riichi"]}  まだ無いタグは自動で作る
+func SetProblemTags(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/tags")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var problem models.Problem
+	if err := database.DB.Preload("Tags").First(&problem, id).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	before := problem.Tags
+
+	// 手動のタグだけを入れ替える (自動タグは手牌の解析結果のまま残す)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceManualTags(tx, problem.ID, input.Tags)
+	})
+	if err != nil {
+		http.Error(w, "Failed to update tags", http.StatusInternalServerError)
+		return
+	}
+
+	database.DB.Preload("Tags").First(&problem, problem.ID)
+	recordAudit(r, admin, AuditProblemTags, problemTarget(problem.ID), before, problem.Tags, "")
+
+	json.NewEncoder(w).Encode(problem.Tags)
+}
+
+// 問題の手動のタグを names に入れ替える (まだ無いタグは作る)
+func replaceManualTags(tx *gorm.DB, problemID uint, names []string) error {
+	tags, err := findOrCreateTags(tx, names)
+	if err != nil {
+		return err
+	}
+	if err := tx.Where("problem_id = ? AND source = ?", problemID, models.TagSourceManual).Delete(&models.ProblemTag{}).Error; err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		// 自動で付いていたタグを手動でも付けた場合は手動扱いにする
+		link := models.ProblemTag{ProblemID: problemID, TagID: tag.ID, Source: models.TagSourceManual}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "problem_id"}, {Name: "tag_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"source": models.TagSourceManual}),
+		}).Create(&link).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 自動タグ (Problem.AutoTags) を problem_tags に反映する
+// 前回の自動タグは消して付け直す。手動で付けたタグはそのまま
+func syncAutoTags(tx *gorm.DB, problem *models.Problem) error {
+	if err := tx.Where("problem_id = ? AND source = ?", problem.ID, models.TagSourceAuto).Delete(&models.ProblemTag{}).Error; err != nil {
+		return err
+	}
+	tags, err := findOrCreateTags(tx, problem.AutoTags)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		link := models.ProblemTag{ProblemID: problem.ID, TagID: tag.ID, Source: models.TagSourceAuto}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 全ての問題の自動タグを計算し直す (POST /admin/problems/retag)
+// 判定ルールを変えたときや、自動タグ導入前の問題に付けるとき用
+func RetagProblems(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var problems []models.Problem
+	if err := database.DB.Find(&problems).Error; err != nil {
+		http.Error(w, "Failed to fetch problems", http.StatusInternalServerError)
+		return
+	}
+
+	updated := 0
+	failed := map[uint]string{}
+	for i := range problems {
+		problem := &problems[i]
+		if err := problem.Analyze(); err != nil {
+			failed[problem.ID] = err.Error()
+			continue
+		}
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(problem).UpdateColumns(map[string]interface{}{"shanten": problem.Shanten, "fingerprint": problem.Fingerprint}).Error; err != nil {
+				return err
+			}
+			return syncAutoTags(tx, problem)
+		})
+		if err != nil {
+			failed[problem.ID] = err.Error()
+			continue
+		}
+		updated++
+	}
+
+	result := map[string]interface{}{"updated": updated, "failed": failed}
+	recordAudit(r, admin, AuditProblemRetag, auditTarget{Type: "problem"}, nil, result, "")
+	json.NewEncoder(w).Encode(result)
+}
+
+// タグ名から検索用の名前を作る (例: "Early Riichi" -> "early-riichi")
+func slugify(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// 名前のリストに対応するタグを取得し、無いものは作る
+func findOrCreateTags(tx *gorm.DB, names []string) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		slug := slugify(name)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		tag := models.Tag{Name: name, Slug: slug}
+		// 同時に同じタグが作られても重複しないよう、衝突したら既存のものを使う
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tag).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("slug = ?", slug).First(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// ?tags=defense,early-riichi の絞り込み条件 (全てのタグが付いている問題)
+func withAllTags(query *gorm.DB, param string) *gorm.DB {
+	var slugs []string
+	for _, s := range strings.Split(param, ",") {
+		if slug := slugify(s); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return query
+	}
+	return query.Where(`problems.id IN (
+		SELECT problem_tags.problem_id FROM problem_tags JOIN tags ON tags.id = problem_tags.tag_id
+		WHERE tags.slug IN ? GROUP BY problem_tags.problem_id HAVING COUNT(DISTINCT tags.id) = ?)`,
+		slugs, len(slugs))
+}
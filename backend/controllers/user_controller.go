@@ -2,33 +2,117 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"portfolio-backend/database"
+	"portfolio-backend/mahjong"
 	"portfolio-backend/models"
 	"strconv"
 	"strings"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // ユーザー一覧を取得 (GET /users)
+// クエリパラメータ:
+//   q=          名前・メールアドレスの部分一致検索
+//   status=     active / suspended / banned で絞り込み
+//   sort=       created_at (登録日, デフォルト) / votes (投票数)
+//   order=      desc (デフォルト) / asc
+//   limit=      1ページの件数 (デフォルト50, 最大200)
+//   cursor=     前のレスポンスの X-Next-Cursor ヘッダーの値
+//   include_deleted=true  論理削除済みのユーザーも含める (復元用)
 func GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions {
 		return
 	}
+	if _, ok := requireAdmin(w, r); !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit := parseLimit(r, 50, 200)
+
+	query := database.DB.Model(&models.User{}).
+		Select("users.*, COALESCE(vc.vote_count, 0) AS vote_count").
+		Joins("LEFT JOIN (SELECT user_id, COUNT(*) AS vote_count FROM votes WHERE deleted_at IS NULL GROUP BY user_id) vc ON vc.user_id = users.id")
+	if q.Get("include_deleted") == "true" {
+		query = query.Unscoped()
+	}
 
-	var users []models.User
-	result := database.DB.Find(&users)
+	if keyword := strings.TrimSpace(q.Get("q")); keyword != "" {
+		pattern := likePattern(keyword)
+		query = query.Where("users.name ILIKE ? OR users.email ILIKE ?", pattern, pattern)
+	}
+	if status := q.Get("status"); status != "" {
+		query = query.Where("users.status = ?", status)
+	}
+
+	// 並び順 (同じ値のときはIDで順序を固定する)
+	sortKey := "users.created_at"
+	if q.Get("sort") == "votes" {
+		sortKey = "COALESCE(vc.vote_count, 0)"
+	} else if s := q.Get("sort"); s != "" && s != "created_at" {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+	direction, cmp := "DESC", "<"
+	if q.Get("order") == "asc" {
+		direction, cmp = "ASC", ">"
+	}
+
+	// カーソル以降のデータだけを取る
+	if c := q.Get("cursor"); c != "" {
+		cursor, err := decodeCursor(c)
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		var value interface{}
+		if q.Get("sort") == "votes" {
+			value, err = strconv.ParseInt(cursor.Value, 10, 64)
+		} else {
+			value, err = time.Parse(time.RFC3339Nano, cursor.Value)
+		}
+		if err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND users.id %s ?)", sortKey, cmp, sortKey, cmp),
+			value, value, cursor.ID,
+		)
+	}
+
+	var users []models.UserListItem
+	result := query.Order(sortKey + " " + direction).Order("users.id " + direction).
+		Limit(limit + 1).Find(&users)
 	if result.Error != nil {
 		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
 		return
 	}
 
+	// 1件多く取って、次のページがあるか判定する
+	nextCursor := ""
+	if len(users) > limit {
+		users = users[:limit]
+		last := users[limit-1]
+		if q.Get("sort") == "votes" {
+			nextCursor = encodeCursor(strconv.FormatInt(last.VoteCount, 10), last.ID)
+		} else {
+			nextCursor = encodeCursor(last.CreatedAt.Format(time.RFC3339Nano), last.ID)
+		}
+	}
+
 	// パスワードを隠す
 	for i := range users {
 		users[i].Password = ""
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	setNextCursor(w, nextCursor)
 	json.NewEncoder(w).Encode(users)
 }
 
@@ -38,6 +122,10 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodOptions {
 		return
 	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
 
 	// URLからユーザーIDを抽出 (/users/123 -> 123)
 	pathParts := strings.Split(r.URL.Path, "/")
@@ -52,16 +140,97 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// ユーザーを削除 (および関連する投票も削除)
-	if err := database.DB.Delete(&models.User{}, userID).Error; err != nil {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// ユーザーを論理削除し、関連する投票はポリシーに従って処理する
+	// 投票とユーザーに同じ削除時刻を入れておき、復元時に一緒に戻せるようにする
+	deletedAt := time.Now().Truncate(time.Microsecond)
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		switch votePolicy() {
+		case VotePolicyCascade:
+			if err := tx.Model(&models.Vote{}).Where("user_id = ?", user.ID).Update("deleted_at", deletedAt).Error; err != nil {
+				return err
+			}
+		case VotePolicyAnonymize:
+			if err := tx.Model(&models.Vote{}).Where("user_id = ?", user.ID).Update("user_id", nil).Error; err != nil {
+				return err
+			}
+		}
+		// retain の場合は投票に触らない (集計時に除外される)
+		return tx.Model(&user).Update("deleted_at", deletedAt).Error
+	})
+	if err != nil {
 		http.Error(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
 
+	recordAudit(r, admin, AuditUserDelete, userTarget(user.ID), userSnapshot(user), nil, "vote policy: "+votePolicy())
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
 }
 
+// 論理削除したユーザーを復元 (POST /users/{id}/restore)
+func RestoreUser(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	// URLからユーザーIDを抽出 (/users/123/restore -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Unscoped().First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.DeletedAt.Valid {
+		http.Error(w, "User is not deleted", http.StatusConflict)
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 削除時に一緒に消した投票 (同じ削除時刻のもの) を戻す
+		// 匿名化された投票は誰のものか分からないので戻せない
+		if err := tx.Unscoped().Model(&models.Vote{}).
+			Where("user_id = ? AND deleted_at = ?", user.ID, user.DeletedAt.Time).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&user).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		http.Error(w, "Failed to restore user", http.StatusInternalServerError)
+		return
+	}
+
+	before := userSnapshot(user)
+	user.DeletedAt = gorm.DeletedAt{}
+	recordAudit(r, admin, AuditUserRestore, userTarget(user.ID), before, userSnapshot(user), "")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "User restored successfully"})
+}
+
 // ユーザーの投票履歴と統計を取得 (GET /users/{id}/votes)
 func GetUserVotes(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
@@ -82,6 +251,25 @@ func GetUserVotes(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// 本人か管理者だけが見られる
+	viewer, ok := requireUser(w, r)
+	if !ok {
+		return
+	}
+	if viewer.Role != "admin" && int(viewer.ID) != userID {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	// 判断力のレーティング (履歴は GET /users/{id}/rating)
+	var user models.User
+	database.DB.Select("id", "rating", "rating_deviation", "rated_votes").First(&user, userID)
+	rating := map[string]interface{}{
+		"rating":           user.Rating,
+		"rating_deviation": user.RatingDeviation,
+		"rated_votes":      user.RatedVotes,
+	}
+
 	// ユーザーの投票を取得
 	var votes []models.Vote
 	database.DB.Where("user_id = ?", userID).Find(&votes)
@@ -93,6 +281,7 @@ func GetUserVotes(w http.ResponseWriter, r *http.Request) {
 			"user_id":       userID,
 			"total_votes":   0,
 			"average_score": 0,
+			"rating":        rating,
 			"votes":         []models.Vote{},
 		})
 		return
@@ -120,6 +309,214 @@ func GetUserVotes(w http.ResponseWriter, r *http.Request) {
 		"average_score": average,
 		"min_score":     minScore,
 		"max_score":     maxScore,
+		"rating":        rating,
 		"votes":         votes,
 	})
 }
+
+// ユーザーの停止・BANを設定 (POST /users/{id}/status)
+// 例: {"status": "suspended", "reason": "荒らし行為", "until": "2026-01-01T00:00:00+09:00"}
+// status を "active" にすると解除
+func SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	// URLからユーザーIDを抽出 (/users/123/status -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input models.UserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch input.Status {
+	case models.UserStatusActive:
+		// 解除するときは理由と期限も消す
+		input.Reason = ""
+		input.Until = nil
+	case models.UserStatusSuspended, models.UserStatusBanned:
+		if input.Until != nil && input.Until.Before(time.Now()) {
+			http.Error(w, "until must be in the future", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+	if uint(userID) == admin.ID {
+		http.Error(w, "Cannot change your own status", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	before := userSnapshot(user)
+	user.Status = input.Status
+	user.StatusReason = input.Reason
+	user.StatusUntil = input.Until
+	if err := database.DB.Model(&user).Select("Status", "StatusReason", "StatusUntil").Updates(&user).Error; err != nil {
+		http.Error(w, "Failed to update user status", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditUserStatus, userTarget(user.ID), before, userSnapshot(user), input.Reason)
+
+	user.Password = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// ユーザーの権限を変更 (POST /users/{id}/role)
+// 例: {"role": "admin"}
+func SetUserRole(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions {
+		return
+	}
+	admin, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	// URLからユーザーIDを抽出 (/users/123/role -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if input.Role != "admin" && input.Role != "user" {
+		http.Error(w, "Invalid role", http.StatusBadRequest)
+		return
+	}
+	// 自分自身の権限を外すと管理者がいなくなる恐れがあるので禁止
+	if uint(userID) == admin.ID {
+		http.Error(w, "Cannot change your own role", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	before := userSnapshot(user)
+	if err := database.DB.Model(&user).Update("role", input.Role).Error; err != nil {
+		http.Error(w, "Failed to update role", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(r, admin, AuditUserRole, userTarget(user.ID), before, userSnapshot(user), "")
+
+	user.Password = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// 段位を登録する (POST /users/{id}/rank)
+// 例: {"platform": "tenhou", "rank": "4dan"}  platform を空にすると登録を消す
+// 本人か管理者が変更できる。管理者は {"verified": true} で確認済みにできる (本人が変えると未確認に戻る)
+func SetUserRank(w http.ResponseWriter, r *http.Request) {
+	SetupResponse(&w)
+	if r.Method == http.MethodOptions { return }
+	caller, ok := requireUser(w, r)
+	if !ok { return }
+
+	// URLからユーザーIDを抽出 (/users/123/rank -> 123)
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(pathParts[2])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+	admin := caller.Role == "admin"
+	if uint(userID) != caller.ID && !admin {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	var input struct {
+		Platform string `json:"platform"`
+		Rank     string `json:"rank"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cohort := ""
+	if input.Platform != "" {
+		cohort, err = mahjong.RankCohort(input.Platform, input.Rank)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		input.Rank = ""
+	}
+	if input.Verified && (!admin || input.Platform == "") {
+		http.Error(w, "Only admins can verify a rank", http.StatusForbidden)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	before := userSnapshot(user)
+	user.RankPlatform = input.Platform
+	user.Rank = input.Rank
+	user.RankVerified = input.Verified
+	user.Cohort = cohort
+	if err := database.DB.Model(&user).Select("RankPlatform", "Rank", "RankVerified", "Cohort").Updates(&user).Error; err != nil {
+		http.Error(w, "Failed to update rank", http.StatusInternalServerError)
+		return
+	}
+	// 管理者が他人の段位を変えたときだけ監査ログに残す
+	if admin && caller.ID != user.ID {
+		recordAudit(r, caller, AuditUserRank, userTarget(user.ID), before, userSnapshot(user), "")
+	}
+
+	user.Password = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
@@ -2,131 +2,269 @@ package controllers
 
 import (
 	"encoding/json"
-	"math"
+	"errors"
+	"fmt"
 	"net/http"
 	"portfolio-backend/database"
 	"portfolio-backend/models"
+	"portfolio-backend/realtime"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// 投票の理由の最大文字数
+const maxReasonLength = 1000
+
+var errAlreadyVoted = errors.New("already voted on this problem")
+
 // 投票を受け付ける (POST /votes)
+// 例: {"problem_id": 1, "point": 70, "reason": "両面が多くて打点も見込める"}
+// 投票者はログイン中のユーザー (本文の user_id は使わない)
+// 1人1問1票で、投票したあとは変えられない (409)。手牌が大きく変わる編集があったら、新しい局面にもう一度投票できる
 func CastVote(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions { return }
+	// 停止中・BANされたユーザーは requireUser で弾かれる
+	voter, ok := requireUser(w, r)
+	if !ok { return }
 
-	var vote models.Vote
-	if err := json.NewDecoder(r.Body).Decode(&vote); err != nil {
+	var input struct {
+		ProblemID uint   `json:"problem_id"`
+		Point     int    `json:"point"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	if input.Point < 0 || input.Point > 100 {
+		http.Error(w, "point must be 0-100", http.StatusBadRequest)
+		return
+	}
+
+	// 理由は任意 (前後の空白は落とす)
+	input.Reason = strings.TrimSpace(input.Reason)
+	if utf8.RuneCountInString(input.Reason) > maxReasonLength {
+		http.Error(w, fmt.Sprintf("reason must be at most %d characters", maxReasonLength), http.StatusBadRequest)
+		return
+	}
+
+	// どの版に対する投票かを記録する (クライアントの申告は使わない)
+	var problem models.Problem
+	if err := database.DB.First(&problem, input.ProblemID).Error; err != nil || !problem.IsPublished(time.Now()) {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return
+	}
+	if !problem.IsVotingOpen(time.Now()) {
+		http.Error(w, "Voting is closed", http.StatusForbidden)
+		return
+	}
 
 	// DBに保存
-	if err := database.DB.Create(&vote).Error; err != nil {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 同じユーザーの投票が同時に来ても2票にならないように、ユーザーの行をロックする
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.User{}, voter.ID).Error; err != nil {
+			return err
+		}
+		// 今の局面に投票済みなら受け付けない (結果を見てから点数を合わせられないように)
+		var count int64
+		err := tx.Model(&models.Vote{}).
+			Where("problem_id = ? AND user_id = ? AND revision >= ?", problem.ID, voter.ID, problem.MaterialRevision).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return errAlreadyVoted
+		}
+		return tx.Create(&models.Vote{
+			ProblemID: problem.ID,
+			UserID:    &voter.ID,
+			Revision:  problem.Revision,
+			Point:     input.Point,
+			Reason:    input.Reason,
+			// 投票したときの段位の層で集計する (あとで段位を変えても変わらない)
+			Cohort:         voter.Cohort,
+			CohortVerified: voter.RankVerified,
+		}).Error
+	})
+	if errors.Is(err, errAlreadyVoted) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
 		http.Error(w, "Failed to cast vote", http.StatusInternalServerError)
 		return
 	}
 
+	// 結果画面を開いている人に集計の更新を知らせる
+	realtime.Results.Publish(problem.ID)
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Vote casted!"})
 }
 
-// 結果を集計して返す (GET /problems/{id}/result?my_score=80)
+// 結果を集計して返す (GET /problems/{id}/result)
+// あなたの点数はログイン中のユーザーの実際の投票 (最新のもの) を使う
+// 結果を見てから投票できないように、投票するまでは 403 (管理者と、投票を締め切った問題は投票なしで見られる)
+//
+//	what_if=80        仮にその点数だった場合の偏差値・パーセンタイル順位も返す (比較用)
+//	revision=current  手牌が大きく変わる編集より前の投票を除く
+//	by=cohort         投票者の段位の層ごとの集計も返す (票が COHORT_MIN_VOTES 未満の層は中身を出さない)
+//	verified_only=true  by=cohort で、管理者が確認した段位だけを層分けに使う
+//	ヒストグラムの区切り方は ?bins= / ?width= / ?min= / ?max= / ?closed= で変えられる (parseHistogramParams)
 func GetProblemResult(w http.ResponseWriter, r *http.Request) {
 	SetupResponse(&w)
 	if r.Method == http.MethodOptions { return }
+	user, ok := requireUser(w, r)
+	if !ok { return }
+
+	idStr := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/problems/"), "/result")
+	q, ok := prepareResult(w, r, user, idStr)
+	if !ok { return }
 
-	// 1. URLからProblemIDを取得
-	// URL構造: /problems/{id}/result なので、少し強引ですがPathから取ります
-	// 本来はChiやGinなどのルーターを使うと楽ですが、標準機能でやります
-	//pathParts := r.URL.Path
-	// 例: /problems/1/result -> IDは "1"
-	// IDの抽出ロジック (簡易実装)
-	var idStr string
-	var problemID int
-	
-	// クエリパラメータから自分のスコアを取得 (?my_score=80)
-	myScoreStr := r.URL.Query().Get("my_score")
-	myScore, _ := strconv.Atoi(myScoreStr)
-
-	// パス解析 (/problems/XX/result)
-	// 単純化のため、IDは main.go のハンドリングで渡す前提にするか、ここでParseするか。
-	// 今回は main.go で id を抽出するラッパーを書くのが面倒なので、
-	// main.go で "/problems/" で受けて、ここで分解します。
-	// 想定URL: /problems/1/result
-	
-	// ID抽出 (簡易的)
-	// /problems/1/result -> [ "", "problems", "1", "result" ]
-	// Goの標準ライブラリではパスパラメータの取得が面倒なので、
-	// フロントから `?problem_id=1` で送ってもらう形に変更させてください（実装簡易化のため）
-	
-	idStr = r.URL.Query().Get("problem_id")
-	problemID, _ = strconv.Atoi(idStr)
-
-	// 2. この問題に対する全投票を取得
-	var votes []models.Vote
-	database.DB.Where("problem_id = ?", problemID).Find(&votes)
-
-	if len(votes) == 0 {
-		json.NewEncoder(w).Encode(models.ResultResponse{})
+	response, err := q.build()
+	if err != nil {
+		http.Error(w, "Failed to aggregate votes", http.StatusInternalServerError)
 		return
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	// 3. 統計計算 (Mean, StdDev)
-	var sum float64
-	var values []float64
-	
-	// ヒストグラムの初期化 (10点刻み: 0-9, 10-19, ... 90-100)
-	// 配列のインデックス 0->0~9, 1->10~19 ... 10->100
-	histogramCounts := make([]int, 11)
+// 結果の集計に必要なもの (リクエストから読んだ条件)
+type resultQuery struct {
+	user        *models.User
+	problem     models.Problem
+	whatIf      *int
+	bins        histogramParams
+	currentOnly bool // ?revision=current
 
-	for _, v := range votes {
-		score := float64(v.Point)
-		sum += score
-		values = append(values, score)
+	byCohort     bool // ?by=cohort
+	verifiedOnly bool // ?verified_only=true
+}
 
-		// ヒストグラム集計
-		idx := v.Point / 10
-		if idx > 10 { idx = 10 } // 100点の場合
-		histogramCounts[idx]++
+// リクエストを読んで、結果を見てよいか確かめる
+// 見られなければエラーを返して false
+func prepareResult(w http.ResponseWriter, r *http.Request, user *models.User, idStr string) (*resultQuery, bool) {
+	problemID, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+	q := &resultQuery{
+		user:         user,
+		currentOnly:  r.URL.Query().Get("revision") == "current",
+		byCohort:     r.URL.Query().Get("by") == "cohort",
+		verifiedOnly: r.URL.Query().Get("verified_only") == "true",
+	}
+	if err := database.DB.First(&q.problem, problemID).Error; err != nil {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return nil, false
+	}
+	admin := user.Role == "admin"
+	if !q.problem.IsPublished(time.Now()) && !admin {
+		http.Error(w, "Problem not found", http.StatusNotFound)
+		return nil, false
 	}
 
-	count := float64(len(votes))
-	average := sum / count
+	if s := r.URL.Query().Get("what_if"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 || n > 100 {
+			http.Error(w, "what_if must be 0-100", http.StatusBadRequest)
+			return nil, false
+		}
+		q.whatIf = &n
+	}
+	if q.bins, err = parseHistogramParams(r.URL.Query()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
 
-	// 分散と標準偏差
-	var varianceSum float64
-	for _, val := range values {
-		varianceSum += math.Pow(val - average, 2)
+	if !requireVoted(w, user, &q.problem, "viewing the result") {
+		return nil, false
 	}
-	variance := varianceSum / count
-	stdDev := math.Sqrt(variance)
+	return q, true
+}
 
-	// 4. 自分の偏差値を計算 (T-score)
-	// 偏差値 = (得点 - 平均) / 標準偏差 * 10 + 50
-	var deviationValue float64 = 50
-	if stdDev > 0 {
-		deviationValue = 50 + 10*(float64(myScore)-average)/stdDev
+// 結果や議論を見る前に、その問題に投票しているか確かめる (投票してから見る、を守らせる)
+// 管理者と、投票を締め切った問題は投票なしでよい。だめならエラーを返して false
+func requireVoted(w http.ResponseWriter, user *models.User, problem *models.Problem, action string) bool {
+	if user.Role == "admin" || !problem.IsVotingOpen(time.Now()) {
+		return true
+	}
+	var count int64
+	err := countedVotes(int(problem.ID)).Where("votes.user_id = ?", user.ID).Count(&count).Error
+	if err != nil {
+		http.Error(w, "Failed to fetch your vote", http.StatusInternalServerError)
+		return false
+	}
+	if count == 0 {
+		http.Error(w, "Vote on this problem before "+action, http.StatusForbidden)
+		return false
 	}
+	return true
+}
 
-	// 5. ヒストグラムデータを整形
-	var histogramData []models.HistogramBin
-	labels := []string{"0-9", "10-19", "20-29", "30-39", "40-49", "50-59", "60-69", "70-79", "80-89", "90-99", "100"}
-	for i, c := range histogramCounts {
-		histogramData = append(histogramData, models.HistogramBin{
-			Range: labels[i],
-			Count: c,
-		})
+// 自分の投票 (集計対象のうち最新のもの)
+func (q *resultQuery) myVote() (models.Vote, bool, error) {
+	var mine models.Vote
+	err := countedVotes(int(q.problem.ID)).Select("votes.*").
+		Where("votes.user_id = ?", q.user.ID).
+		Order("votes.created_at DESC, votes.id DESC").
+		Take(&mine).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return mine, false, nil
 	}
+	return mine, err == nil, err
+}
 
-	// レスポンス作成
-	response := models.ResultResponse{
-		Average:   math.Round(average*10) / 10, // 小数点第1位まで
-		StdDev:    math.Round(stdDev*10) / 10,
-		UserScore: myScore,
-		UserDev:   math.Round(deviationValue*10) / 10,
-		VoteCount: int(count),
-		Histogram: histogramData,
+// この問題に対する投票を DB 側で集計する (退会ユーザーの票などは除く)
+func (q *resultQuery) build() (models.ResultResponse, error) {
+	votes := func() *gorm.DB {
+		query := countedVotes(int(q.problem.ID))
+		if q.currentOnly {
+			query = query.Where("votes.revision >= problems.material_revision")
+		}
+		return query
+	}
+	agg, err := aggregateVotes(votes, q.bins)
+	if err != nil {
+		return models.ResultResponse{}, err
 	}
 
-	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+	response := resultResponse(agg)
+	standing := func(score int) (models.ScoreStanding, error) {
+		rank, err := rankScore(votes, score)
+		return scoreStanding(agg, score, rank), err
+	}
+	mine, hasVoted, err := q.myVote()
+	if err != nil {
+		return response, err
+	}
+	if hasVoted {
+		s, err := standing(mine.Point)
+		if err != nil {
+			return response, err
+		}
+		response.HasVoted = true
+		response.UserScore, response.UserDev, response.UserPercentile = s.Score, s.Dev, s.Percentile
+	}
+	if q.whatIf != nil {
+		s, err := standing(*q.whatIf)
+		if err != nil {
+			return response, err
+		}
+		response.WhatIf = &s
+	}
+	if q.byCohort {
+		if response.Cohorts, err = cohortResults(votes, q.bins, q.verifiedOnly); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 投票 → 結果を見る → 点数を変えて投票し直す、はできない
+func TestCastVoteAfterViewingResult(t *testing.T) {
+	database.Connect()
+
+	voter := models.User{Email: fmt.Sprintf("revote-%d@example.com", time.Now().UnixNano()), Name: "voter", Role: "user"}
+	if err := database.DB.Create(&voter).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer database.DB.Unscoped().Delete(&voter)
+	problem := models.Problem{HandTiles: "[0,1,2,9,10,11,18,19,20,27,27,31,31,32]", DoraTiles: "[28]", Wind: "East", Round: "East-1", Status: models.ProblemStatusPublished}
+	if err := database.DB.Create(&problem).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer database.DB.Unscoped().Delete(&problem)
+	token := "Bearer " + issueToken(voter.ID)
+
+	vote := func(point int) int {
+		body := fmt.Sprintf(`{"problem_id": %d, "point": %d}`, problem.ID, point)
+		req := httptest.NewRequest(http.MethodPost, "/votes", strings.NewReader(body))
+		req.Header.Set("Authorization", token)
+		w := httptest.NewRecorder()
+		CastVote(w, req)
+		return w.Code
+	}
+
+	if code := vote(30); code != http.StatusCreated {
+		t.Fatalf("first vote: status = %d, want 201", code)
+	}
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/problems/%d/result", problem.ID), nil)
+	req.Header.Set("Authorization", token)
+	w := httptest.NewRecorder()
+	GetProblemResult(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("result: status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if code := vote(70); code != http.StatusConflict {
+		t.Errorf("revote: status = %d, want 409", code)
+	}
+
+	var votes []models.Vote
+	database.DB.Where("problem_id = ?", problem.ID).Find(&votes)
+	if len(votes) != 1 || votes[0].Point != 30 {
+		t.Errorf("votes = %+v, want the first vote only", votes)
+	}
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// 削除されたユーザーの投票をどう扱うか (環境変数 VOTE_RETENTION_POLICY で切り替え)
+const (
+	VotePolicyCascade   = "cascade"   // 投票も一緒に論理削除する (ユーザーを復元すると投票も戻る)
+	VotePolicyAnonymize = "anonymize" // 投票は残して user_id を外す (集計には残り続ける)
+	VotePolicyRetain    = "retain"    // 投票はそのまま残すが集計からは除外する (復元すると集計に戻る)
+)
+
+// 現在の投票の扱いポリシーを返す (未設定や不正な値なら cascade)
+func votePolicy() string {
+	switch policy := os.Getenv("VOTE_RETENTION_POLICY"); policy {
+	case VotePolicyAnonymize, VotePolicyRetain:
+		return policy
+	default:
+		return VotePolicyCascade
+	}
+}
+
+// 集計対象になる投票だけを返すクエリ
+// 削除済みの問題への投票と、論理削除されたユーザーの投票は数えない
+// (匿名化された投票は user_id が NULL なので残る)
+func countedVotes(problemID int) *gorm.DB {
+	return database.DB.Model(&models.Vote{}).
+		Joins("JOIN problems ON problems.id = votes.problem_id AND problems.deleted_at IS NULL").
+		Joins("LEFT JOIN users ON users.id = votes.user_id").
+		Where("votes.problem_id = ?", problemID).
+		Where("votes.user_id IS NULL OR users.deleted_at IS NULL")
+}
+
+// 問題ごとの投票数・標準偏差を集計して problems に LEFT JOIN する (別名 vs)
+// 集計対象は countedVotes と同じく、退会ユーザーの票を除いたもの
+const voteStatsJoin = `LEFT JOIN (
+	SELECT votes.problem_id, COUNT(*) AS vote_count, STDDEV_POP(votes.point)::float8 AS vote_std_dev
+	FROM votes LEFT JOIN users ON users.id = votes.user_id
+	WHERE votes.deleted_at IS NULL AND (votes.user_id IS NULL OR users.deleted_at IS NULL)
+	GROUP BY votes.problem_id
+) vs ON vs.problem_id = problems.id`
@@ -0,0 +1,23 @@
+package controllers
+
+import "testing"
+
+func TestVotePolicy(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"", VotePolicyCascade},
+		{"cascade", VotePolicyCascade},
+		{"anonymize", VotePolicyAnonymize},
+		{"retain", VotePolicyRetain},
+		{"RETAIN", VotePolicyCascade},
+		{"delete", VotePolicyCascade},
+	}
+	for _, tt := range tests {
+		t.Setenv("VOTE_RETENTION_POLICY", tt.env)
+		if got := votePolicy(); got != tt.want {
+			t.Errorf("votePolicy() with %q = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
@@ -14,17 +14,21 @@ import (
 
 var DB *gorm.DB
 
+// アプリ全体で使うタイムゾーン (DBの接続設定と日替わりの判定で共通)
+const TimeZone = "Asia/Tokyo"
+
 func Connect() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("Note: .env file not found")
 	}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Tokyo",
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=%s",
 		os.Getenv("DB_HOST"),
 		os.Getenv("DB_USER"),
 		os.Getenv("DB_PASSWORD"),
 		os.Getenv("DB_NAME"),
 		os.Getenv("DB_PORT"),
+		TimeZone,
 	)
 
 	var err error
@@ -35,16 +39,130 @@ func Connect() {
 
 	// 1. マイグレーション (テーブル作成)
 	// Todo を削除し、Problem と Vote を追加
-	err = DB.AutoMigrate(&models.Problem{}, &models.Vote{}, &models.User{})
+	// 投票は問題・ユーザーを参照するので、外部キーを張る前に古いデータを整理しておく
+	prepareVoteForeignKeys()
+	// 投票に段位の層を持たせる前の投票は、マイグレーション後に今の段位で埋める
+	fillVoteCohorts := DB.Migrator().HasTable(&models.Vote{}) && !DB.Migrator().HasColumn(&models.Vote{}, "Cohort")
+	// 問題とタグの中間テーブルは ProblemTag で定義する
+	if err := DB.SetupJoinTable(&models.Problem{}, "Tags", &models.ProblemTag{}); err != nil {
+		log.Fatal("Failed to set up join table:", err)
+	}
+	err = DB.AutoMigrate(
+		&models.User{},
+		&models.Problem{},
+		&models.Vote{},
+		&models.ProblemRevision{},
+		&models.DailyProblem{},
+		&models.DailyQueueEntry{},
+		&models.Tag{},
+		&models.ProblemTag{},
+		&models.Collection{},
+		&models.CollectionItem{},
+		&models.AuditLog{},
+		&models.EngineEvaluation{},
+		&models.RatingHistory{},
+		&models.Comment{},
+		&models.CommentLike{},
+	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
+	protectAuditLogs()
+	if fillVoteCohorts {
+		DB.Exec(`UPDATE votes SET cohort = users.cohort, cohort_verified = users.rank_verified
+			FROM users WHERE users.id = votes.user_id`)
+	}
 	fmt.Println("🚀 Database migrated!")
 
-	// 2. シーディング (初期データ投入)
+	// 2. 手牌から計算する項目がまだ入っていない問題を埋める
+	backfillProblemAnalysis()
+
+	// 3. シーディング (初期データ投入)
 	seedDatabase()
 }
 
+// 投票の外部キー制約を張るための下準備
+// 以前のバージョンでは問題を物理削除しても投票が残っていたので、
+// 孤立した投票を片付けてから制約を作成する
+func prepareVoteForeignKeys() {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.Vote{}) {
+		return
+	}
+
+	// 存在しない問題への投票は集計しようがないので消す
+	if migrator.HasTable(&models.Problem{}) {
+		result := DB.Exec(`DELETE FROM votes WHERE NOT EXISTS (SELECT 1 FROM problems WHERE problems.id = votes.problem_id)`)
+		if result.RowsAffected > 0 {
+			fmt.Printf("🧹 Removed %d orphan votes\n", result.RowsAffected)
+		}
+	}
+
+	// 存在しないユーザーの投票は匿名票として残す
+	if migrator.HasTable(&models.User{}) {
+		DB.Exec(`UPDATE votes SET user_id = NULL WHERE user_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = votes.user_id)`)
+	}
+
+	// ON DELETE の動作が想定と違う古い制約は作り直す
+	// (confdeltype: c = CASCADE, n = SET NULL)
+	expected := map[string]string{
+		"fk_problems_votes": "c",
+		"fk_users_votes":    "n",
+	}
+	for name, action := range expected {
+		var current string
+		DB.Raw(`SELECT confdeltype::text FROM pg_constraint WHERE conname = ?`, name).Scan(&current)
+		if current != "" && current != action {
+			DB.Exec(`ALTER TABLE votes DROP CONSTRAINT ` + name)
+		}
+	}
+}
+
+// 監査ログを追記専用にする
+// アプリのバグや手作業のSQLで書き換えられないよう、UPDATE/DELETE をトリガーで拒否する
+func protectAuditLogs() {
+	err := DB.Exec(`
+		CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+		BEGIN
+			RAISE EXCEPTION 'audit_logs is append-only';
+		END;
+		$$ LANGUAGE plpgsql;
+
+		DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+		CREATE TRIGGER audit_logs_append_only
+			BEFORE UPDATE OR DELETE ON audit_logs
+			FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+	`).Error
+	if err != nil {
+		log.Fatal("Failed to protect audit logs:", err)
+	}
+}
+
+// 向聴数などの派生項目を追加する前に作られた問題を計算し直す
+// 副露・河のある問題は、指紋に副露・河を含める前に作られたかもしれないので毎回計算し直す
+// (副露・河のない問題の指紋は変わっていない)
+func backfillProblemAnalysis() {
+	var problems []models.Problem
+	DB.Unscoped().Where("shanten IS NULL OR fingerprint IS NULL OR fingerprint = ''").
+		Or("COALESCE(melds, '') <> '' OR COALESCE(rivers, '') <> ''").
+		Find(&problems)
+	for i := range problems {
+		old := problems[i].Fingerprint
+		hadShanten := problems[i].Shanten != nil
+		if err := problems[i].Analyze(); err != nil {
+			log.Printf("Skipped analysis of problem #%d: %v", problems[i].ID, err)
+			continue
+		}
+		if hadShanten && problems[i].Fingerprint == old {
+			continue
+		}
+		DB.Unscoped().Model(&problems[i]).UpdateColumns(map[string]interface{}{
+			"shanten":     problems[i].Shanten,
+			"fingerprint": problems[i].Fingerprint,
+		})
+	}
+}
+
 // 初期データ投入関数
 func seedDatabase() {
 	var count int64
@@ -64,6 +182,7 @@ func seedDatabase() {
 			Score:     25000,
 		}
 		
+		sampleProblem.Analyze()
 		DB.Create(&sampleProblem)
 		fmt.Println("✅ Sample problem created!")
 	}
@@ -0,0 +1,66 @@
+// Package evaluator は外部の麻雀AIに問題の局面を評価させる処理です。
+//
+// AIとは MJAI プロトコルでやり取りする (Subprocess)。
+// 別の方式の AI を足すときは Evaluator を実装する。
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"portfolio-backend/mahjong/mjai"
+	"strings"
+	"time"
+)
+
+// 打牌の候補1つ分
+type Candidate struct {
+	Tile        int     `json:"tile"`        // 切る牌 (0-33)
+	Probability float64 `json:"probability"` // AIがこの牌を選ぶ確率 (0-1)
+}
+
+// AIの評価結果 (Candidates は確率の高い順)
+type Result struct {
+	Engine     string      `json:"engine"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// 局面 (start_game から打牌を考えるところまでの MJAI イベント) を評価するもの
+type Evaluator interface {
+	// 評価結果に残すエンジン名
+	Name() string
+	Evaluate(ctx context.Context, events []mjai.Event) (*Result, error)
+}
+
+// AIが設定されていない
+var ErrNotConfigured = errors.New("evaluator is not configured (set EVALUATOR_COMMAND)")
+
+// 評価1回あたりの時間制限の既定値 (環境変数 EVALUATOR_TIMEOUT で変更可)
+const defaultTimeout = 30 * time.Second
+
+// 環境変数の設定から AI を用意する
+//
+//	EVALUATOR_COMMAND  起動するコマンド (必須)
+//	EVALUATOR_ARGS     引数 (空白区切り)
+//	EVALUATOR_NAME     評価結果に残すエンジン名 (省略時はコマンド名)
+//	EVALUATOR_TIMEOUT  1回の評価の時間制限 (例: "30s")
+func FromEnv() (Evaluator, error) {
+	command := os.Getenv("EVALUATOR_COMMAND")
+	if command == "" {
+		return nil, ErrNotConfigured
+	}
+	s := &Subprocess{
+		Command: command,
+		Args:    strings.Fields(os.Getenv("EVALUATOR_ARGS")),
+		Engine:  os.Getenv("EVALUATOR_NAME"),
+		Timeout: defaultTimeout,
+	}
+	if v := os.Getenv("EVALUATOR_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.New("invalid EVALUATOR_TIMEOUT")
+		}
+		s.Timeout = d
+	}
+	return s, nil
+}
@@ -0,0 +1,135 @@
+package evaluator
+
+import (
+	"context"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"portfolio-backend/mahjong/mjai"
+	"strings"
+	"testing"
+	"time"
+)
+
+// テスト用のボット (testdata/fakebot) をビルドしたもの
+var fakebot string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "fakebot")
+	if err != nil {
+		panic(err)
+	}
+	fakebot = filepath.Join(dir, "fakebot")
+	build := exec.Command("go", "build", "-o", fakebot, "./testdata/fakebot")
+	build.Stderr = os.Stderr
+	if err := build.Run(); err != nil {
+		panic("failed to build fakebot: " + err.Error())
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// 孤立した北が1枚ある14枚の手牌
+func sampleEvents(t *testing.T) []mjai.Event {
+	t.Helper()
+	pos := gamelog.Position{
+		Wind: 0, Number: 1, SeatWind: 1,
+		Hand:   []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 24, 25, 31, 31, mahjong.North},
+		Dora:   []int{4},
+		Scores: [4]int{25000, 25000, 25000, 25000},
+	}
+	k, err := pos.Kyoku()
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := 0
+	start := mjai.Event{Type: mjai.TypeStartGame, ID: &self}
+	return append([]mjai.Event{start}, mjai.EncodeKyoku(k)...)
+}
+
+func TestSubprocessEvaluate(t *testing.T) {
+	s := &Subprocess{Command: fakebot, Timeout: 10 * time.Second}
+	res, err := s.Evaluate(context.Background(), sampleEvents(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Engine != "fakebot" {
+		t.Errorf("Engine = %q, want fakebot", res.Engine)
+	}
+	if len(res.Candidates) == 0 || res.Candidates[0].Tile != mahjong.North {
+		t.Fatalf("best candidate = %+v, want North", res.Candidates)
+	}
+	sum := 0.0
+	for i, c := range res.Candidates {
+		sum += c.Probability
+		if i > 0 && c.Probability > res.Candidates[i-1].Probability {
+			t.Errorf("candidates are not sorted: %+v", res.Candidates)
+		}
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("probabilities sum to %v, want 1", sum)
+	}
+}
+
+func TestSubprocessWithoutMeta(t *testing.T) {
+	s := &Subprocess{Command: fakebot, Args: []string{"-nometa"}, Engine: "plain"}
+	res, err := s.Evaluate(context.Background(), sampleEvents(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Engine != "plain" || len(res.Candidates) != 1 || res.Candidates[0].Probability != 1 {
+		t.Errorf("result = %+v, want a single candidate with probability 1", res)
+	}
+}
+
+func TestSubprocessErrors(t *testing.T) {
+	events := sampleEvents(t)
+
+	crash := &Subprocess{Command: fakebot, Args: []string{"-crash"}}
+	if _, err := crash.Evaluate(context.Background(), events); err == nil || !strings.Contains(err.Error(), "crashed on purpose") {
+		t.Errorf("crash: err = %v, want the bot's stderr", err)
+	}
+
+	slow := &Subprocess{Command: fakebot, Args: []string{"-sleep", "5s"}, Timeout: 200 * time.Millisecond}
+	if _, err := slow.Evaluate(context.Background(), events); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("timeout: err = %v", err)
+	}
+
+	missing := &Subprocess{Command: filepath.Join(t.TempDir(), "no-such-bot")}
+	if _, err := missing.Evaluate(context.Background(), events); err == nil {
+		t.Error("missing command should fail")
+	}
+
+	// 最後がツモでない (打牌を求めていない) 場合
+	if _, err := (&Subprocess{Command: fakebot}).Evaluate(context.Background(), events[:2]); err == nil {
+		t.Error("events without a decision should fail")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("EVALUATOR_COMMAND", "")
+	if _, err := FromEnv(); err != ErrNotConfigured {
+		t.Errorf("FromEnv without command = %v, want ErrNotConfigured", err)
+	}
+
+	t.Setenv("EVALUATOR_COMMAND", "/opt/bot/mortal")
+	t.Setenv("EVALUATOR_ARGS", "--model  strong.pth")
+	t.Setenv("EVALUATOR_TIMEOUT", "5s")
+	e, err := FromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := e.(*Subprocess)
+	if s.Name() != "mortal" || len(s.Args) != 2 || s.Timeout != 5*time.Second {
+		t.Errorf("FromEnv = %+v", s)
+	}
+
+	t.Setenv("EVALUATOR_TIMEOUT", "soon")
+	if _, err := FromEnv(); err == nil {
+		t.Error("invalid timeout should fail")
+	}
+}
@@ -0,0 +1,156 @@
+package evaluator
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"portfolio-backend/mahjong/mjai"
+	"sort"
+	"time"
+)
+
+// ローカルのAIを子プロセスとして起動し、標準入出力の MJAI で評価させる
+//
+// イベントを1行ずつ送り、AIは1行ずつ返事をする (打牌を考えるところ以外は {"type":"none"})。
+// 最後のツモへの返事が打牌 {"type":"dahai","pai":"3m"} になる。
+// 候補ごとの確率は返事の meta に付けてもらう (なければ選んだ牌を確率1とする)
+//
+//	{"type":"dahai","actor":0,"pai":"3m","tsumogiri":false,
+//	 "meta":{"candidates":[{"pai":"3m","prob":0.7},{"pai":"E","prob":0.3}]}}
+type Subprocess struct {
+	Command string
+	Args    []string
+	Engine  string        // 評価結果に残すエンジン名 (空ならコマンド名)
+	Timeout time.Duration // 0 なら時間制限なし (ctx の期限だけ)
+}
+
+func (s *Subprocess) Name() string {
+	if s.Engine != "" {
+		return s.Engine
+	}
+	return filepath.Base(s.Command)
+}
+
+// AIの返事 (使う項目だけ)
+type reply struct {
+	Type string `json:"type"`
+	Pai  string `json:"pai"`
+	Meta *struct {
+		Candidates []struct {
+			Pai  string  `json:"pai"`
+			Prob float64 `json:"prob"`
+		} `json:"candidates"`
+	} `json:"meta"`
+}
+
+func (s *Subprocess) Evaluate(ctx context.Context, events []mjai.Event) (*Result, error) {
+	if len(events) == 0 {
+		return nil, errors.New("no events to evaluate")
+	}
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start evaluator: %w", err)
+	}
+
+	last, err := converse(stdin, stdout, events)
+	stdin.Close()
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("evaluator timed out: %w", ctx.Err())
+	}
+	if err != nil {
+		if waitErr != nil {
+			return nil, fmt.Errorf("evaluator failed: %v (%s)", waitErr, bytes.TrimSpace(stderr.Bytes()))
+		}
+		return nil, err
+	}
+	return s.result(last)
+}
+
+// イベントを1行ずつ送って返事を読み、最後の返事を返す
+func converse(stdin io.Writer, stdout io.Reader, events []mjai.Event) (*reply, error) {
+	enc := json.NewEncoder(stdin)
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	var last reply
+	for i, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return nil, fmt.Errorf("failed to send event %d: %w", i, err)
+		}
+		if !sc.Scan() {
+			if err := sc.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("evaluator closed its output at event %d", i)
+		}
+		last = reply{}
+		if err := json.Unmarshal(sc.Bytes(), &last); err != nil {
+			return nil, fmt.Errorf("invalid reply to event %d: %w", i, err)
+		}
+	}
+	return &last, nil
+}
+
+// 最後の返事 (打牌) を評価結果にする
+func (s *Subprocess) result(last *reply) (*Result, error) {
+	if last.Type != mjai.TypeDahai {
+		return nil, fmt.Errorf("evaluator answered %q instead of a discard", last.Type)
+	}
+	res := &Result{Engine: s.Name()}
+
+	if last.Meta == nil || len(last.Meta.Candidates) == 0 {
+		tile, err := mjai.ParseTile(last.Pai)
+		if err != nil {
+			return nil, err
+		}
+		res.Candidates = []Candidate{{Tile: tile, Probability: 1}}
+		return res, nil
+	}
+
+	// 同じ牌 (赤5など) の確率はまとめる
+	probs := map[int]float64{}
+	for _, c := range last.Meta.Candidates {
+		tile, err := mjai.ParseTile(c.Pai)
+		if err != nil {
+			return nil, err
+		}
+		if c.Prob < 0 {
+			return nil, fmt.Errorf("negative probability for %s", c.Pai)
+		}
+		probs[tile] += c.Prob
+	}
+	for tile, p := range probs {
+		res.Candidates = append(res.Candidates, Candidate{Tile: tile, Probability: p})
+	}
+	sort.Slice(res.Candidates, func(i, j int) bool {
+		a, b := res.Candidates[i], res.Candidates[j]
+		if a.Probability != b.Probability {
+			return a.Probability > b.Probability
+		}
+		return a.Tile < b.Tile
+	})
+	return res, nil
+}
@@ -0,0 +1,143 @@
+// fakebot はテスト用の MJAI ボットです。
+//
+// 自分の手牌だけを追いかけ、ツモのたびに「孤立した字牌 > 孤立した么九牌 > その他」の
+// 重みで打牌の確率を付けて返します。
+//
+//	-crash    最初のイベントを読んだら異常終了する
+//	-sleep    打牌を返す前に待つ時間
+//	-nometa   確率 (meta) を付けずに打牌だけ返す
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type event struct {
+	Type     string     `json:"type"`
+	ID       *int       `json:"id"`
+	Actor    *int       `json:"actor"`
+	Pai      string     `json:"pai"`
+	Consumed []string   `json:"consumed"`
+	Tehais   [][]string `json:"tehais"`
+}
+
+type candidate struct {
+	Pai  string  `json:"pai"`
+	Prob float64 `json:"prob"`
+}
+
+func main() {
+	crash := flag.Bool("crash", false, "exit after the first event")
+	sleep := flag.Duration("sleep", 0, "wait before answering a discard")
+	nometa := flag.Bool("nometa", false, "answer without probabilities")
+	flag.Parse()
+
+	id := 0
+	var hand []string
+	out := json.NewEncoder(os.Stdout)
+	sc := bufio.NewScanner(os.Stdin)
+	for sc.Scan() {
+		if *crash {
+			fmt.Fprintln(os.Stderr, "fakebot: crashed on purpose")
+			os.Exit(2)
+		}
+		var e event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			fmt.Fprintln(os.Stderr, "fakebot:", err)
+			os.Exit(1)
+		}
+		mine := e.Actor != nil && *e.Actor == id
+
+		switch {
+		case e.Type == "start_game" && e.ID != nil:
+			id = *e.ID
+		case e.Type == "start_kyoku":
+			hand = append([]string(nil), e.Tehais[id]...)
+		case e.Type == "tsumo" && mine:
+			hand = append(hand, e.Pai)
+			if *sleep > 0 {
+				time.Sleep(*sleep)
+			}
+			cands := rank(hand)
+			reply := map[string]interface{}{"type": "dahai", "actor": id, "pai": cands[0].Pai, "tsumogiri": cands[0].Pai == e.Pai}
+			if !*nometa {
+				reply["meta"] = map[string]interface{}{"candidates": cands}
+			}
+			out.Encode(reply)
+			continue
+		case e.Type == "dahai" && mine:
+			hand = remove(hand, e.Pai)
+		case (e.Type == "chi" || e.Type == "pon" || e.Type == "daiminkan" || e.Type == "ankan") && mine:
+			for _, c := range e.Consumed {
+				hand = remove(hand, c)
+			}
+		case e.Type == "kakan" && mine:
+			hand = remove(hand, e.Pai)
+		}
+		out.Encode(map[string]string{"type": "none"})
+	}
+}
+
+func remove(hand []string, pai string) []string {
+	for i, p := range hand {
+		if p == pai {
+			return append(hand[:i], hand[i+1:]...)
+		}
+	}
+	return hand
+}
+
+// 牌ごとの重みを付けて確率にする
+func rank(hand []string) []candidate {
+	count := map[string]int{}
+	for _, p := range hand {
+		count[p]++
+	}
+	weights := map[string]float64{}
+	total := 0.0
+	for p := range count {
+		w := 1.0
+		if count[p] == 1 && !hasNeighbor(count, p) {
+			w += 2
+			if len(p) == 1 {
+				w += 3 // 字牌
+			} else if p[0] == '1' || p[0] == '9' {
+				w += 1
+			}
+		}
+		weights[p] = w
+		total += w
+	}
+	var cands []candidate
+	for p, w := range weights {
+		cands = append(cands, candidate{Pai: p, Prob: w / total})
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].Prob != cands[j].Prob {
+			return cands[i].Prob > cands[j].Prob
+		}
+		return cands[i].Pai < cands[j].Pai
+	})
+	return cands
+}
+
+// 数牌の両隣・1つ飛びに同じ色の牌があるか
+func hasNeighbor(count map[string]int, p string) bool {
+	if len(p) != 2 || !strings.ContainsRune("mps", rune(p[1])) {
+		return false
+	}
+	for _, d := range []int{-2, -1, 1, 2} {
+		n := int(p[0]-'0') + d
+		if n >= 1 && n <= 9 && count[fmt.Sprintf("%d%c", n, p[1])] > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,97 @@
+package mahjong
+
+import "fmt"
+
+// 自動タグの判定に使う状況
+type Situation struct {
+	Dealer         bool // 親番
+	AllLast        bool // オーラス
+	OpponentRiichi bool // 他家からリーチがかかっている
+}
+
+// 自動タグの名前 (Tag.Slug と同じ形式)
+const (
+	TagHonitsu       = "honitsu"        // 混一色が狙える
+	TagChinitsu      = "chinitsu"       // 清一色が狙える
+	TagChiitoitsu    = "chiitoitsu"     // 七対子の候補
+	TagDealer        = "dealer"         // 親
+	TagNonDealer     = "non-dealer"     // 子
+	TagAllLast       = "all-last"       // オーラス
+	TagRiichiDefense = "riichi-defense" // リーチを受けての押し引き
+)
+
+// 混一色・清一色の候補とみなす、他の色の牌の上限枚数
+const flushOffSuitLimit = 3
+
+// 手牌と状況から自動で付けるタグを求める
+//
+//	向聴数: shanten-0 (聴牌以上) / shanten-1 / shanten-2 / shanten-3-plus
+//	ドラ:   dora-0 / dora-1 / dora-2 / dora-3-plus
+//	その他: honitsu, chinitsu, chiitoitsu, dealer / non-dealer, all-last, riichi-defense
+func AutoTags(hand, doraIndicators []int, melds int, s Situation) []string {
+	c := CountTiles(hand)
+	tags := []string{ShantenTag(Shanten(hand, melds))}
+
+	// ドラの枚数
+	dora := 0
+	for _, indicator := range doraIndicators {
+		dora += c[DoraFromIndicator(indicator)]
+	}
+	if dora >= 3 {
+		tags = append(tags, "dora-3-plus")
+	} else {
+		tags = append(tags, fmt.Sprintf("dora-%d", dora))
+	}
+
+	// 染め手: 一番多い色と字牌以外が少なければ候補
+	honors := 0
+	for t := East; t < NumTileKinds; t++ {
+		honors += c[t]
+	}
+	bestSuit := 0
+	for suit := 0; suit < 3; suit++ {
+		n := 0
+		for t := suit * 9; t < suit*9+9; t++ {
+			n += c[t]
+		}
+		if n > bestSuit {
+			bestSuit = n
+		}
+	}
+	switch {
+	case len(hand)-bestSuit <= flushOffSuitLimit:
+		tags = append(tags, TagChinitsu)
+	case len(hand)-bestSuit-honors <= flushOffSuitLimit && honors > 0:
+		tags = append(tags, TagHonitsu)
+	}
+
+	// 七対子: 副露なしで七対子の向聴数が2以下
+	if melds == 0 && ChiitoitsuShanten(c) <= 2 {
+		tags = append(tags, TagChiitoitsu)
+	}
+
+	if s.Dealer {
+		tags = append(tags, TagDealer)
+	} else {
+		tags = append(tags, TagNonDealer)
+	}
+	if s.AllLast {
+		tags = append(tags, TagAllLast)
+	}
+	if s.OpponentRiichi {
+		tags = append(tags, TagRiichiDefense)
+	}
+	return tags
+}
+
+// 向聴数のタグ (和了形・聴牌はまとめて shanten-0)
+func ShantenTag(shanten int) string {
+	switch {
+	case shanten <= 0:
+		return "shanten-0"
+	case shanten >= 3:
+		return "shanten-3-plus"
+	default:
+		return fmt.Sprintf("shanten-%d", shanten)
+	}
+}
@@ -0,0 +1,61 @@
+package mahjong
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutoTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		hand  []int
+		dora  []int
+		melds int
+		s     Situation
+		want  []string
+	}{
+		{
+			name: "seed problem",
+			hand: []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 32},
+			dora: []int{32}, // 發表示 -> 中がドラ
+			s:    Situation{Dealer: true},
+			want: []string{"shanten-0", "dora-0", TagDealer},
+		},
+		{
+			name: "manzu flush with honors, dora three",
+			hand: []int{0, 1, 2, 4, 4, 4, 6, 7, 27, 27, 31, 11, 20, 30},
+			dora: []int{3}, // 4m表示 -> 5mがドラ
+			s:    Situation{AllLast: true, OpponentRiichi: true},
+			want: []string{"shanten-2", "dora-3-plus", TagHonitsu, TagNonDealer, TagAllLast, TagRiichiDefense},
+		},
+		{
+			name: "pure flush",
+			hand: []int{9, 9, 10, 11, 12, 13, 14, 15, 16, 17, 17, 17, 0, 27},
+			dora: []int{27},
+			want: []string{"shanten-1", "dora-0", TagChinitsu, TagNonDealer},
+		},
+		{
+			name: "many pairs",
+			hand: []int{0, 0, 4, 4, 10, 10, 15, 15, 21, 24, 28, 30, 33, 33},
+			dora: []int{28},
+			want: []string{"shanten-1", "dora-0", TagChiitoitsu, TagNonDealer},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AutoTags(tt.hand, tt.dora, tt.melds, tt.s)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShantenTag(t *testing.T) {
+	for shanten, want := range map[int]string{-1: "shanten-0", 0: "shanten-0", 1: "shanten-1", 2: "shanten-2", 5: "shanten-3-plus"} {
+		if got := ShantenTag(shanten); got != want {
+			t.Errorf("ShantenTag(%d): expected %s, got %s", shanten, want, got)
+		}
+	}
+}
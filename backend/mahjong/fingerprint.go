@@ -0,0 +1,189 @@
+package mahjong
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 同じ問題とみなす牌の並べ替え
+//
+// 萬子・筒子・索子の入れ替えでは牌効率も押し引きも変わらないので、6通りの色の入れ替えは同じ手牌とみなす。
+// 1↔9 の反転 (1萬→9萬, 2萬→8萬...) も牌効率は変わらないが、数牌のドラ表示牌があると
+// ドラの位置がずれる (1萬表示→2萬ドラ が 9萬表示→1萬ドラ になる) ので、ドラ表示牌が字牌だけのときに限る。
+type transform struct {
+	suits  [3]int // 元の色 i を suits[i] に移す
+	mirror bool   // 1↔9 を反転する
+}
+
+var suitPermutations = [][3]int{
+	{0, 1, 2}, {0, 2, 1}, {1, 0, 2}, {1, 2, 0}, {2, 0, 1}, {2, 1, 0},
+}
+
+func (t transform) apply(tile int) int {
+	if !IsSuited(tile) {
+		return tile
+	}
+	n := tile % 9
+	if t.mirror {
+		n = 8 - n
+	}
+	return t.suits[tile/9]*9 + n
+}
+
+func (t transform) counts(tiles []int) Counts {
+	var c Counts
+	for _, tile := range tiles {
+		if tile >= 0 && tile < NumTileKinds {
+			c[t.apply(tile)]++
+		}
+	}
+	return c
+}
+
+// 手牌とドラ表示牌に使える並べ替えの一覧
+func transforms(dora []int) []transform {
+	mirror := true
+	for _, t := range dora {
+		if IsSuited(t) {
+			mirror = false
+			break
+		}
+	}
+	var list []transform
+	for _, p := range suitPermutations {
+		list = append(list, transform{suits: p})
+		if mirror {
+			list = append(list, transform{suits: p, mirror: true})
+		}
+	}
+	return list
+}
+
+// 枚数を 0-4 の数字で並べた文字列にする
+func (c Counts) key() string {
+	var b strings.Builder
+	for _, n := range c {
+		b.WriteByte(byte('0' + n))
+	}
+	return b.String()
+}
+
+// 手牌とドラ表示牌の正規形
+// 色の入れ替え・1↔9 の反転で移り合う手牌は同じ文字列になる
+//
+//	手牌34桁 + "/" + ドラ表示牌34桁 (各桁はその牌の枚数)
+func Canonical(hand, dora []int) string {
+	best := ""
+	for _, t := range transforms(dora) {
+		k := t.counts(hand).key() + "/" + t.counts(dora).key()
+		if best == "" || k < best {
+			best = k
+		}
+	}
+	return best
+}
+
+// 副露・河も含めた局面の正規形
+// 色の入れ替え・1↔9 の反転は手牌・ドラ表示牌と同じものを副露と河にもかける。
+// 副露も河もなければ Canonical と同じ文字列になる
+//
+//	Canonical + "/" + 4人分の副露 + "/" + 4人分の河 (副露・河は並び順も区別する)
+func CanonicalPosition(hand, dora []int, rivers Rivers, melds Melds) string {
+	if isEmptyTable(rivers, melds) {
+		return Canonical(hand, dora)
+	}
+	best := ""
+	for _, t := range transforms(dora) {
+		k := t.counts(hand).key() + "/" + t.counts(dora).key() + "/" + t.meldsKey(melds) + "/" + t.riversKey(rivers)
+		if best == "" || k < best {
+			best = k
+		}
+	}
+	return best
+}
+
+func isEmptyTable(rivers Rivers, melds Melds) bool {
+	for i := range rivers {
+		if len(rivers[i]) > 0 || len(melds[i]) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// 副露を "種類:牌,牌,牌:鳴いた牌:相手" で並べる (座席の区切りは "|")
+func (t transform) meldsKey(melds Melds) string {
+	var b strings.Builder
+	for seat, list := range melds {
+		if seat > 0 {
+			b.WriteByte('|')
+		}
+		for i, m := range list {
+			if i > 0 {
+				b.WriteByte(';')
+			}
+			tiles := make([]int, len(m.Tiles))
+			for j, tile := range m.Tiles {
+				tiles[j] = t.apply(tile)
+			}
+			sort.Ints(tiles)
+			fmt.Fprintf(&b, "%s:%v:%d:%d", m.Type, tiles, t.apply(m.Called), m.From)
+		}
+	}
+	return b.String()
+}
+
+// 河を捨てた順に並べる (ツモ切り・リーチ宣言・鳴かれたを印で付ける。座席の区切りは "|")
+func (t transform) riversKey(rivers Rivers) string {
+	var b strings.Builder
+	for seat, river := range rivers {
+		if seat > 0 {
+			b.WriteByte('|')
+		}
+		for _, r := range river {
+			fmt.Fprintf(&b, "%02d", t.apply(r.Tile))
+			if r.Tsumogiri {
+				b.WriteByte('t')
+			}
+			if r.Riichi {
+				b.WriteByte('r')
+			}
+			if r.Called {
+				b.WriteByte('c')
+			}
+			b.WriteByte(',')
+		}
+	}
+	return b.String()
+}
+
+// 2つの問題の牌の違い (何枚入れ替えれば同じ正規形になるか)
+// 手牌の枚数が違う場合は枚数の差も含める。0 なら Canonical が一致する
+func Distance(handA, doraA, handB, doraB []int) int {
+	ha, da := CountTiles(handA), CountTiles(doraA)
+	best := -1
+	for _, t := range transforms(doraB) {
+		d := countDistance(ha, t.counts(handB)) + countDistance(da, t.counts(doraB))
+		if best < 0 || d < best {
+			best = d
+		}
+	}
+	return best
+}
+
+// 枚数の差から入れ替える枚数を求める (余る側の枚数)
+func countDistance(a, b Counts) int {
+	more, less := 0, 0
+	for i := range a {
+		if d := a[i] - b[i]; d > 0 {
+			more += d
+		} else {
+			less -= d
+		}
+	}
+	if more > less {
+		return more
+	}
+	return less
+}
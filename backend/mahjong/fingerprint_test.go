@@ -0,0 +1,127 @@
+package mahjong
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	hand := []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 32}
+
+	tests := []struct {
+		name      string
+		hand      []int
+		dora      []int
+		otherHand []int
+		otherDora []int
+		same      bool
+	}{
+		{
+			name:      "order does not matter",
+			hand:      hand,
+			dora:      []int{32},
+			otherHand: []int{32, 31, 31, 27, 27, 20, 19, 18, 11, 10, 9, 2, 1, 0},
+			otherDora: []int{32},
+			same:      true,
+		},
+		{
+			name:      "suits swapped",
+			hand:      []int{0, 1, 2, 3, 12, 13, 14, 22, 23, 24, 27, 27, 27, 33},
+			dora:      []int{5}, // 6m表示
+			otherHand: []int{9, 10, 11, 12, 21, 22, 23, 4, 5, 6, 27, 27, 27, 33},
+			otherDora: []int{14}, // 6p表示
+			same:      true,
+		},
+		{
+			name:      "mirrored with honor dora",
+			hand:      []int{0, 1, 2, 3, 9, 9, 27, 27, 27, 31, 31, 31, 33, 33},
+			dora:      []int{28},
+			otherHand: []int{8, 7, 6, 5, 17, 17, 27, 27, 27, 31, 31, 31, 33, 33},
+			otherDora: []int{28},
+			same:      true,
+		},
+		{
+			name:      "mirrored with suited dora",
+			hand:      []int{0, 1, 2, 3, 9, 9, 27, 27, 27, 31, 31, 31, 33, 33},
+			dora:      []int{0},
+			otherHand: []int{8, 7, 6, 5, 17, 17, 27, 27, 27, 31, 31, 31, 33, 33},
+			otherDora: []int{8},
+			same:      false,
+		},
+		{
+			name:      "honors are not permuted",
+			hand:      []int{0, 1, 2, 3, 9, 9, 27, 27, 27, 31, 31, 31, 33, 33},
+			dora:      []int{28},
+			otherHand: []int{0, 1, 2, 3, 9, 9, 28, 28, 28, 31, 31, 31, 33, 33},
+			otherDora: []int{28},
+			same:      false,
+		},
+		{
+			name:      "different dora indicator",
+			hand:      hand,
+			dora:      []int{32},
+			otherHand: hand,
+			otherDora: []int{33},
+			same:      false,
+		},
+	}
+
+	for _, tt := range tests {
+		a := Canonical(tt.hand, tt.dora)
+		b := Canonical(tt.otherHand, tt.otherDora)
+		if (a == b) != tt.same {
+			t.Errorf("%s: Canonical equal = %v, want %v\n%s\n%s", tt.name, a == b, tt.same, a, b)
+		}
+		d := Distance(tt.hand, tt.dora, tt.otherHand, tt.otherDora)
+		if (d == 0) != tt.same {
+			t.Errorf("%s: Distance = %d, want zero = %v", tt.name, d, tt.same)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	hand := []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 32}
+	tests := []struct {
+		name  string
+		other []int
+		want  int
+	}{
+		{"identical", hand, 0},
+		{"one tile swapped", []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 33}, 1},
+		{"suits swapped and one tile", []int{9, 10, 11, 0, 1, 2, 18, 19, 21, 27, 27, 31, 31, 32}, 1},
+		{"two tiles swapped", []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 28, 28, 31, 31, 32}, 2},
+		{"one tile missing", []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31}, 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(hand, []int{27}, tt.other, []int{27}); got != tt.want {
+			t.Errorf("%s: Distance = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalPosition(t *testing.T) {
+	hand := []int{0, 1, 2, 9, 10, 11, 27, 27, 31, 31, 32}
+	melds := Melds{{{Type: MeldChi, Tiles: []int{18, 19, 20}, Called: 18, From: 3}}}
+	rivers := Rivers{{{Tile: 8}, {Tile: 33, Tsumogiri: true}}, {{Tile: 26}}}
+
+	// 萬子と索子を入れ替えた同じ局面
+	swapped := []int{18, 19, 20, 9, 10, 11, 27, 27, 31, 31, 32}
+	swappedMelds := Melds{{{Type: MeldChi, Tiles: []int{0, 1, 2}, Called: 0, From: 3}}}
+	swappedRivers := Rivers{{{Tile: 26}, {Tile: 33, Tsumogiri: true}}, {{Tile: 8}}}
+
+	base := CanonicalPosition(hand, []int{27}, rivers, melds)
+	if got := CanonicalPosition(swapped, []int{27}, swappedRivers, swappedMelds); got != base {
+		t.Errorf("suit swap should not change the key\n%s\n%s", base, got)
+	}
+	// 河が違えば別の局面
+	other := Rivers{{{Tile: 8}, {Tile: 33}}, {{Tile: 26}}}
+	if CanonicalPosition(hand, []int{27}, other, melds) == base {
+		t.Error("a different river should change the key")
+	}
+	// 鳴いた相手が違えば別の局面
+	otherMelds := Melds{{{Type: MeldChi, Tiles: []int{18, 19, 20}, Called: 18, From: 2}}}
+	if CanonicalPosition(hand, []int{27}, rivers, otherMelds) == base {
+		t.Error("a different meld should change the key")
+	}
+	// 副露も河もなければ手牌だけの正規形と同じ
+	if CanonicalPosition(hand, []int{27}, Rivers{}, Melds{}) != Canonical(hand, []int{27}) {
+		t.Error("an empty table should match Canonical")
+	}
+}
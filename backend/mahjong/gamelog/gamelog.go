@@ -0,0 +1,70 @@
+// Package gamelog は牌譜 (天鳳・MJAI など) を共通の形に読み替えたものと、
+// それを再生して途中の局面を取り出す処理をまとめたものです。
+//
+// 座席は起家を 0 とした絶対位置 (0-3) で表します。
+package gamelog
+
+import (
+	"fmt"
+	"portfolio-backend/mahjong"
+)
+
+// 見えていない牌 (他家の手牌やツモ)
+// 再生するときはどの牌の代わりにもなる
+const Unknown = -1
+
+// 局の中で起きたこと
+type EventKind int
+
+const (
+	Draw    EventKind = iota // ツモ (嶺上牌も含む)
+	Discard                  // 打牌
+	Call                     // 副露 (チー・ポン・槓)
+	Dora                     // 槓ドラがめくれた
+)
+
+type Event struct {
+	Kind      EventKind
+	Seat      int
+	Tile      int          // Draw / Discard の牌、Dora の表示牌
+	Tsumogiri bool         // Discard: ツモ切り
+	Riichi    bool         // Discard: リーチ宣言牌
+	Meld      mahjong.Meld // Call: From は絶対位置
+}
+
+// 1局分の牌譜
+type Kyoku struct {
+	Wind         int // 場風 (0 東, 1 南, 2 西, 3 北)
+	Number       int // 何局目か (1-4)
+	Honba        int
+	RiichiSticks int
+	Dealer       int
+	Scores       [4]int
+	Dora         int      // 最初のドラ表示牌
+	Hands        [4][]int // 配牌
+	Events       []Event
+}
+
+var windNames = [4]string{"東", "南", "西", "北"}
+
+// 風の名前 (フロントエンドと同じ表記)
+func WindName(wind int) string { return windNames[((wind%4)+4)%4] }
+
+// 局の名前 (例: "東1")
+func (k *Kyoku) Name() string {
+	return fmt.Sprintf("%s%d", WindName(k.Wind), k.Number)
+}
+
+// 座席の自風
+func (k *Kyoku) SeatWind(seat int) int { return (seat - k.Dealer + 4) % 4 }
+
+// 座席ごとの打牌の回数 (何巡目まで選べるか)
+func (k *Kyoku) Turns() [4]int {
+	var turns [4]int
+	for _, e := range k.Events {
+		if e.Kind == Discard {
+			turns[e.Seat]++
+		}
+	}
+	return turns
+}
@@ -0,0 +1,300 @@
+package gamelog
+
+import (
+	"errors"
+	"fmt"
+	"portfolio-backend/mahjong"
+)
+
+// 問題として保存している局面 (手牌・ドラ・状況・河・副露)
+// 河・副露・点数は自分から見た順 (0 自分, 1 下家, 2 対面, 3 上家)
+type Position struct {
+	Wind     int // 場風 (0 東, 1 南, ...)
+	Number   int // 何局目か (1-4)
+	Honba    int
+	SeatWind int   // 自風 (0 東, 1 南, ...)
+	Hand     []int // 手牌 (14枚形なら最後の1枚をツモった牌とみなす)
+	Dora     []int // ドラ表示牌 (2枚目以降は槓ドラ)
+	Rivers   mahjong.Rivers
+	Melds    mahjong.Melds
+	Scores   [4]int
+}
+
+// 局面を、そこに至る牌譜にする (自分を座席 0 にする)
+//
+// 実際の順番は残っていないので、河の順に1人ずつ打牌したものとして組み立てる。
+// 他家の手牌とツモは Unknown、自分のツモは切った牌をそのままツモったことにし、
+// 自分の配牌は最後の手牌と辻褄が合うように逆算する。
+// 鳴きは鳴かれた牌の直後、暗槓・加槓は次のツモのときに行ったことにする。
+func (p Position) Kyoku() (*Kyoku, error) {
+	if p.Number < 1 || p.Number > 4 {
+		return nil, errors.New("round number must be 1-4")
+	}
+	if len(p.Dora) == 0 {
+		return nil, errors.New("at least one dora indicator is required")
+	}
+
+	b := &positionBuilder{p: p}
+	b.k = &Kyoku{
+		Wind:   p.Wind,
+		Number: p.Number,
+		Honba:  p.Honba,
+		Dealer: (4 - p.SeatWind%4) % 4,
+		Dora:   p.Dora[0],
+		Scores: p.Scores,
+	}
+	b.doras = p.Dora[1:]
+	if err := b.matchCalls(); err != nil {
+		return nil, err
+	}
+	if err := b.run(); err != nil {
+		return nil, err
+	}
+	if err := b.fillHands(); err != nil {
+		return nil, err
+	}
+	if err := Validate(b.k); err != nil {
+		return nil, fmt.Errorf("position is not consistent: %w", err)
+	}
+	return b.k, nil
+}
+
+type positionBuilder struct {
+	p     Position
+	k     *Kyoku
+	doras []int // まだめくっていない槓ドラ
+
+	calls    [4]map[int]mahjong.Meld // calls[座席][河の何枚目] = その牌を鳴いた副露 (From は絶対位置)
+	callers  [4]map[int]int          // 鳴いた人
+	kans     [4][]mahjong.Meld       // 次のツモのときに行う暗槓・加槓
+	ponDone  [4]map[int]bool         // 加槓の元になるポンを済ませたか (牌の種類ごと)
+	removed  mahjong.Counts          // 自分の手牌から出ていった牌
+	drawn    mahjong.Counts          // 自分がツモった牌
+	unplaced int                     // まだ行っていない暗槓・加槓の数
+}
+
+func (b *positionBuilder) emit(e Event) { b.k.Events = append(b.k.Events, e) }
+
+// 鳴いた副露と、河の「鳴かれた」牌を対応させる
+func (b *positionBuilder) matchCalls() error {
+	for i := range b.calls {
+		b.calls[i] = map[int]mahjong.Meld{}
+		b.callers[i] = map[int]int{}
+		b.ponDone[i] = map[int]bool{}
+	}
+	for seat, list := range b.p.Melds {
+		for _, m := range list {
+			m.Tiles = append([]int(nil), m.Tiles...)
+			switch m.Type {
+			case mahjong.MeldAnkan:
+				m.From = seat
+				b.kans[seat] = append(b.kans[seat], m)
+				b.unplaced++
+				continue
+			case mahjong.MeldKakan:
+				// 加槓は元のポンと、あとの加槓に分ける
+				b.kans[seat] = append(b.kans[seat], m)
+				b.unplaced++
+				m = mahjong.Meld{Type: mahjong.MeldPon, Tiles: m.Tiles[:3], Called: m.Called, From: m.From}
+			}
+			if m.From < 1 || m.From > 3 {
+				return fmt.Errorf("%s must be called from another player", m.Type)
+			}
+			from := (seat + m.From) % 4
+			m.From = from
+			found := false
+			for i, r := range b.p.Rivers[from] {
+				if _, used := b.calls[from][i]; !used && r.Called && r.Tile == m.Called {
+					b.calls[from][i] = m
+					b.callers[from][i] = seat
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("no called tile %d in the river of player %d", m.Called, m.From)
+			}
+		}
+	}
+	return nil
+}
+
+// 河の順に打牌していく
+func (b *positionBuilder) run() error {
+	rivers := b.p.Rivers
+	hand := b.p.Hand
+	var next [4]int
+	cur, afterCall, skipped := b.k.Dealer, false, 0
+
+	for {
+		if afterCall && next[cur] >= len(rivers[cur]) {
+			// 鳴いた直後 (打牌の前) で河が終わっている
+			if cur != 0 {
+				return fmt.Errorf("river of player %d ends right after a call", cur)
+			}
+			break
+		}
+		if !afterCall && next[cur] >= len(rivers[cur]) {
+			if cur == 0 {
+				break
+			}
+			// 河が短い他家は飛ばす (手で入力した局面など)
+			if skipped++; skipped > 4 {
+				break
+			}
+			cur = (cur + 1) % 4
+			continue
+		}
+		skipped = 0
+
+		if !afterCall {
+			b.placeKans(cur)
+			b.draw(cur, rivers[cur][next[cur]].Tile)
+		}
+		r := rivers[cur][next[cur]]
+		b.emit(Event{Kind: Discard, Seat: cur, Tile: r.Tile, Tsumogiri: r.Tsumogiri, Riichi: r.Riichi})
+		if cur == 0 {
+			b.removed[r.Tile]++
+		}
+
+		m, called := b.calls[cur][next[cur]]
+		next[cur]++
+		if !called {
+			cur, afterCall = (cur+1)%4, false
+			continue
+		}
+
+		caller := b.callers[cur][next[cur]-1]
+		b.emit(Event{Kind: Call, Seat: caller, Meld: m})
+		if caller == 0 {
+			consumed := append([]int(nil), m.Tiles...)
+			consumed, _ = removeTile(consumed, m.Called)
+			for _, t := range consumed {
+				b.removed[t]++
+			}
+		}
+		if m.Type == mahjong.MeldPon {
+			b.ponDone[caller][m.Called] = true
+		}
+		cur = caller
+		// 大明槓なら嶺上牌をツモって打牌、チー・ポンはそのまま打牌
+		afterCall = m.Type != mahjong.MeldDaiminkan
+		if !afterCall {
+			b.revealDora()
+		}
+	}
+
+	// 最後に自分がツモったところで止める (手牌が 3n+2 枚のとき。鳴いた直後ならツモはない)
+	if len(hand)%3 == 2 && !afterCall {
+		b.placeKans(0)
+		b.draw(0, hand[len(hand)-1])
+	}
+	if b.unplaced > 0 {
+		return errors.New("could not place every ankan/kakan")
+	}
+	for seat := range rivers {
+		if next[seat] < len(rivers[seat]) {
+			return fmt.Errorf("river of player %d does not fit the turn order", seat)
+		}
+	}
+	return nil
+}
+
+// ツモ (自分以外は見えない牌)
+func (b *positionBuilder) draw(seat, tile int) {
+	if seat != 0 {
+		tile = Unknown
+	} else {
+		b.drawn[tile]++
+	}
+	b.emit(Event{Kind: Draw, Seat: seat, Tile: tile})
+}
+
+// まだ行っていない暗槓・加槓をツモのときに行う (加槓は元のポンのあと)
+func (b *positionBuilder) placeKans(seat int) {
+	rest := b.kans[seat][:0]
+	for _, m := range b.kans[seat] {
+		tile := m.Tiles[0]
+		if m.Type == mahjong.MeldKakan {
+			tile = m.Called
+			if !b.ponDone[seat][m.Called] {
+				rest = append(rest, m)
+				continue
+			}
+		}
+		b.draw(seat, tile)
+		b.emit(Event{Kind: Call, Seat: seat, Meld: m})
+		if seat == 0 {
+			if m.Type == mahjong.MeldKakan {
+				b.removed[m.Called]++
+			} else {
+				for _, t := range m.Tiles {
+					b.removed[t]++
+				}
+			}
+		}
+		b.revealDora()
+		b.unplaced--
+	}
+	b.kans[seat] = rest
+}
+
+func (b *positionBuilder) revealDora() {
+	if len(b.doras) > 0 {
+		b.emit(Event{Kind: Dora, Tile: b.doras[0]})
+		b.doras = b.doras[1:]
+	}
+}
+
+// 配牌を決める: 自分は 最後の手牌 + 出ていった牌 - ツモった牌、他家は見えない牌
+func (b *positionBuilder) fillHands() error {
+	// 槓の数より多いドラ表示牌は最初からめくれていたことにする
+	if len(b.doras) > 0 {
+		extra := make([]Event, len(b.doras))
+		for i, d := range b.doras {
+			extra[i] = Event{Kind: Dora, Tile: d}
+		}
+		b.k.Events = append(extra, b.k.Events...)
+	}
+
+	counts := mahjong.CountTiles(b.p.Hand)
+	total := 0
+	for t := range counts {
+		counts[t] += b.removed[t] - b.drawn[t]
+		if counts[t] < 0 {
+			return fmt.Errorf("tile %d is discarded more often than it could be held", t)
+		}
+		total += counts[t]
+	}
+	if total != 13 {
+		return fmt.Errorf("hand, rivers and melds add up to %d starting tiles (want 13)", total)
+	}
+	for t, n := range counts {
+		for i := 0; i < n; i++ {
+			b.k.Hands[0] = append(b.k.Hands[0], t)
+		}
+	}
+	for seat := 1; seat < 4; seat++ {
+		b.k.Hands[seat] = make([]int, 13)
+		for i := range b.k.Hands[seat] {
+			b.k.Hands[seat][i] = Unknown
+		}
+	}
+	return nil
+}
+
+// 再生した局面を seat から見た Position にする
+func (s *State) Position(seat int) Position {
+	rivers, melds, scores := s.View(seat)
+	return Position{
+		Wind:     s.Kyoku.Wind,
+		Number:   s.Kyoku.Number,
+		Honba:    s.Kyoku.Honba,
+		SeatWind: s.Kyoku.SeatWind(seat),
+		Hand:     append([]int(nil), s.Players[seat].Hand...),
+		Dora:     append([]int(nil), s.DoraIndicators...),
+		Rivers:   rivers,
+		Melds:    melds,
+		Scores:   scores,
+	}
+}
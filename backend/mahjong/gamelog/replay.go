@@ -0,0 +1,189 @@
+package gamelog
+
+import (
+	"fmt"
+	"portfolio-backend/mahjong"
+	"sort"
+)
+
+// 再生中の1人分の状態
+type Player struct {
+	Hand   []int // 手牌 (小さい順)
+	River  []mahjong.RiverTile
+	Melds  []mahjong.Meld // From は絶対位置
+	Riichi bool
+}
+
+// ある時点の局面
+type State struct {
+	Kyoku          *Kyoku
+	Players        [4]Player
+	DoraIndicators []int
+	Turn           int // Seat が何回目の打牌を考えているところか
+	Seat           int // 打牌を考えている人
+}
+
+// 局を再生して、seat の turn 回目 (1始まり) の打牌の直前の局面を返す
+// ツモ・鳴きの後、牌を切る前の状態になる
+func Replay(k *Kyoku, seat, turn int) (*State, error) {
+	if seat < 0 || seat > 3 {
+		return nil, fmt.Errorf("seat must be 0-3")
+	}
+	if turn < 1 {
+		return nil, fmt.Errorf("turn must be 1 or more")
+	}
+
+	s := &State{Kyoku: k, DoraIndicators: []int{k.Dora}, Seat: seat, Turn: turn}
+	for i := range s.Players {
+		s.Players[i].Hand = append([]int(nil), k.Hands[i]...)
+		sort.Ints(s.Players[i].Hand)
+	}
+
+	discards := 0
+	for i, e := range k.Events {
+		if e.Kind == Discard && e.Seat == seat {
+			discards++
+			if discards == turn {
+				return s, nil
+			}
+		}
+		if err := s.apply(e); err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+	}
+	// 牌譜がちょうど seat の打牌の直前 (ツモか鳴きのあと) で終わっている場合 (問題から作った牌譜など)
+	if n := len(k.Events); discards == turn-1 && n > 0 {
+		if last := k.Events[n-1]; last.Seat == seat && (last.Kind == Draw || last.Kind == Call) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("seat %d has only %d discards in %s", seat, discards, k.Name())
+}
+
+// 局を最後まで再生する (牌譜が壊れていないかのチェックに使う)
+func Validate(k *Kyoku) error {
+	s := &State{Kyoku: k, DoraIndicators: []int{k.Dora}}
+	for i := range s.Players {
+		s.Players[i].Hand = append([]int(nil), k.Hands[i]...)
+		sort.Ints(s.Players[i].Hand)
+	}
+	for i, e := range k.Events {
+		if err := s.apply(e); err != nil {
+			return fmt.Errorf("%s event %d: %w", k.Name(), i, err)
+		}
+	}
+	return nil
+}
+
+func (s *State) apply(e Event) error {
+	p := &s.Players[e.Seat]
+	switch e.Kind {
+	case Draw:
+		p.Hand = insertTile(p.Hand, e.Tile)
+	case Discard:
+		hand, ok := removeTile(p.Hand, e.Tile)
+		if !ok {
+			return fmt.Errorf("seat %d discards tile %d which is not in hand", e.Seat, e.Tile)
+		}
+		p.Hand = hand
+		p.River = append(p.River, mahjong.RiverTile{Tile: e.Tile, Tsumogiri: e.Tsumogiri, Riichi: e.Riichi})
+		if e.Riichi {
+			p.Riichi = true
+		}
+	case Call:
+		return s.call(e)
+	case Dora:
+		s.DoraIndicators = append(s.DoraIndicators, e.Tile)
+	}
+	return nil
+}
+
+// 副露: 手牌から使った牌を抜き、鳴いた牌は相手の河で「鳴かれた」印を付ける
+func (s *State) call(e Event) error {
+	p := &s.Players[e.Seat]
+	m := e.Meld
+	m.Tiles = append([]int(nil), m.Tiles...)
+	sort.Ints(m.Tiles)
+
+	fromHand := append([]int(nil), m.Tiles...)
+	switch m.Type {
+	case mahjong.MeldChi, mahjong.MeldPon, mahjong.MeldDaiminkan:
+		river := s.Players[m.From].River
+		if len(river) == 0 || river[len(river)-1].Tile != m.Called {
+			return fmt.Errorf("seat %d calls tile %d which was not just discarded", e.Seat, m.Called)
+		}
+		river[len(river)-1].Called = true
+		fromHand, _ = removeTile(fromHand, m.Called)
+	case mahjong.MeldKakan:
+		// 元のポンを槓に置き換える。手牌から出すのは加えた1枚だけ
+		found := false
+		for i, old := range p.Melds {
+			if old.Type == mahjong.MeldPon && len(old.Tiles) > 0 && old.Tiles[0] == m.Called {
+				m.From = old.From
+				p.Melds = append(p.Melds[:i], p.Melds[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("seat %d adds tile %d without a pon", e.Seat, m.Called)
+		}
+		fromHand = []int{m.Called}
+	case mahjong.MeldAnkan:
+		m.From = e.Seat
+	default:
+		return fmt.Errorf("unknown meld type %q", m.Type)
+	}
+
+	for _, t := range fromHand {
+		hand, ok := removeTile(p.Hand, t)
+		if !ok {
+			return fmt.Errorf("seat %d calls %s without tile %d in hand", e.Seat, m.Type, t)
+		}
+		p.Hand = hand
+	}
+	p.Melds = append(p.Melds, m)
+	return nil
+}
+
+// 自分 (seat) から見た相対位置 (0 自分, 1 下家, 2 対面, 3 上家)
+func Relative(seat, other int) int { return (other - seat + 4) % 4 }
+
+// 局面を問題に保存する形にする (河・副露・点数を seat から見た順に並べ替える)
+func (s *State) View(seat int) (rivers mahjong.Rivers, melds mahjong.Melds, scores [4]int) {
+	for abs := 0; abs < 4; abs++ {
+		rel := Relative(seat, abs)
+		rivers[rel] = append([]mahjong.RiverTile{}, s.Players[abs].River...)
+		melds[rel] = []mahjong.Meld{}
+		for _, m := range s.Players[abs].Melds {
+			if m.Type == mahjong.MeldAnkan {
+				m.From = 0
+			} else {
+				m.From = Relative(abs, m.From)
+			}
+			melds[rel] = append(melds[rel], m)
+		}
+		scores[rel] = s.Kyoku.Scores[abs]
+	}
+	return rivers, melds, scores
+}
+
+func insertTile(hand []int, tile int) []int {
+	i := sort.SearchInts(hand, tile)
+	hand = append(hand, 0)
+	copy(hand[i+1:], hand[i:])
+	hand[i] = tile
+	return hand
+}
+
+// 手牌から1枚抜く。その牌がなければ見えていない牌を代わりに抜く
+func removeTile(hand []int, tile int) ([]int, bool) {
+	for _, want := range []int{tile, Unknown} {
+		for i, t := range hand {
+			if t == want {
+				return append(hand[:i:i], hand[i+1:]...), true
+			}
+		}
+	}
+	return hand, false
+}
@@ -0,0 +1,134 @@
+package mahjong
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 副露の種類
+const (
+	MeldChi       = "chi"       // チー
+	MeldPon       = "pon"       // ポン
+	MeldDaiminkan = "daiminkan" // 大明槓
+	MeldAnkan     = "ankan"     // 暗槓
+	MeldKakan     = "kakan"     // 加槓
+)
+
+// 副露1つ分
+// From は鳴いた相手 (問題に保存するときは自分から見た位置: 1 下家, 2 対面, 3 上家。暗槓は 0)
+type Meld struct {
+	Type   string `json:"type"`
+	Tiles  []int  `json:"tiles"`  // 副露した牌 (鳴いた牌も含む、小さい順)
+	Called int    `json:"called"` // 鳴いた牌 (加槓は加えた牌、暗槓は -1)
+	From   int    `json:"from"`
+}
+
+// 槓か
+func (m Meld) IsKan() bool {
+	return m.Type == MeldDaiminkan || m.Type == MeldAnkan || m.Type == MeldKakan
+}
+
+// 河の牌1枚分
+type RiverTile struct {
+	Tile      int  `json:"tile"`
+	Tsumogiri bool `json:"tsumogiri,omitempty"` // ツモ切り
+	Riichi    bool `json:"riichi,omitempty"`    // リーチ宣言牌
+	Called    bool `json:"called,omitempty"`    // 鳴かれた (副露の方に移った)
+}
+
+// 4人分の河・副露・点数は自分から見た順 (0 自分, 1 下家, 2 対面, 3 上家) で並べる
+type (
+	Rivers [4][]RiverTile
+	Melds  [4][]Meld
+)
+
+// DBに保存している JSON 文字列から河を読む (空文字は河なし)
+func ParseRivers(s string) (Rivers, error) {
+	var rivers Rivers
+	if s == "" {
+		return rivers, nil
+	}
+	if err := json.Unmarshal([]byte(s), &rivers); err != nil {
+		return rivers, fmt.Errorf("rivers must be a JSON array of 4 rivers: %w", err)
+	}
+	for _, river := range rivers {
+		for _, r := range river {
+			if r.Tile < 0 || r.Tile >= NumTileKinds {
+				return rivers, fmt.Errorf("invalid tile ID %d in rivers", r.Tile)
+			}
+		}
+	}
+	return rivers, nil
+}
+
+// DBに保存している JSON 文字列から副露を読む (空文字は副露なし)
+func ParseMelds(s string) (Melds, error) {
+	var melds Melds
+	if s == "" {
+		return melds, nil
+	}
+	if err := json.Unmarshal([]byte(s), &melds); err != nil {
+		return melds, fmt.Errorf("melds must be a JSON array of 4 meld lists: %w", err)
+	}
+	for _, list := range melds {
+		for _, m := range list {
+			want := 3
+			switch m.Type {
+			case MeldChi, MeldPon:
+			case MeldDaiminkan, MeldAnkan, MeldKakan:
+				want = 4
+			default:
+				return melds, fmt.Errorf("invalid meld type %q", m.Type)
+			}
+			if len(m.Tiles) != want {
+				return melds, fmt.Errorf("%s must have %d tiles", m.Type, want)
+			}
+			for _, t := range m.Tiles {
+				if t < 0 || t >= NumTileKinds {
+					return melds, fmt.Errorf("invalid tile ID %d in melds", t)
+				}
+			}
+		}
+	}
+	return melds, nil
+}
+
+// 見えている牌 (副露した牌と、鳴かれていない河の牌) をまとめる
+// CheckCopies で手牌・ドラ表示牌と一緒に枚数をチェックするのに使う
+func VisibleTiles(rivers Rivers, melds Melds) []int {
+	var tiles []int
+	for _, river := range rivers {
+		for _, r := range river {
+			if !r.Called {
+				tiles = append(tiles, r.Tile)
+			}
+		}
+	}
+	for _, list := range melds {
+		for _, m := range list {
+			tiles = append(tiles, m.Tiles...)
+		}
+	}
+	return tiles
+}
+
+// JSON 文字列にする (DBに保存する形式)
+func (r Rivers) String() string {
+	for i := range r {
+		if r[i] == nil {
+			r[i] = []RiverTile{}
+		}
+	}
+	b, _ := json.Marshal(r)
+	return string(b)
+}
+
+func (m Melds) String() string {
+	for i := range m {
+		if m[i] == nil {
+			m[i] = []Meld{}
+		}
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
@@ -0,0 +1,40 @@
+package mahjong
+
+import "testing"
+
+func TestParseMelds(t *testing.T) {
+	melds, err := ParseMelds(`[[{"type":"pon","tiles":[27,27,27],"called":27,"from":3}],[],[],[{"type":"ankan","tiles":[0,0,0,0],"called":-1,"from":0}]]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(melds[0]) != 1 || melds[0][0].IsKan() || !melds[3][0].IsKan() {
+		t.Errorf("melds = %+v", melds)
+	}
+	if back, _ := ParseMelds(melds.String()); back[0][0].Called != East {
+		t.Errorf("round trip lost the called tile: %+v", back)
+	}
+
+	for _, bad := range []string{
+		`[[{"type":"pon","tiles":[27,27],"called":27,"from":3}],[],[],[]]`,
+		`[[{"type":"riichi","tiles":[1,2,3]}],[],[],[]]`,
+		`[[{"type":"chi","tiles":[1,2,40]}],[],[],[]]`,
+		`{}`,
+	} {
+		if _, err := ParseMelds(bad); err == nil {
+			t.Errorf("ParseMelds(%s) should fail", bad)
+		}
+	}
+}
+
+func TestVisibleTiles(t *testing.T) {
+	rivers, _ := ParseRivers(`[[{"tile":9}],[{"tile":27,"called":true},{"tile":5}],[],[]]`)
+	melds, _ := ParseMelds(`[[],[],[{"type":"pon","tiles":[27,27,27],"called":27,"from":3}],[]]`)
+	// 鳴かれた東は副露の方で数える
+	visible := VisibleTiles(rivers, melds)
+	if c := CountTiles(visible); c[East] != 3 || c[9] != 1 || c[5] != 1 || len(visible) != 5 {
+		t.Errorf("VisibleTiles = %v", visible)
+	}
+	if err := CheckCopies([]int{27, 27}, visible); err == nil {
+		t.Error("five Easts should be rejected")
+	}
+}
@@ -0,0 +1,257 @@
+package mjai
+
+import (
+	"errors"
+	"fmt"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"sort"
+)
+
+var bakazeNames = []string{"E", "S", "W", "N"}
+
+// 局を MJAI のイベント列にする (start_kyoku から、局の最後のイベントまで)
+// end_kyoku は付けないので、問題の局面を渡すときは最後のツモで止まる
+func EncodeKyoku(k *gamelog.Kyoku) []Event {
+	tehais := make([][]string, 4)
+	for i, hand := range k.Hands {
+		tehais[i] = tileStrings(hand)
+	}
+	events := []Event{{
+		Type:       TypeStartKyoku,
+		Bakaze:     bakazeNames[k.Wind%4],
+		DoraMarker: TileString(k.Dora),
+		Kyoku:      intp(k.Number),
+		Honba:      intp(k.Honba),
+		Kyotaku:    intp(k.RiichiSticks),
+		Oya:        intp(k.Dealer),
+		Scores:     k.Scores[:],
+		Tehais:     tehais,
+	}}
+
+	for _, e := range k.Events {
+		switch e.Kind {
+		case gamelog.Draw:
+			events = append(events, Event{Type: TypeTsumo, Actor: intp(e.Seat), Pai: TileString(e.Tile)})
+		case gamelog.Discard:
+			if e.Riichi {
+				events = append(events, Event{Type: TypeReach, Actor: intp(e.Seat)})
+			}
+			events = append(events, Event{Type: TypeDahai, Actor: intp(e.Seat), Pai: TileString(e.Tile), Tsumogiri: boolp(e.Tsumogiri)})
+			if e.Riichi {
+				events = append(events, Event{Type: TypeReachAccepted, Actor: intp(e.Seat)})
+			}
+		case gamelog.Call:
+			events = append(events, encodeCall(e))
+		case gamelog.Dora:
+			events = append(events, Event{Type: TypeDora, DoraMarker: TileString(e.Tile)})
+		}
+	}
+	return events
+}
+
+func encodeCall(e gamelog.Event) Event {
+	m := e.Meld
+	ev := Event{Type: m.Type, Actor: intp(e.Seat)}
+	consumed := append([]int(nil), m.Tiles...)
+	switch m.Type {
+	case mahjong.MeldAnkan:
+		ev.Consumed = tileStrings(consumed)
+		return ev
+	case mahjong.MeldKakan:
+		// 加えた牌と、元のポンの3枚
+		ev.Pai = TileString(m.Called)
+		ev.Consumed = tileStrings(consumed[:3])
+		return ev
+	}
+	for i, t := range consumed {
+		if t == m.Called {
+			consumed = append(consumed[:i], consumed[i+1:]...)
+			break
+		}
+	}
+	ev.Target = intp(m.From)
+	ev.Pai = TileString(m.Called)
+	ev.Consumed = tileStrings(consumed)
+	return ev
+}
+
+// 対局全体を MJAI のイベント列にする (start_game から end_game まで)
+func Encode(kyokus []gamelog.Kyoku) []Event {
+	events := []Event{{Type: TypeStartGame, Names: []string{"", "", "", ""}}}
+	for i := range kyokus {
+		events = append(events, EncodeKyoku(&kyokus[i])...)
+		events = append(events, Event{Type: TypeEndKyoku})
+	}
+	return append(events, Event{Type: TypeEndGame})
+}
+
+// MJAI のイベント列を局ごとの牌譜にする
+// 和了・流局・end_kyoku で局を区切る (end_kyoku がなくても最後の局は読み込む)
+func Decode(events []Event) ([]gamelog.Kyoku, error) {
+	var kyokus []gamelog.Kyoku
+	var k *gamelog.Kyoku
+	var riichi [4]bool
+
+	finish := func() error {
+		if k == nil {
+			return nil
+		}
+		if err := gamelog.Validate(k); err != nil {
+			return err
+		}
+		kyokus = append(kyokus, *k)
+		k = nil
+		return nil
+	}
+
+	for i, e := range events {
+		if e.Type == TypeStartKyoku {
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			var err error
+			if k, err = decodeStart(e); err != nil {
+				return nil, fmt.Errorf("event %d: %w", i, err)
+			}
+			riichi = [4]bool{}
+			continue
+		}
+		if k == nil {
+			continue // start_game など
+		}
+
+		var err error
+		switch e.Type {
+		case TypeTsumo, TypeDahai:
+			err = decodeDrawOrDiscard(k, e, &riichi)
+		case TypeReach:
+			var seat int
+			if seat, err = actor(e); err == nil {
+				riichi[seat] = true
+			}
+		case TypeChi, TypePon, TypeDaiminkan, TypeAnkan, TypeKakan:
+			err = decodeCall(k, e)
+		case TypeDora:
+			var t int
+			if t, err = ParseTile(e.DoraMarker); err == nil {
+				k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Dora, Tile: t})
+			}
+		case TypeHora, TypeRyukyoku, TypeEndKyoku:
+			err = finish()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("event %d (%s): %w", i, e.Type, err)
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(kyokus) == 0 {
+		return nil, errors.New("mjai log has no start_kyoku")
+	}
+	return kyokus, nil
+}
+
+func actor(e Event) (int, error) {
+	if e.Actor == nil || *e.Actor < 0 || *e.Actor > 3 {
+		return 0, errors.New("actor must be 0-3")
+	}
+	return *e.Actor, nil
+}
+
+func decodeStart(e Event) (*gamelog.Kyoku, error) {
+	k := &gamelog.Kyoku{Number: 1}
+	for i, name := range bakazeNames {
+		if e.Bakaze == name {
+			k.Wind = i
+		}
+	}
+	if e.Kyoku != nil {
+		k.Number = *e.Kyoku
+	}
+	if e.Honba != nil {
+		k.Honba = *e.Honba
+	}
+	if e.Kyotaku != nil {
+		k.RiichiSticks = *e.Kyotaku
+	}
+	if e.Oya != nil {
+		k.Dealer = *e.Oya
+	} else {
+		k.Dealer = (k.Number - 1) % 4
+	}
+	copy(k.Scores[:], e.Scores)
+
+	dora, err := ParseTile(e.DoraMarker)
+	if err != nil || dora == gamelog.Unknown {
+		return nil, errors.New("start_kyoku needs a dora_marker")
+	}
+	k.Dora = dora
+	if len(e.Tehais) != 4 {
+		return nil, errors.New("start_kyoku needs 4 tehais")
+	}
+	for i, tehai := range e.Tehais {
+		if k.Hands[i], err = parseTiles(tehai); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+func decodeDrawOrDiscard(k *gamelog.Kyoku, e Event, riichi *[4]bool) error {
+	seat, err := actor(e)
+	if err != nil {
+		return err
+	}
+	tile, err := ParseTile(e.Pai)
+	if err != nil {
+		return err
+	}
+	if e.Type == TypeTsumo {
+		k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Draw, Seat: seat, Tile: tile})
+		return nil
+	}
+	ev := gamelog.Event{Kind: gamelog.Discard, Seat: seat, Tile: tile, Riichi: riichi[seat]}
+	if e.Tsumogiri != nil {
+		ev.Tsumogiri = *e.Tsumogiri
+	}
+	riichi[seat] = false
+	k.Events = append(k.Events, ev)
+	return nil
+}
+
+func decodeCall(k *gamelog.Kyoku, e Event) error {
+	seat, err := actor(e)
+	if err != nil {
+		return err
+	}
+	consumed, err := parseTiles(e.Consumed)
+	if err != nil {
+		return err
+	}
+	m := mahjong.Meld{Type: e.Type, Called: -1}
+	if e.Type == TypeAnkan {
+		m.From = seat
+	}
+	want := map[string]int{TypeChi: 2, TypePon: 2, TypeDaiminkan: 3, TypeAnkan: 4, TypeKakan: 3}[e.Type]
+	if len(consumed) != want {
+		return fmt.Errorf("%s needs %d consumed tiles", e.Type, want)
+	}
+	m.Tiles = consumed
+	if e.Type != TypeAnkan {
+		if m.Called, err = ParseTile(e.Pai); err != nil {
+			return err
+		}
+		m.Tiles = append(m.Tiles, m.Called)
+	}
+	if e.Type == TypeChi || e.Type == TypePon || e.Type == TypeDaiminkan {
+		if e.Target == nil || *e.Target < 0 || *e.Target > 3 {
+			return errors.New("target must be 0-3")
+		}
+		m.From = *e.Target
+	}
+	sort.Ints(m.Tiles)
+	k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Call, Seat: seat, Meld: m})
+	return nil
+}
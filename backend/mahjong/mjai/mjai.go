@@ -0,0 +1,149 @@
+// Package mjai は MJAI プロトコル (1行1イベントの JSON) と gamelog の変換をまとめたものです。
+//
+// 牌は "1m"-"9m", "1p"-"9p", "1s"-"9s", 字牌は "E" "S" "W" "N" "P"(白) "F"(發) "C"(中)、
+// 赤5は "5mr" のように書きます (読み込むときは通常の5として扱う)。見えない牌は "?"。
+package mjai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"strings"
+)
+
+// イベントの種類
+const (
+	TypeStartGame     = "start_game"
+	TypeStartKyoku    = "start_kyoku"
+	TypeTsumo         = "tsumo"
+	TypeDahai         = "dahai"
+	TypeReach         = "reach"
+	TypeReachAccepted = "reach_accepted"
+	TypeChi           = "chi"
+	TypePon           = "pon"
+	TypeDaiminkan     = "daiminkan"
+	TypeAnkan         = "ankan"
+	TypeKakan         = "kakan"
+	TypeDora          = "dora"
+	TypeHora          = "hora"
+	TypeRyukyoku      = "ryukyoku"
+	TypeEndKyoku      = "end_kyoku"
+	TypeEndGame       = "end_game"
+	TypeNone          = "none"
+)
+
+// MJAI のイベント1つ分 (使う項目だけ)
+// 0 でも省略できない数値はポインタにしている
+type Event struct {
+	Type string `json:"type"`
+
+	ID    *int     `json:"id,omitempty"`    // start_game: ボットの座席
+	Names []string `json:"names,omitempty"` // start_game
+
+	Bakaze     string     `json:"bakaze,omitempty"`
+	DoraMarker string     `json:"dora_marker,omitempty"`
+	Kyoku      *int       `json:"kyoku,omitempty"`
+	Honba      *int       `json:"honba,omitempty"`
+	Kyotaku    *int       `json:"kyotaku,omitempty"`
+	Oya        *int       `json:"oya,omitempty"`
+	Scores     []int      `json:"scores,omitempty"`
+	Tehais     [][]string `json:"tehais,omitempty"`
+
+	Actor     *int     `json:"actor,omitempty"`
+	Target    *int     `json:"target,omitempty"`
+	Pai       string   `json:"pai,omitempty"`
+	Consumed  []string `json:"consumed,omitempty"`
+	Tsumogiri *bool    `json:"tsumogiri,omitempty"`
+}
+
+func intp(n int) *int    { return &n }
+func boolp(b bool) *bool { return &b }
+
+var honorNames = []string{"E", "S", "W", "N", "P", "F", "C"}
+
+// 0-33 の牌を MJAI の表記にする (Unknown は "?")
+func TileString(tile int) string {
+	switch {
+	case tile == gamelog.Unknown:
+		return "?"
+	case mahjong.IsSuited(tile):
+		return fmt.Sprintf("%d%c", tile%9+1, "mps"[tile/9])
+	case mahjong.IsHonor(tile):
+		return honorNames[tile-mahjong.East]
+	}
+	return "?"
+}
+
+// MJAI の表記を 0-33 の牌にする ("?" は Unknown)
+func ParseTile(s string) (int, error) {
+	if s == "?" {
+		return gamelog.Unknown, nil
+	}
+	for i, name := range honorNames {
+		if s == name {
+			return mahjong.East + i, nil
+		}
+	}
+	s = strings.TrimSuffix(s, "r") // 赤5
+	if len(s) == 2 && s[0] >= '1' && s[0] <= '9' {
+		if suit := strings.IndexByte("mps", s[1]); suit >= 0 {
+			return suit*9 + int(s[0]-'1'), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid mjai tile %q", s)
+}
+
+func tileStrings(tiles []int) []string {
+	s := make([]string, len(tiles))
+	for i, t := range tiles {
+		s[i] = TileString(t)
+	}
+	return s
+}
+
+func parseTiles(s []string) ([]int, error) {
+	tiles := make([]int, len(s))
+	for i, name := range s {
+		t, err := ParseTile(name)
+		if err != nil {
+			return nil, err
+		}
+		tiles[i] = t
+	}
+	return tiles, nil
+}
+
+// 1行1イベントの JSON を読む (空行は飛ばす)
+func Read(r io.Reader) ([]Event, error) {
+	var events []Event
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	line := 0
+	for sc.Scan() {
+		line++
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var e Event
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, e)
+	}
+	return events, sc.Err()
+}
+
+// 1行1イベントの JSON で書き出す
+func Write(w io.Writer, events []Event) error {
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package mjai
+
+import (
+	"bytes"
+	"os"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"portfolio-backend/mahjong/tenhou"
+	"reflect"
+	"testing"
+)
+
+func loadTenhouSample(t *testing.T) []gamelog.Kyoku {
+	t.Helper()
+	data, err := os.ReadFile("../tenhou/testdata/sample.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kyokus, err := tenhou.Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kyokus
+}
+
+// JSON 行に書き出して読み直す
+func roundTrip(t *testing.T, events []Event) []gamelog.Kyoku {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, events); err != nil {
+		t.Fatal(err)
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kyokus, err := Decode(read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kyokus
+}
+
+func TestTileString(t *testing.T) {
+	for tile := 0; tile < mahjong.NumTileKinds; tile++ {
+		back, err := ParseTile(TileString(tile))
+		if err != nil || back != tile {
+			t.Errorf("tile %d -> %q -> %d (%v)", tile, TileString(tile), back, err)
+		}
+	}
+	if tile, _ := ParseTile("5pr"); tile != 13 {
+		t.Errorf("ParseTile(5pr) = %d, want 13", tile)
+	}
+	if tile, _ := ParseTile("?"); tile != gamelog.Unknown {
+		t.Errorf("ParseTile(?) = %d, want Unknown", tile)
+	}
+	for _, bad := range []string{"", "0m", "5x", "Z", "10p"} {
+		if _, err := ParseTile(bad); err == nil {
+			t.Errorf("ParseTile(%q) should fail", bad)
+		}
+	}
+}
+
+// 天鳳の牌譜 -> MJAI -> 牌譜 で元に戻る
+func TestGameRoundTrip(t *testing.T) {
+	kyokus := loadTenhouSample(t)
+	back := roundTrip(t, Encode(kyokus))
+	if !reflect.DeepEqual(kyokus, back) {
+		t.Errorf("round trip changed the game\nbefore: %+v\nafter:  %+v", kyokus, back)
+	}
+}
+
+// 牌譜の途中の局面 -> MJAI (問題の書き出し) -> 局面 で元に戻る
+func TestPositionRoundTrip(t *testing.T) {
+	kyokus := loadTenhouSample(t)
+	k := &kyokus[0]
+	for seat := 0; seat < 4; seat++ {
+		for turn := 1; turn <= k.Turns()[seat]; turn++ {
+			s, err := gamelog.Replay(k, seat, turn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := s.Position(seat)
+			got := positionRoundTrip(t, want)
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("seat %d turn %d:\nwant %+v\ngot  %+v", seat, turn, want, got)
+			}
+		}
+	}
+}
+
+func positionRoundTrip(t *testing.T, pos gamelog.Position) gamelog.Position {
+	t.Helper()
+	k, err := pos.Kyoku()
+	if err != nil {
+		t.Fatalf("Kyoku: %v", err)
+	}
+	events := EncodeKyoku(k)
+	// 他家の手牌は見えない
+	if events[0].Tehais[1][0] != "?" {
+		t.Errorf("other hands should be hidden, got %v", events[0].Tehais[1])
+	}
+	kyokus := roundTrip(t, events)
+	s, err := gamelog.Replay(&kyokus[0], 0, len(pos.Rivers[0])+1)
+	if err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+	return s.Position(0)
+}
+
+// 他家の大明槓・加槓、自分のチーを含む手入力の局面
+func TestPositionWithKans(t *testing.T) {
+	rivers, _ := mahjong.ParseRivers(`[
+		[{"tile":27,"called":true},{"tile":33},{"tile":30,"tsumogiri":true}],
+		[{"tile":9},{"tile":1}],
+		[{"tile":31,"called":true},{"tile":29}],
+		[{"tile":14,"called":true},{"tile":28,"riichi":true}]
+	]`)
+	melds, _ := mahjong.ParseMelds(`[
+		[{"type":"chi","tiles":[12,13,14],"called":14,"from":3}],
+		[{"type":"kakan","tiles":[27,27,27,27],"called":27,"from":3}],
+		[],
+		[{"type":"daiminkan","tiles":[31,31,31,31],"called":31,"from":3}]
+	]`)
+	pos := gamelog.Position{
+		Wind: 1, Number: 3, SeatWind: 2,
+		Hand:   []int{0, 1, 2, 4, 5, 6, 18, 19, 20, 24, 24},
+		Dora:   []int{8, 17, 5},
+		Rivers: rivers,
+		Melds:  melds,
+		Scores: [4]int{30000, 20000, 25000, 25000},
+	}
+	got := positionRoundTrip(t, pos)
+	if !reflect.DeepEqual(pos, got) {
+		t.Errorf("\nwant %+v\ngot  %+v", pos, got)
+	}
+
+	// 河と副露が合わない局面は書き出せない
+	broken := pos
+	broken.Melds[0] = []mahjong.Meld{{Type: mahjong.MeldPon, Tiles: []int{9, 9, 9}, Called: 9, From: 2}}
+	if _, err := broken.Kyoku(); err == nil {
+		t.Error("a pon without a called tile in the river should fail")
+	}
+}
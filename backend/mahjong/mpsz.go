@@ -0,0 +1,57 @@
+package mahjong
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// 色の記号 (萬子・筒子・索子・字牌)
+const suitLetters = "mpsz"
+
+// mpsz 表記 (例: "123m456p789s11z") を牌の配列にする
+//
+// 数字のあとの記号でまとめて色を決める。0 は赤5 (5 として扱う)、
+// 字牌は 1-7z が東南西北白發中。空白は無視する。牌の並び順は書いたとおり
+func ParseMPSZ(s string) ([]int, error) {
+	var tiles, pending []int
+	for _, c := range strings.ReplaceAll(strings.TrimSpace(s), " ", "") {
+		switch {
+		case c >= '0' && c <= '9':
+			pending = append(pending, int(c-'0'))
+		case strings.ContainsRune(suitLetters, c):
+			if len(pending) == 0 {
+				return nil, fmt.Errorf("no numbers before %q", c)
+			}
+			suit := strings.IndexRune(suitLetters, c)
+			for _, n := range pending {
+				if n == 0 {
+					n = 5
+				}
+				if suit == 3 && n > 7 {
+					return nil, fmt.Errorf("invalid honor %dz (must be 1-7)", n)
+				}
+				tiles = append(tiles, suit*9+n-1)
+			}
+			pending = pending[:0]
+		default:
+			return nil, fmt.Errorf("invalid character %q in tiles", c)
+		}
+	}
+	if len(pending) > 0 {
+		return nil, errors.New("tiles must end with m, p, s or z")
+	}
+	return tiles, nil
+}
+
+// 牌の配列を mpsz 表記にする (並び順はそのまま、続けて同じ色の牌をまとめる)
+func FormatMPSZ(tiles []int) string {
+	var b strings.Builder
+	for i, t := range tiles {
+		b.WriteByte(byte('1' + t%9))
+		if i == len(tiles)-1 || tiles[i+1]/9 != t/9 {
+			b.WriteByte(suitLetters[t/9])
+		}
+	}
+	return b.String()
+}
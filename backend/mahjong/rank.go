@@ -0,0 +1,108 @@
+package mahjong
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 実力の層 (結果を層ごとに分けて集計するのに使う)
+const (
+	CohortBeginner     = "beginner"     // 初心者
+	CohortIntermediate = "intermediate" // 中級者
+	CohortAdvanced     = "advanced"     // 上級者
+	CohortExpert       = "expert"       // 最上位
+	CohortUnranked     = "unranked"     // 段位の登録なし (匿名化された投票も含む)
+)
+
+// 層の並び順 (弱い順)
+var Cohorts = []string{CohortBeginner, CohortIntermediate, CohortAdvanced, CohortExpert, CohortUnranked}
+
+// 段位を登録できる対戦サイト
+const (
+	PlatformTenhou  = "tenhou"  // 天鳳
+	PlatformMajsoul = "majsoul" // 雀魂
+	PlatformSelf    = "self"    // 自己申告 (層をそのまま選ぶ)
+)
+
+// 段位1つ分
+type rankDef struct {
+	Code   string // API で使う表記
+	Label  string // 表示名
+	Cohort string
+}
+
+// 対戦サイトごとの段位 (低い順)
+var platformRanks = map[string][]rankDef{
+	PlatformTenhou:  tenhouRanks(),
+	PlatformMajsoul: majsoulRanks(),
+	PlatformSelf: {
+		{CohortBeginner, "初心者", CohortBeginner},
+		{CohortIntermediate, "中級者", CohortIntermediate},
+		{CohortAdvanced, "上級者", CohortAdvanced},
+		{CohortExpert, "最上位", CohortExpert},
+	},
+}
+
+// 天鳳: 新人・9級-1級は初心者、初段-三段は中級者、四段-六段は上級者、七段以上は最上位
+func tenhouRanks() []rankDef {
+	dan := []string{"初段", "二段", "三段", "四段", "五段", "六段", "七段", "八段", "九段", "十段"}
+	ranks := []rankDef{{"shinjin", "新人", CohortBeginner}}
+	for k := 9; k >= 1; k-- {
+		ranks = append(ranks, rankDef{strconv.Itoa(k) + "kyu", strconv.Itoa(k) + "級", CohortBeginner})
+	}
+	for i, label := range dan {
+		cohort := CohortIntermediate
+		switch {
+		case i >= 6:
+			cohort = CohortExpert
+		case i >= 3:
+			cohort = CohortAdvanced
+		}
+		ranks = append(ranks, rankDef{strconv.Itoa(i+1) + "dan", label, cohort})
+	}
+	return append(ranks, rankDef{"tenhoui", "天鳳位", CohortExpert})
+}
+
+// 雀魂: 初心・雀士は初心者、雀傑は中級者、雀豪は上級者、雀聖・魂天は最上位
+func majsoulRanks() []rankDef {
+	tiers := []struct {
+		code, label, cohort string
+	}{
+		{"novice", "初心", CohortBeginner},
+		{"adept", "雀士", CohortBeginner},
+		{"expert", "雀傑", CohortIntermediate},
+		{"master", "雀豪", CohortAdvanced},
+		{"saint", "雀聖", CohortExpert},
+	}
+	var ranks []rankDef
+	for _, t := range tiers {
+		for n := 1; n <= 3; n++ {
+			ranks = append(ranks, rankDef{t.code + strconv.Itoa(n), t.label + strconv.Itoa(n), t.cohort})
+		}
+	}
+	return append(ranks, rankDef{"celestial", "魂天", CohortExpert})
+}
+
+// 対戦サイトと段位 (例: "tenhou", "4dan") から層を求める
+func RankCohort(platform, rank string) (string, error) {
+	ranks, ok := platformRanks[platform]
+	if !ok {
+		return "", fmt.Errorf("unknown platform %q (must be tenhou, majsoul or self)", platform)
+	}
+	for _, r := range ranks {
+		if r.Code == rank {
+			return r.Cohort, nil
+		}
+	}
+	return "", fmt.Errorf("unknown %s rank %q", platform, rank)
+}
+
+// 段位の表示名 (例: "4dan" -> "四段")。不明なら空
+func RankLabel(platform, rank string) string {
+	for _, r := range platformRanks[platform] {
+		if r.Code == rank {
+			return r.Label
+		}
+	}
+	return ""
+}
@@ -0,0 +1,35 @@
+package mahjong
+
+import "testing"
+
+func TestRankCohort(t *testing.T) {
+	tests := []struct {
+		platform, rank string
+		want           string
+		wantErr        bool
+	}{
+		{PlatformTenhou, "shinjin", CohortBeginner, false},
+		{PlatformTenhou, "1kyu", CohortBeginner, false},
+		{PlatformTenhou, "1dan", CohortIntermediate, false},
+		{PlatformTenhou, "4dan", CohortAdvanced, false},
+		{PlatformTenhou, "7dan", CohortExpert, false},
+		{PlatformTenhou, "tenhoui", CohortExpert, false},
+		{PlatformMajsoul, "adept3", CohortBeginner, false},
+		{PlatformMajsoul, "expert1", CohortIntermediate, false},
+		{PlatformMajsoul, "master2", CohortAdvanced, false},
+		{PlatformMajsoul, "celestial", CohortExpert, false},
+		{PlatformSelf, "advanced", CohortAdvanced, false},
+		{PlatformTenhou, "11dan", "", true},
+		{PlatformMajsoul, "master4", "", true},
+		{"riichicity", "1dan", "", true},
+	}
+	for _, tt := range tests {
+		got, err := RankCohort(tt.platform, tt.rank)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("RankCohort(%q, %q) = %q, %v; want %q", tt.platform, tt.rank, got, err, tt.want)
+		}
+	}
+	if got := RankLabel(PlatformTenhou, "4dan"); got != "四段" {
+		t.Errorf("RankLabel(tenhou, 4dan) = %q, want 四段", got)
+	}
+}
@@ -0,0 +1,136 @@
+package mahjong
+
+// 向聴数を求める
+// 一般形・七対子・国士無双のうち一番小さいものを返す (-1 で和了、0 で聴牌)
+// melds は副露の数 (副露があると七対子・国士無双は狙えない)
+func Shanten(tiles []int, melds int) int {
+	c := CountTiles(tiles)
+	best := StandardShanten(c, melds)
+	if melds == 0 {
+		if s := ChiitoitsuShanten(c); s < best {
+			best = s
+		}
+		if s := KokushiShanten(c); s < best {
+			best = s
+		}
+	}
+	return best
+}
+
+// 一般形 (4面子1雀頭) の向聴数
+// 向聴数 = 8 - 2×面子数 - 搭子数 - 雀頭 (副露も面子に数え、面子 + 搭子は4つまで)
+func StandardShanten(c Counts, melds int) int {
+	s := shantenSearch{counts: c, best: 8}
+
+	// 雀頭なし
+	s.search(0, melds, 0, 0)
+	// 雀頭を1つ決めてから残りを分解する
+	for i := 0; i < NumTileKinds; i++ {
+		if s.counts[i] >= 2 {
+			s.counts[i] -= 2
+			s.search(0, melds, 0, 1)
+			s.counts[i] += 2
+		}
+	}
+	return s.best
+}
+
+type shantenSearch struct {
+	counts Counts
+	best   int
+}
+
+// i 番目以降の牌を面子・搭子・孤立牌に分解しながら最小の向聴数を探す
+// mentsu には副露の数も含める
+func (s *shantenSearch) search(i, mentsu, taatsu, pair int) {
+	for i < NumTileKinds && s.counts[i] == 0 {
+		i++
+	}
+	if i == NumTileKinds {
+		if mentsu+taatsu > 4 {
+			taatsu = 4 - mentsu
+		}
+		if v := 8 - 2*mentsu - taatsu - pair; v < s.best {
+			s.best = v
+		}
+		return
+	}
+	c := &s.counts
+	seq := IsSuited(i) && i%9 <= 6
+
+	// 面子: 刻子
+	if c[i] >= 3 {
+		c[i] -= 3
+		s.search(i, mentsu+1, taatsu, pair)
+		c[i] += 3
+	}
+	// 面子: 順子
+	if seq && c[i+1] > 0 && c[i+2] > 0 {
+		c[i]--
+		c[i+1]--
+		c[i+2]--
+		s.search(i, mentsu+1, taatsu, pair)
+		c[i]++
+		c[i+1]++
+		c[i+2]++
+	}
+	// 搭子: 対子
+	if c[i] >= 2 {
+		c[i] -= 2
+		s.search(i, mentsu, taatsu+1, pair)
+		c[i] += 2
+	}
+	// 搭子: 両面・辺張
+	if IsSuited(i) && i%9 <= 7 && c[i+1] > 0 {
+		c[i]--
+		c[i+1]--
+		s.search(i, mentsu, taatsu+1, pair)
+		c[i]++
+		c[i+1]++
+	}
+	// 搭子: 嵌張
+	if seq && c[i+2] > 0 {
+		c[i]--
+		c[i+2]--
+		s.search(i, mentsu, taatsu+1, pair)
+		c[i]++
+		c[i+2]++
+	}
+	// 孤立牌として外す
+	c[i]--
+	s.search(i, mentsu, taatsu, pair)
+	c[i]++
+}
+
+// 七対子の向聴数 (同じ牌4枚は2組と数えない)
+func ChiitoitsuShanten(c Counts) int {
+	pairs, kinds := 0, 0
+	for _, n := range c {
+		if n > 0 {
+			kinds++
+		}
+		if n >= 2 {
+			pairs++
+		}
+	}
+	s := 6 - pairs
+	if kinds < 7 {
+		s += 7 - kinds
+	}
+	return s
+}
+
+// 国士無双の向聴数
+func KokushiShanten(c Counts) int {
+	kinds, pair := 0, 0
+	for t, n := range c {
+		if !IsTerminalOrHonor(t) || n == 0 {
+			continue
+		}
+		kinds++
+		if n >= 2 {
+			pair = 1
+		}
+	}
+	return 13 - kinds - pair
+}
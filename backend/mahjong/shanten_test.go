@@ -0,0 +1,76 @@
+package mahjong
+
+import "testing"
+
+func TestShanten(t *testing.T) {
+	tests := []struct {
+		name  string
+		tiles []int
+		melds int
+		want  int
+	}{
+		{
+			name:  "complete hand",
+			tiles: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 13, 13}, // 123456789m123p55p
+			want:  -1,
+		},
+		{
+			name:  "seed problem is tenpai",
+			tiles: []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 32},
+			want:  0,
+		},
+		{
+			name:  "13 tiles ryanmen wait",
+			tiles: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 13, 13}, // 123456789m12p55p
+			want:  0,
+		},
+		{
+			name:  "one away",
+			tiles: []int{0, 1, 2, 3, 4, 5, 9, 10, 13, 13, 20, 22, 30, 33},
+			want:  1,
+		},
+		{
+			name:  "chiitoitsu tenpai",
+			tiles: []int{0, 0, 1, 1, 11, 11, 12, 12, 22, 22, 23, 23, 33},
+			want:  0,
+		},
+		{
+			name:  "kokushi thirteen-sided wait",
+			tiles: []int{0, 8, 9, 17, 18, 26, 27, 28, 29, 30, 31, 32, 33},
+			want:  0,
+		},
+		{
+			name:  "scattered hand",
+			tiles: []int{0, 3, 6, 9, 12, 15, 18, 21, 24, 27, 28, 29, 30},
+			want:  6,
+		},
+		{
+			name:  "three melds, complete",
+			tiles: []int{0, 1, 2, 13, 13},
+			melds: 3,
+			want:  -1,
+		},
+		{
+			name:  "one meld, no chiitoitsu",
+			tiles: []int{0, 0, 1, 1, 11, 11, 12, 12, 22, 22, 33},
+			melds: 1,
+			want:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Shanten(tt.tiles, tt.melds); got != tt.want {
+				t.Errorf("Expected shanten %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestChiitoitsuShantenQuads(t *testing.T) {
+	// 同じ牌4枚は1組としか数えない
+	c := CountTiles([]int{0, 0, 0, 0, 1, 1, 2, 2, 3, 3, 4, 4, 5})
+	if got := ChiitoitsuShanten(c); got != 2 {
+		t.Errorf("Expected 2, got %d", got)
+	}
+}
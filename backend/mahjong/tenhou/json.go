@@ -0,0 +1,332 @@
+package tenhou
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"sort"
+	"strconv"
+)
+
+// 天鳳の JSON 形式の牌譜 (牌譜ビューアの「JSON」から保存できるもの)
+//
+//	log[i] = [[局, 本場, 供託], [点数x4], [ドラ表示牌], [裏ドラ表示牌],
+//	          配牌0, ツモ0, 打牌0, 配牌1, ツモ1, 打牌1, ..., [結果]]
+type jsonLog struct {
+	Log [][]json.RawMessage `json:"log"`
+}
+
+// ツモ切りを表す打牌
+const tsumogiri = 60
+
+// 天鳳の牌番号 (11-19 萬子, 21-29 筒子, 31-39 索子, 41-47 字牌, 51-53 赤5) を 0-33 にする
+func tileFromJSON(n int) (int, error) {
+	switch {
+	case n >= 51 && n <= 53:
+		return (n-51)*9 + 4, nil
+	case n >= 11 && n <= 39 && n%10 != 0:
+		return (n/10-1)*9 + n%10 - 1, nil
+	case n >= 41 && n <= 47:
+		return mahjong.East + n - 41, nil
+	}
+	return 0, fmt.Errorf("invalid tenhou tile %d", n)
+}
+
+// JSON 形式の牌譜を読み込む
+func ParseJSON(data []byte) ([]gamelog.Kyoku, error) {
+	var l jsonLog
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("invalid tenhou json: %w", err)
+	}
+	if len(l.Log) == 0 {
+		return nil, errors.New("tenhou json has no rounds")
+	}
+
+	kyokus := make([]gamelog.Kyoku, 0, len(l.Log))
+	for i, raw := range l.Log {
+		k, err := parseJSONKyoku(raw)
+		if err != nil {
+			return nil, fmt.Errorf("round %d: %w", i, err)
+		}
+		if err := gamelog.Validate(k); err != nil {
+			return nil, err
+		}
+		kyokus = append(kyokus, *k)
+	}
+	return kyokus, nil
+}
+
+func parseJSONKyoku(raw []json.RawMessage) (*gamelog.Kyoku, error) {
+	if len(raw) < 16 {
+		return nil, errors.New("round must have at least 16 entries")
+	}
+	var header []int
+	var scores []int
+	var dora []int
+	// header は [局 (0=東1局), 本場, 供託]。局が負だと親の座席が決まらない
+	if err := json.Unmarshal(raw[0], &header); err != nil || len(header) < 3 ||
+		header[0] < 0 || header[1] < 0 || header[2] < 0 {
+		return nil, errors.New("invalid round header")
+	}
+	if err := json.Unmarshal(raw[1], &scores); err != nil || len(scores) != 4 {
+		return nil, errors.New("invalid scores")
+	}
+	if err := json.Unmarshal(raw[2], &dora); err != nil || len(dora) == 0 {
+		return nil, errors.New("invalid dora indicators")
+	}
+
+	k := &gamelog.Kyoku{
+		Wind:         header[0] / 4,
+		Number:       header[0]%4 + 1,
+		Honba:        header[1],
+		RiichiSticks: header[2],
+		Dealer:       header[0] % 4,
+	}
+	copy(k.Scores[:], scores)
+	indicators := make([]int, len(dora))
+	for i, d := range dora {
+		t, err := tileFromJSON(d)
+		if err != nil {
+			return nil, err
+		}
+		indicators[i] = t
+	}
+	k.Dora = indicators[0]
+
+	var draws, discards [4][]interface{}
+	for seat := 0; seat < 4; seat++ {
+		var haipai []int
+		if err := json.Unmarshal(raw[4+seat*3], &haipai); err != nil {
+			return nil, fmt.Errorf("invalid hand of seat %d", seat)
+		}
+		for _, n := range haipai {
+			t, err := tileFromJSON(n)
+			if err != nil {
+				return nil, err
+			}
+			k.Hands[seat] = append(k.Hands[seat], t)
+		}
+		if err := json.Unmarshal(raw[5+seat*3], &draws[seat]); err != nil {
+			return nil, fmt.Errorf("invalid draws of seat %d", seat)
+		}
+		if err := json.Unmarshal(raw[6+seat*3], &discards[seat]); err != nil {
+			return nil, fmt.Errorf("invalid discards of seat %d", seat)
+		}
+	}
+
+	r := jsonReplay{k: k, draws: draws, discards: discards, indicators: indicators}
+	if err := r.run(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// JSON 形式はツモと打牌が座席ごとに分かれているので、順番を組み立て直す
+type jsonReplay struct {
+	k          *gamelog.Kyoku
+	draws      [4][]interface{}
+	discards   [4][]interface{}
+	di, ti     [4]int // 次に読むツモ・打牌の位置
+	lastDraw   [4]int
+	indicators []int
+	kans       int
+}
+
+func (r *jsonReplay) emit(e gamelog.Event) { r.k.Events = append(r.k.Events, e) }
+
+// 槓のあとに槓ドラをめくる
+func (r *jsonReplay) kan() {
+	r.kans++
+	if r.kans < len(r.indicators) {
+		r.emit(gamelog.Event{Kind: gamelog.Dora, Tile: r.indicators[r.kans]})
+	}
+}
+
+func (r *jsonReplay) run() error {
+	cur := r.k.Dealer
+	from := -1 // 直前に打牌した人 (鳴きの相手)
+	for r.di[cur] < len(r.draws[cur]) {
+		entry := r.draws[cur][r.di[cur]]
+		r.di[cur]++
+
+		switch v := entry.(type) {
+		case float64:
+			t, err := tileFromJSON(int(v))
+			if err != nil {
+				return err
+			}
+			r.lastDraw[cur] = t
+			r.emit(gamelog.Event{Kind: gamelog.Draw, Seat: cur, Tile: t})
+		case string:
+			meld, err := parseCall(v)
+			if err != nil {
+				return err
+			}
+			if from < 0 {
+				return fmt.Errorf("seat %d calls %q without a discard", cur, v)
+			}
+			meld.From = from
+			r.emit(gamelog.Event{Kind: gamelog.Call, Seat: cur, Meld: meld})
+			if meld.Type == mahjong.MeldDaiminkan {
+				// 大明槓のあとは打牌欄に 0 が入り、嶺上牌をツモる
+				r.ti[cur]++
+				r.kan()
+				continue
+			}
+		default:
+			return fmt.Errorf("invalid draw %v", entry)
+		}
+
+		// 打牌 (暗槓・加槓なら嶺上牌をツモってもう一度)
+		if r.ti[cur] >= len(r.discards[cur]) {
+			break // ツモ和了
+		}
+		entry = r.discards[cur][r.ti[cur]]
+		r.ti[cur]++
+		discard, kan, err := r.parseDiscard(cur, entry)
+		if err != nil {
+			return err
+		}
+		if kan != nil {
+			r.emit(gamelog.Event{Kind: gamelog.Call, Seat: cur, Meld: *kan})
+			r.kan()
+			continue
+		}
+		r.emit(discard)
+		from = cur
+		cur = r.nextPlayer(cur, discard.Tile)
+	}
+	return nil
+}
+
+// 打牌欄の1件を読む
+func (r *jsonReplay) parseDiscard(seat int, entry interface{}) (gamelog.Event, *mahjong.Meld, error) {
+	e := gamelog.Event{Kind: gamelog.Discard, Seat: seat}
+	n := 0
+	switch v := entry.(type) {
+	case float64:
+		n = int(v)
+	case string:
+		if len(v) > 0 && v[0] == 'r' {
+			// リーチ宣言 ("r45", ツモ切りリーチは "r60")
+			e.Riichi = true
+			var err error
+			if n, err = strconv.Atoi(v[1:]); err != nil {
+				return e, nil, fmt.Errorf("invalid riichi discard %q", v)
+			}
+			break
+		}
+		meld, err := parseCall(v)
+		if err != nil {
+			return e, nil, err
+		}
+		if meld.Type != mahjong.MeldAnkan && meld.Type != mahjong.MeldKakan {
+			return e, nil, fmt.Errorf("invalid discard %q", v)
+		}
+		if meld.Type == mahjong.MeldAnkan {
+			meld.From = seat
+		}
+		return e, &meld, nil
+	default:
+		return e, nil, fmt.Errorf("invalid discard %v", entry)
+	}
+
+	if n == tsumogiri {
+		e.Tile, e.Tsumogiri = r.lastDraw[seat], true
+		return e, nil, nil
+	}
+	t, err := tileFromJSON(n)
+	if err != nil {
+		return e, nil, err
+	}
+	e.Tile = t
+	return e, nil, nil
+}
+
+// 打牌のあと誰の番になるか (ポン・大明槓を優先、次にチー、なければ下家)
+func (r *jsonReplay) nextPlayer(cur, tile int) int {
+	for _, types := range []string{"pm", "c"} {
+		for i := 1; i < 4; i++ {
+			seat := (cur + i) % 4
+			if types == "c" && i != 1 {
+				continue // チーは下家だけ
+			}
+			if r.di[seat] >= len(r.draws[seat]) {
+				continue
+			}
+			s, ok := r.draws[seat][r.di[seat]].(string)
+			if !ok {
+				continue
+			}
+			meld, err := parseCall(s)
+			if err != nil || meld.Called != tile {
+				continue
+			}
+			for _, c := range types {
+				if callLetters[byte(c)] == meld.Type {
+					return seat
+				}
+			}
+		}
+	}
+	return (cur + 1) % 4
+}
+
+var callLetters = map[byte]string{
+	'c': mahjong.MeldChi,
+	'p': mahjong.MeldPon,
+	'm': mahjong.MeldDaiminkan,
+	'a': mahjong.MeldAnkan,
+	'k': mahjong.MeldKakan,
+}
+
+// 鳴きの文字列 ("c275226", "45p4545", "m39393939", "424242a42", "37k373737" など) を読む
+// 記号の直後の牌が鳴いた牌 (加槓は加えた牌)
+func parseCall(s string) (mahjong.Meld, error) {
+	var m mahjong.Meld
+	m.Called = -1
+	next := false
+	for i := 0; i < len(s); {
+		if t, ok := callLetters[s[i]]; ok {
+			if m.Type != "" {
+				return m, fmt.Errorf("invalid call %q", s)
+			}
+			m.Type = t
+			next = true
+			i++
+			continue
+		}
+		if i+2 > len(s) {
+			return m, fmt.Errorf("invalid call %q", s)
+		}
+		n, err := strconv.Atoi(s[i : i+2])
+		if err != nil {
+			return m, fmt.Errorf("invalid call %q", s)
+		}
+		t, err := tileFromJSON(n)
+		if err != nil {
+			return m, err
+		}
+		if next {
+			m.Called = t
+			next = false
+		}
+		m.Tiles = append(m.Tiles, t)
+		i += 2
+	}
+
+	want := 3
+	if m.Type == mahjong.MeldDaiminkan || m.Type == mahjong.MeldAnkan || m.Type == mahjong.MeldKakan {
+		want = 4
+	}
+	if m.Type == "" || len(m.Tiles) != want {
+		return m, fmt.Errorf("invalid call %q", s)
+	}
+	if m.Type == mahjong.MeldAnkan {
+		m.Called = -1
+	}
+	sort.Ints(m.Tiles)
+	return m, nil
+}
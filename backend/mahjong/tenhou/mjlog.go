@@ -0,0 +1,226 @@
+package tenhou
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"strconv"
+	"strings"
+)
+
+// mjlog (天鳳の XML 形式の牌譜) を読み込む
+//
+//	<INIT seed="局,本場,供託,サイコロ,サイコロ,ドラ表示牌" ten="点数x4 (百点単位)" oya="親" hai0="配牌" .../>
+//	<T12/> ツモ (T/U/V/W が座席 0-3)、<D12/> 打牌 (D/E/F/G)
+//	<N who="座席" m="副露"/>、<DORA hai="表示牌"/>、<REACH who="座席" step="1"/>
+//
+// 牌は 0-135 の番号 (4で割ると 0-33 の種類になる)
+func ParseMjlog(data []byte) ([]gamelog.Kyoku, error) {
+	dec := xml.NewDecoder(strings.NewReader(string(data)))
+
+	var kyokus []gamelog.Kyoku
+	var k *gamelog.Kyoku
+	var lastDraw [4]int // 最後にツモった牌 (136種の番号、ツモ切りの判定用)
+	var riichi [4]bool  // リーチ宣言中 (次の打牌が宣言牌)
+
+	finish := func() error {
+		if k == nil {
+			return nil
+		}
+		if err := gamelog.Validate(k); err != nil {
+			return err
+		}
+		kyokus = append(kyokus, *k)
+		k = nil
+		return nil
+	}
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid mjlog: %w", err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, a := range el.Attr {
+			attrs[a.Name.Local] = a.Value
+		}
+
+		name := el.Name.Local
+		switch {
+		case name == "INIT":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			if k, err = parseInit(attrs); err != nil {
+				return nil, err
+			}
+			lastDraw, riichi = [4]int{-1, -1, -1, -1}, [4]bool{}
+		case k == nil:
+			// 対局開始前のタグ (SHUFFLE, GO, UN, TAIKYOKU など)
+		case name == "N":
+			seat, err := strconv.Atoi(attrs["who"])
+			if err != nil || seat < 0 || seat > 3 {
+				return nil, errors.New("invalid N who")
+			}
+			code, err := strconv.Atoi(attrs["m"])
+			if err != nil {
+				return nil, errors.New("invalid N m")
+			}
+			meld, err := decodeMeld(code, seat)
+			if err != nil {
+				return nil, err
+			}
+			k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Call, Seat: seat, Meld: meld})
+		case name == "DORA":
+			hai, err := strconv.Atoi(attrs["hai"])
+			if err != nil || hai < 0 || hai >= 136 {
+				return nil, errors.New("invalid DORA hai")
+			}
+			k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Dora, Tile: hai / 4})
+		case name == "REACH":
+			seat, err := strconv.Atoi(attrs["who"])
+			if err == nil && seat >= 0 && seat < 4 && attrs["step"] == "1" {
+				riichi[seat] = true
+			}
+		case name == "AGARI" || name == "RYUUKYOKU":
+			if err := finish(); err != nil {
+				return nil, err
+			}
+		default:
+			// ツモ・打牌 (<T12/>, <d34/> など)
+			seat, draw, ok := drawOrDiscard(name)
+			if !ok {
+				continue
+			}
+			hai, err := strconv.Atoi(name[1:])
+			if err != nil || hai < 0 || hai >= 136 {
+				return nil, fmt.Errorf("invalid tag %s", name)
+			}
+			if draw {
+				lastDraw[seat] = hai
+				k.Events = append(k.Events, gamelog.Event{Kind: gamelog.Draw, Seat: seat, Tile: hai / 4})
+			} else {
+				k.Events = append(k.Events, gamelog.Event{
+					Kind: gamelog.Discard, Seat: seat, Tile: hai / 4,
+					Tsumogiri: hai == lastDraw[seat],
+					Riichi:    riichi[seat],
+				})
+				riichi[seat] = false
+				lastDraw[seat] = -1
+			}
+		}
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if len(kyokus) == 0 {
+		return nil, errors.New("mjlog has no rounds")
+	}
+	return kyokus, nil
+}
+
+// タグ名からツモ・打牌と座席を判断する (T-W はツモ、D-G は打牌。小文字も同じ扱い)
+func drawOrDiscard(name string) (seat int, draw bool, ok bool) {
+	if len(name) < 2 || name[1] < '0' || name[1] > '9' {
+		return 0, false, false
+	}
+	c := strings.ToUpper(name[:1])[0]
+	switch {
+	case c >= 'T' && c <= 'W':
+		return int(c - 'T'), true, true
+	case c >= 'D' && c <= 'G':
+		return int(c - 'D'), false, true
+	}
+	return 0, false, false
+}
+
+func parseInit(attrs map[string]string) (*gamelog.Kyoku, error) {
+	seed, err := splitInts(attrs["seed"])
+	if err != nil || len(seed) < 6 || seed[0] < 0 || seed[1] < 0 || seed[2] < 0 {
+		return nil, errors.New("invalid INIT seed")
+	}
+	ten, err := splitInts(attrs["ten"])
+	if err != nil || len(ten) < 4 {
+		return nil, errors.New("invalid INIT ten")
+	}
+	oya, err := strconv.Atoi(attrs["oya"])
+	if err != nil || oya < 0 || oya > 3 {
+		return nil, errors.New("invalid INIT oya")
+	}
+
+	k := &gamelog.Kyoku{
+		Wind:         seed[0] / 4,
+		Number:       seed[0]%4 + 1,
+		Honba:        seed[1],
+		RiichiSticks: seed[2],
+		Dealer:       oya,
+		Dora:         seed[5] / 4,
+	}
+	for i := 0; i < 4; i++ {
+		k.Scores[i] = ten[i] * 100
+		hai, err := splitInts(attrs["hai"+strconv.Itoa(i)])
+		if err != nil {
+			return nil, fmt.Errorf("invalid INIT hai%d", i)
+		}
+		for _, h := range hai {
+			k.Hands[i] = append(k.Hands[i], h/4)
+		}
+	}
+	return k, nil
+}
+
+func splitInts(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	nums := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, err
+		}
+		nums[i] = n
+	}
+	return nums, nil
+}
+
+// N タグの m (副露をビットで詰めたもの) を読む
+func decodeMeld(m, seat int) (mahjong.Meld, error) {
+	from := (seat + m&3) % 4 // 下位2ビットが鳴いた相手 (自分からの相対位置)
+	switch {
+	case m&0x4 != 0:
+		// チー: 順子の先頭と鳴いた牌の位置
+		t := (m & 0xFC00) >> 10
+		r := t % 3
+		t /= 3
+		base := t/7*9 + t%7
+		return mahjong.Meld{Type: mahjong.MeldChi, Tiles: []int{base, base + 1, base + 2}, Called: base + r, From: from}, nil
+	case m&0x18 != 0:
+		// ポン (0x8) と加槓 (0x10)
+		t := (m & 0xFE00) >> 9 / 3
+		if m&0x8 != 0 {
+			return mahjong.Meld{Type: mahjong.MeldPon, Tiles: []int{t, t, t}, Called: t, From: from}, nil
+		}
+		return mahjong.Meld{Type: mahjong.MeldKakan, Tiles: []int{t, t, t, t}, Called: t}, nil
+	case m&0x20 != 0:
+		return mahjong.Meld{}, errors.New("three-player games (nukidora) are not supported")
+	default:
+		// 大明槓・暗槓
+		t := (m & 0xFF00) >> 8 / 4
+		if m&3 == 0 {
+			return mahjong.Meld{Type: mahjong.MeldAnkan, Tiles: []int{t, t, t, t}, Called: -1, From: seat}, nil
+		}
+		return mahjong.Meld{Type: mahjong.MeldDaiminkan, Tiles: []int{t, t, t, t}, Called: t, From: from}, nil
+	}
+}
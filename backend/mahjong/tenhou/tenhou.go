@@ -0,0 +1,48 @@
+// Package tenhou は天鳳の牌譜 (JSON 形式と mjlog の XML 形式) を読み込みます。
+//
+// 読み込んだ牌譜は gamelog.Kyoku の形にするので、再生や局面の取り出しは gamelog で行います。
+// 赤ドラは通常の5として扱います (問題の牌は 0-33 で赤を区別しないため)。
+package tenhou
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"portfolio-backend/mahjong/gamelog"
+)
+
+// 展開後の牌譜の最大サイズ (取り込みAPIが受け付けるサイズと同じ)
+// 小さな gzip が巨大に展開されてメモリを使い切らないようにする
+const MaxLogSize = 10 << 20
+
+// 牌譜の形式を中身から判断して読み込む
+// mjlog は gzip で圧縮されたままでもよい (展開後が MaxLogSize を超えたらエラー)
+func Parse(data []byte) ([]gamelog.Kyoku, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(io.LimitReader(zr, MaxLogSize+1)); err != nil {
+			return nil, err
+		}
+		if len(data) > MaxLogSize {
+			return nil, fmt.Errorf("game log is larger than %d bytes when decompressed", MaxLogSize)
+		}
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	switch {
+	case len(trimmed) == 0:
+		return nil, errors.New("empty game log")
+	case trimmed[0] == '{':
+		return ParseJSON(trimmed)
+	case trimmed[0] == '<':
+		return ParseMjlog(trimmed)
+	default:
+		return nil, errors.New("unknown game log format (expected Tenhou JSON or mjlog XML)")
+	}
+}
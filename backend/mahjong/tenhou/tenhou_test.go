@@ -0,0 +1,129 @@
+package tenhou
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"reflect"
+	"testing"
+)
+
+// testdata の2つのファイルは同じ対局 (暗槓・ポン・チー・リーチを含む) を別の形式で書いたもの
+func loadSample(t *testing.T, name string) []gamelog.Kyoku {
+	t.Helper()
+	data, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kyokus, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", name, err)
+	}
+	return kyokus
+}
+
+func TestParseJSON(t *testing.T) {
+	kyokus := loadSample(t, "sample.json")
+	if len(kyokus) != 1 {
+		t.Fatalf("got %d rounds, want 1", len(kyokus))
+	}
+	k := &kyokus[0]
+	if k.Name() != "東1" || k.Dealer != 0 || k.Scores != [4]int{25000, 25000, 25000, 25000} {
+		t.Errorf("header = %s dealer %d scores %v", k.Name(), k.Dealer, k.Scores)
+	}
+	if got := k.Turns(); got != [4]int{2, 2, 2, 2} {
+		t.Errorf("Turns = %v, want [2 2 2 2]", got)
+	}
+
+	// 親のリーチ宣言の直前
+	s, err := gamelog.Replay(k, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7, 8, 8, 10, 22}; !reflect.DeepEqual(s.Players[0].Hand, want) {
+		t.Errorf("hand = %v, want %v", s.Players[0].Hand, want)
+	}
+	if want := []int{4, mahjong.Red}; !reflect.DeepEqual(s.DoraIndicators, want) {
+		t.Errorf("dora = %v, want %v", s.DoraIndicators, want)
+	}
+
+	rivers, melds, _ := s.View(0)
+	if len(melds[0]) != 1 || melds[0][0].Type != mahjong.MeldAnkan {
+		t.Errorf("own melds = %+v, want one ankan", melds[0])
+	}
+	// 対面 (座席2) は上家 (座席1) の東をポン
+	if len(melds[2]) != 1 || melds[2][0].Type != mahjong.MeldPon || melds[2][0].Called != mahjong.East || melds[2][0].From != 3 {
+		t.Errorf("toimen melds = %+v, want pon of East from kamicha", melds[2])
+	}
+	// 上家 (座席3) は 6索をチー
+	if len(melds[3]) != 1 || melds[3][0].Type != mahjong.MeldChi || !reflect.DeepEqual(melds[3][0].Tiles, []int{21, 22, 23}) {
+		t.Errorf("kamicha melds = %+v, want chi 456s", melds[3])
+	}
+	if len(rivers[1]) != 1 || !rivers[1][0].Called {
+		t.Errorf("shimocha river = %+v, want called East", rivers[1])
+	}
+
+	// 下家のツモ切り
+	s, err = gamelog.Replay(k, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := s.Players[0].River; len(r) != 2 || !r[1].Riichi || !r[1].Tsumogiri {
+		t.Errorf("dealer river = %+v, want tsumogiri riichi", r)
+	}
+
+	if _, err := gamelog.Replay(k, 0, 3); err == nil {
+		t.Error("Replay past the last discard should fail")
+	}
+}
+
+func TestParseMjlogMatchesJSON(t *testing.T) {
+	fromJSON := loadSample(t, "sample.json")
+	fromXML := loadSample(t, "sample.mjlog")
+	if !reflect.DeepEqual(fromJSON, fromXML) {
+		t.Errorf("mjlog and json differ\njson: %+v\nxml:  %+v", fromJSON, fromXML)
+	}
+}
+
+func TestParseGzip(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.mjlog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	if _, err := Parse(buf.Bytes()); err != nil {
+		t.Errorf("Parse(gzip) = %v", err)
+	}
+}
+
+func TestParseGzipTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(bytes.Repeat([]byte(" "), MaxLogSize+1))
+	zw.Close()
+	if _, err := Parse(buf.Bytes()); err == nil {
+		t.Error("Parse should reject a game log that is too large when decompressed")
+	}
+}
+
+func TestParseBroken(t *testing.T) {
+	tests := map[string]string{
+		"empty":          "",
+		"unknown":        "hello",
+		"no rounds":      `{"log": []}`,
+		"bad discard":    `{"log": [[[0,0,0],[25000,25000,25000,25000],[15],[],[11,11,11,12,13,14,15,16,17,18,19,21,22],[23],[31],[],[],[],[],[],[],[],[],[],[]]]}`,
+		"unclosed xml":   `<mjloggm><INIT seed="0,0,0,1,2,18"`,
+		"negative round": `{"log": [[[-1,0,0],[25000,25000,25000,25000],[15],[],[11,11,11,12,13,14,15,16,17,18,19,21,22],[23],[31],[],[],[],[],[],[],[],[],[],[]]]}`,
+		"negative honba": `{"log": [[[0,-1,0],[25000,25000,25000,25000],[15],[],[11,11,11,12,13,14,15,16,17,18,19,21,22],[23],[31],[],[],[],[],[],[],[],[],[],[]]]}`,
+	}
+	for name, data := range tests {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Package mahjong は牌・手牌の解析 (牌の読み込み、向聴数など) をまとめたものです。
+//
+// 牌はフロントエンドと同じ 0-33 の番号で表します。
+//
+//	0-8: 萬子 1-9, 9-17: 筒子 1-9, 18-26: 索子 1-9,
+//	27-30: 東南西北, 31-33: 白發中
+package mahjong
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// 牌の種類数と、1種類あたりの枚数
+const (
+	NumTileKinds  = 34
+	CopiesPerTile = 4
+)
+
+// 字牌の番号
+const (
+	East  = 27
+	South = 28
+	West  = 29
+	North = 30
+	White = 31 // 白
+	Green = 32 // 發
+	Red   = 33 // 中
+)
+
+// 牌の種類ごとの枚数
+type Counts [NumTileKinds]int
+
+// 数牌か (萬子・筒子・索子)
+func IsSuited(tile int) bool { return tile >= 0 && tile < 27 }
+
+// 字牌か
+func IsHonor(tile int) bool { return tile >= 27 && tile < NumTileKinds }
+
+// 么九牌か (1・9・字牌)
+func IsTerminalOrHonor(tile int) bool {
+	return IsHonor(tile) || (IsSuited(tile) && (tile%9 == 0 || tile%9 == 8))
+}
+
+// 数牌の色 (0: 萬子, 1: 筒子, 2: 索子)。字牌は -1
+func Suit(tile int) int {
+	if !IsSuited(tile) {
+		return -1
+	}
+	return tile / 9
+}
+
+// ドラ表示牌から実際のドラを求める (9の次は1、北の次は東、中の次は白)
+func DoraFromIndicator(indicator int) int {
+	switch {
+	case IsSuited(indicator):
+		return indicator/9*9 + (indicator%9+1)%9
+	case indicator <= North:
+		return East + (indicator-East+1)%4
+	default:
+		return White + (indicator-White+1)%3
+	}
+}
+
+// 牌の配列を種類ごとの枚数にする
+func CountTiles(tiles []int) Counts {
+	var c Counts
+	for _, t := range tiles {
+		if t >= 0 && t < NumTileKinds {
+			c[t]++
+		}
+	}
+	return c
+}
+
+// DBに保存している JSON 配列の文字列 (例: "[0,1,2]") を牌の配列にする
+func ParseTiles(s string) ([]int, error) {
+	var tiles []int
+	if err := json.Unmarshal([]byte(s), &tiles); err != nil {
+		return nil, fmt.Errorf("tiles must be a JSON array of tile IDs: %w", err)
+	}
+	for _, t := range tiles {
+		if t < 0 || t >= NumTileKinds {
+			return nil, fmt.Errorf("invalid tile ID %d (must be 0-33)", t)
+		}
+	}
+	return tiles, nil
+}
+
+// 牌の配列を JSON 配列の文字列にする
+func FormatTiles(tiles []int) string {
+	if tiles == nil {
+		tiles = []int{}
+	}
+	b, _ := json.Marshal(tiles)
+	return string(b)
+}
+
+// 同じ牌が5枚以上使われていないか (手牌とドラ表示牌などをまとめてチェックする)
+func CheckCopies(groups ...[]int) error {
+	var c Counts
+	for _, tiles := range groups {
+		for _, t := range tiles {
+			c[t]++
+			if c[t] > CopiesPerTile {
+				return fmt.Errorf("tile %d is used more than %d times", t, CopiesPerTile)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package mahjong
+
+import "testing"
+
+func TestParseTiles(t *testing.T) {
+	tiles, err := ParseTiles("[0,1,2,33]")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tiles) != 4 || tiles[3] != 33 {
+		t.Errorf("Expected [0 1 2 33], got %v", tiles)
+	}
+
+	for _, input := range []string{"[34]", "[-1]", "0,1,2", ""} {
+		if _, err := ParseTiles(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestCheckCopies(t *testing.T) {
+	if err := CheckCopies([]int{0, 0, 0}, []int{0}); err != nil {
+		t.Errorf("Expected 4 copies to be allowed, got %v", err)
+	}
+	if err := CheckCopies([]int{0, 0, 0}, []int{0, 0}); err == nil {
+		t.Error("Expected error for 5 copies across hand and dora")
+	}
+}
+
+func TestDoraFromIndicator(t *testing.T) {
+	tests := []struct {
+		indicator, want int
+	}{
+		{0, 1},         // 1m -> 2m
+		{8, 0},         // 9m -> 1m
+		{17, 9},        // 9p -> 1p
+		{26, 18},       // 9s -> 1s
+		{East, South},  // 東 -> 南
+		{North, East},  // 北 -> 東
+		{White, Green}, // 白 -> 發
+		{Red, White},   // 中 -> 白
+	}
+	for _, tt := range tests {
+		if got := DoraFromIndicator(tt.indicator); got != tt.want {
+			t.Errorf("DoraFromIndicator(%d): expected %d, got %d", tt.indicator, tt.want, got)
+		}
+	}
+}
+
+func TestParseMPSZ(t *testing.T) {
+	tiles, err := ParseMPSZ("123m 406p 789s 1157z")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []int{0, 1, 2, 12, 13, 14, 24, 25, 26, East, East, White, Red}
+	if FormatTiles(tiles) != FormatTiles(want) {
+		t.Errorf("Expected %v, got %v", want, tiles)
+	}
+
+	for _, input := range []string{"123", "m", "8z", "12x"} {
+		if _, err := ParseMPSZ(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestFormatMPSZ(t *testing.T) {
+	// 並び順は保つ (最後のツモ牌が別の色でもまとめない)
+	tiles := []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27, 31, 31, 3}
+	if got := FormatMPSZ(tiles); got != "123m123p123s1155z4m" {
+		t.Errorf("FormatMPSZ = %q", got)
+	}
+	back, err := ParseMPSZ(FormatMPSZ(tiles))
+	if err != nil || FormatTiles(back) != FormatTiles(tiles) {
+		t.Errorf("round trip = %v, %v", back, err)
+	}
+}
@@ -6,12 +6,20 @@ import (
 	"net/http"
 	"portfolio-backend/controllers"
 	"portfolio-backend/database"
+	"portfolio-backend/rating"
+	"portfolio-backend/scheduler"
 	"strings"
 )
 
 func main() {
 	database.Connect()
 
+	// 「今日の一問」を毎日決まった時刻に公開する
+	scheduler.StartDaily()
+
+	// 投票が締め切られた問題を投票者のレーティングに反映する
+	rating.Start()
+
 	// ---------------------------
 	// ルーティング設定
 	// ---------------------------
@@ -37,7 +45,7 @@ func main() {
 
 	// ユーザー管理
 	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
+		if r.Method == http.MethodGet || r.Method == http.MethodOptions {
 			controllers.GetAllUsers(w, r)
 		} else {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -53,15 +61,76 @@ func main() {
 		}
 	})
 
-	http.HandleFunc("/results", controllers.GetProblemResult) 
+	// ランキング
+	http.HandleFunc("/leaderboards", controllers.GetLeaderboard)
+	http.HandleFunc("/leaderboards/me", controllers.GetMyLeaderboardRank) // ログイン中のユーザーの順位
+
+	// タグと問題集
+	http.HandleFunc("/tags", controllers.GetTags)
+	http.HandleFunc("/collections", controllers.Collections)
+	http.HandleFunc("/collections/", controllers.CollectionDetail)
+
+	// 議論のコメントの編集・削除・いいね・非表示: /comments/{id}
+	http.HandleFunc("/comments/", controllers.CommentDetail)
+
+	// 管理者向け: 監査ログの検索
+	http.HandleFunc("/admin/audit-logs", controllers.GetAuditLogs)
+
+	// 管理者向け: 問題の一括取り込み・書き出し (JSON / CSV)
+	http.HandleFunc("/admin/problems/import", controllers.ImportProblems)
+	http.HandleFunc("/admin/problems/export", controllers.ExportProblems)
+
+	// 管理者向け: 天鳳の牌譜から問題を作る
+	http.HandleFunc("/admin/problems/import/tenhou", controllers.ImportTenhou)
+	// 管理者向け: MJAI の牌譜から問題を作る
+	http.HandleFunc("/admin/problems/import/mjai", controllers.ImportMJAI)
+
+	// 管理者向け: 全問題の自動タグを付け直す
+	http.HandleFunc("/admin/problems/retag", controllers.RetagProblems)
+
+	// 管理者向け: 投票者のレーティングを全部計算し直す
+	http.HandleFunc("/admin/ratings/recompute", controllers.RecomputeRatings)
+
+	// 管理者向け: 今日の一問の公開待ちキュー
+	http.HandleFunc("/admin/daily-queue", controllers.DailyQueue)
+	http.HandleFunc("/admin/daily-queue/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin/daily-queue/publish" {
+			// 今すぐ公開 (?date=2026-01-01)
+			controllers.PublishDailyNow(w, r)
+		} else {
+			// /admin/daily-queue/3/approve, DELETE /admin/daily-queue/3
+			controllers.UpdateDailyQueueEntry(w, r)
+		}
+	})
 
 	fmt.Println("Backend server is running...")
 
 	// ユーザー詳細: /users/ (前方一致でIDを受け取る)
 	// 例: DELETE /users/123 (ユーザー削除)
 	http.HandleFunc("/users/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
+		if r.Method == http.MethodOptions {
+			// CORS プリフライト (Authorization ヘッダー付きのリクエスト用)
+			controllers.SetupResponse(&w)
+		} else if r.Method == http.MethodDelete {
 			controllers.DeleteUser(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restore") {
+			// /users/123/restore -> 論理削除したユーザーを復元
+			controllers.RestoreUser(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status") {
+			// /users/123/status -> 停止・BANの設定
+			controllers.SetUserStatus(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/role") {
+			// /users/123/role -> 権限の変更
+			controllers.SetUserRole(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/rank") {
+			// /users/123/rank -> 段位の登録
+			controllers.SetUserRank(w, r)
+		} else if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/rating") {
+			// /users/123/rating -> レーティングと変動履歴
+			controllers.GetUserRating(w, r)
+		} else if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/analytics") {
+			// /users/123/analytics -> 投票の推移・タグごとの成績・連続日数など
+			controllers.GetUserAnalytics(w, r)
 		} else if strings.Contains(r.URL.Path, "/votes") {
 			// /users/123/votes -> 投票履歴取得
 			controllers.GetUserVotes(w, r)
@@ -73,8 +142,59 @@ func main() {
 	// 詳細取得: /problems/ (前方一致でIDを受け取る)
 	// 例: /problems/1
 	http.HandleFunc("/problems/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodDelete {
+		if r.URL.Path == "/problems/today" {
+			// 今日の一問
+			controllers.GetTodayProblem(w, r)
+		} else if strings.HasPrefix(r.URL.Path, "/problems/archive") {
+			// 過去の一問: /problems/archive, /problems/archive/2026-01-01
+			controllers.GetDailyArchive(w, r)
+		} else if r.Method == http.MethodDelete {
 			controllers.DeleteProblem(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/restore") {
+			// /problems/1/restore -> 論理削除した問題を復元
+			controllers.RestoreProblem(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status") {
+			// /problems/1/status -> 下書き・予約公開・公開の切り替え
+			controllers.SetProblemStatus(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/merge") {
+			// /problems/1/merge -> 重複した問題を別の問題にまとめる
+			controllers.MergeProblem(w, r)
+		} else if r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/evaluate") {
+			// /problems/1/evaluate -> 外部の麻雀AIに評価させる
+			controllers.EvaluateProblem(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/evaluations") {
+			// /problems/1/evaluations -> 保存されているAIの評価
+			controllers.GetProblemEvaluations(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/mjai") {
+			// /problems/1/mjai -> MJAI 形式で書き出す
+			controllers.ExportMJAI(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/image.svg") || strings.HasSuffix(r.URL.Path, "/image.png") {
+			// /problems/1/image.png -> 問題を画像で返す (SNS などに貼る用)
+			controllers.GetProblemImage(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/share") {
+			// /problems/1/share -> Open Graph 付きの共有用ページ
+			controllers.GetProblemSharePage(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/duplicates") {
+			// /problems/1/duplicates -> 似ている問題の一覧
+			controllers.GetNearDuplicates(w, r)
+		} else if r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/tags") {
+			// /problems/1/tags -> タグの付け替え
+			controllers.SetProblemTags(w, r)
+		} else if r.Method == http.MethodPut || r.Method == http.MethodPatch {
+			// /problems/1 -> 問題の編集 (編集前の版は履歴に残る)
+			controllers.UpdateProblem(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/result/stream") {
+			// /problems/1/result/stream -> 集計結果を投票のたびに送る (Server-Sent Events)
+			controllers.StreamProblemResult(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/result") {
+			// /problems/1/result -> 集計結果 (自分が投票した後だけ見られる)
+			controllers.GetProblemResult(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/comments") {
+			// /problems/1/comments -> 投票の理由と議論 (自分が投票した後だけ見られる)
+			controllers.ProblemComments(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/revisions") {
+			// /problems/1/revisions -> 編集履歴
+			controllers.GetProblemRevisions(w, r)
 		} else {
 			controllers.GetProblemByID(w, r)
 		}
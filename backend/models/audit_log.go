@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 監査ログ: 管理操作やログインなど、後から「誰が何をしたか」を追えるようにする記録
+// 追記専用なので gorm.Model (UpdatedAt / DeletedAt) は使わない
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	// 操作したユーザー (ログイン失敗など、誰か分からない場合は nil)
+	ActorID *uint `gorm:"index" json:"actor_id"`
+
+	// 操作の種類 (例: "user.delete", "problem.create", "login.success")
+	Action string `gorm:"index;not null" json:"action"`
+
+	// 操作対象 (例: "user" の 12番)
+	TargetType string `gorm:"index:idx_audit_logs_target" json:"target_type"`
+	TargetID   *uint  `gorm:"index:idx_audit_logs_target" json:"target_id"`
+
+	// 操作前後のスナップショット (JSON)
+	Before json.RawMessage `gorm:"type:jsonb" json:"before,omitempty"`
+	After  json.RawMessage `gorm:"type:jsonb" json:"after,omitempty"`
+
+	// リクエスト情報
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	Note      string `json:"note,omitempty"` // 補足 (ログイン失敗の理由など)
+}
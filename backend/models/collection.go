@@ -0,0 +1,23 @@
+package models
+
+import "gorm.io/gorm"
+
+// 問題集 (例: "Defense Basics 1-20")
+// テーマごとに並び順つきで問題をまとめる
+type Collection struct {
+	gorm.Model
+	Title       string `gorm:"not null" json:"title"`
+	Slug        string `gorm:"uniqueIndex;not null" json:"slug"`
+	Description string `json:"description"`
+
+	Items []CollectionItem `gorm:"constraint:OnDelete:CASCADE;" json:"items,omitempty"`
+}
+
+// 問題集に入っている問題と、その並び順
+type CollectionItem struct {
+	ID           uint    `gorm:"primarykey" json:"id"`
+	CollectionID uint    `gorm:"not null;uniqueIndex:idx_collection_items_problem" json:"collection_id"`
+	ProblemID    uint    `gorm:"not null;uniqueIndex:idx_collection_items_problem;index" json:"problem_id"`
+	Problem      Problem `gorm:"constraint:OnDelete:CASCADE;" json:"problem"`
+	Position     int     `gorm:"not null" json:"position"` // 1 から始まる並び順
+}
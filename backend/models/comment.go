@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 問題ごとの議論のコメント
+// ParentID があれば返信。削除は論理削除で、返信がついていれば「削除されました」として残す
+type Comment struct {
+	gorm.Model
+	ProblemID uint    `gorm:"not null;index" json:"problem_id"`
+	Problem   Problem `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+
+	// 書いたユーザー (ユーザーを物理削除したら NULL)
+	UserID *uint `gorm:"index" json:"user_id"`
+	User   *User `gorm:"constraint:OnDelete:SET NULL;" json:"-"`
+
+	// 返信先のコメント (nil ならスレッドの最初のコメント)
+	ParentID *uint `gorm:"index" json:"parent_id"`
+
+	Body     string     `gorm:"type:text;not null" json:"body"`
+	EditedAt *time.Time `json:"edited_at"` // 最後に本文を編集した日時
+
+	// 管理者による非表示 (本文は残すが、管理者以外には見せない)
+	Hidden       bool   `gorm:"not null;default:false" json:"hidden"`
+	HiddenReason string `json:"hidden_reason,omitempty"`
+
+	LikeCount int `gorm:"not null;default:0" json:"like_count"`
+}
+
+// コメントへのいいね (1人1回)
+type CommentLike struct {
+	CommentID uint      `gorm:"primaryKey" json:"comment_id"`
+	Comment   Comment   `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	UserID    uint      `gorm:"primaryKey;index" json:"user_id"`
+	User      User      `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 「今日の一問」: 日付ごとに1問
+type DailyProblem struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// 日付 (Asia/Tokyo の "2006-01-02" 形式)。1日1問なのでユニーク
+	Date      string  `gorm:"uniqueIndex;not null" json:"date"`
+	ProblemID uint    `gorm:"not null;index" json:"problem_id"`
+	Problem   Problem `gorm:"constraint:OnDelete:CASCADE;" json:"problem"`
+}
+
+// 今日の一問の公開待ちキュー
+// 承認済み (ApprovedAt があるもの) を Position の小さい順に1日1問ずつ公開する
+// 公開したらキューからは消す (履歴は DailyProblem に残る)
+type DailyQueueEntry struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ProblemID  uint       `gorm:"not null;uniqueIndex" json:"problem_id"`
+	Problem    Problem    `gorm:"constraint:OnDelete:CASCADE;" json:"problem"`
+	Position   int        `gorm:"not null;index" json:"position"`
+	ApprovedAt *time.Time `json:"approved_at"`
+	ApprovedBy *uint      `json:"approved_by"`
+}
@@ -1,18 +1,345 @@
 package models
 
+import "time"
+
 // ResultResponse: 結果画面に必要な全データ
 type ResultResponse struct {
 	Average    float64 `json:"average"`     // 平均点
 	StdDev     float64 `json:"std_dev"`     // 標準偏差
-	UserScore  int     `json:"user_score"`  // あなたの点数
+	UserScore  int     `json:"user_score"`  // あなたの点数 (実際の投票)
 	UserDev    float64 `json:"user_dev"`    // あなたの偏差値
 	VoteCount  int     `json:"vote_count"`  // 総投票数
+	HasVoted   bool    `json:"has_voted"`   // 投票済みか (false なら User* は空)
+
+	// 分布の形 (評価が割れている問題は平均と標準偏差だけでは分からない)
+	Median         float64             `json:"median"`          // 中央値
+	Q1             float64             `json:"q1"`              // 第1四分位数
+	Q3             float64             `json:"q3"`              // 第3四分位数
+	UserPercentile float64             `json:"user_percentile"` // あなたの点数のパーセンタイル順位 (0-100、同点は半分だけ数える)
+	WhatIf         *ScoreStanding      `json:"what_if,omitempty"` // ?what_if= で指定した点数だった場合
+	MeanCI         *ConfidenceInterval `json:"mean_ci"`         // 平均の95%信頼区間 (2票未満は null)
+	Bimodality     *Bimodality         `json:"bimodality"`      // 二峰性の指標 (4票未満や全員同じ点数なら null)
+	Cohorts        []CohortResult      `json:"cohorts,omitempty"` // ?by=cohort のときの段位の層ごとの集計
 	
 	// グラフ用データ: [{"range": "0-10", "count": 2}, ...]
 	Histogram  []HistogramBin `json:"histogram"` 
 }
 
+// 点数が全体の中でどのあたりか
+type ScoreStanding struct {
+	Score      int     `json:"score"`
+	Dev        float64 `json:"dev"`        // 偏差値
+	Percentile float64 `json:"percentile"` // パーセンタイル順位 (0-100)
+}
+
+// 段位の層1つ分の集計
+// 票が少ない層は Suppressed で、票数も含めて中身を返さない (個人の投票が分かってしまうので)
+type CohortResult struct {
+	Cohort     string          `json:"cohort"` // beginner / intermediate / advanced / expert / unranked
+	Suppressed bool            `json:"suppressed"`
+	Result     *ResultResponse `json:"result,omitempty"`
+}
+
+// 信頼区間
+type ConfidenceInterval struct {
+	Level float64 `json:"level"` // 信頼水準 (例: 0.95)
+	Low   float64 `json:"low"`
+	High  float64 `json:"high"`
+}
+
+// 二峰性の指標 (Sarle の bimodality coefficient)
+// 5/9 (一様分布の値) を超えると、評価が2つの山に分かれている可能性が高い
+type Bimodality struct {
+	Coefficient float64 `json:"coefficient"`
+	Bimodal     bool    `json:"bimodal"`
+}
+
 type HistogramBin struct {
-	Range string `json:"range"` // ラベル (例: "50-60")
-	Count int    `json:"count"` // 人数
-}
\ No newline at end of file
+	Range string  `json:"range"` // ラベル (例: "50-60")
+	From  float64 `json:"from"`  // 区間の下端
+	To    float64 `json:"to"`    // 区間の上端 (どちらの端を含むかは ?closed= による)
+	Count int     `json:"count"` // 人数
+}
+
+// LoginResponse: ログイン成功時のレスポンス (ユーザー情報 + APIトークン)
+type LoginResponse struct {
+	User
+	Token string `json:"token"`
+}
+
+// UserListItem: 管理画面のユーザー一覧の1行
+type UserListItem struct {
+	User
+	VoteCount int64 `json:"vote_count"` // 投票数
+}
+
+// UserStatusRequest: 停止・BANの設定 (POST /users/{id}/status)
+type UserStatusRequest struct {
+	Status string     `json:"status"` // "active" / "suspended" / "banned"
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"` // 期限 (nil なら無期限)
+}
+
+// ProblemUpdateRequest: 問題の編集 (PUT/PATCH /problems/{id})
+// PATCH では送られてきた項目だけを変更する
+type ProblemUpdateRequest struct {
+	HandTiles *string `json:"hand_tiles"`
+	DoraTiles *string `json:"dora_tiles"`
+	Wind      *string `json:"wind"`
+	Round     *string `json:"round"`
+	Score     *int    `json:"score"`
+
+	OpponentRiichi *bool `json:"opponent_riichi"`
+
+	// 牌譜から作った問題の河・副露・点数 (形式は Problem と同じ、空文字で消す)
+	Rivers *string `json:"rivers"`
+	Melds  *string `json:"melds"`
+	Scores *string `json:"scores"`
+
+	// 手牌の評価が変わる変更かどうか (省略時は内容から自動判定)
+	Material *bool `json:"material"`
+}
+
+// ProblemStatusRequest: 公開状態の変更 (POST /problems/{id}/status)
+type ProblemStatusRequest struct {
+	Status         string     `json:"status"` // "draft" / "scheduled" / "published"
+	PublishAt      *time.Time `json:"publish_at"`
+	VotingClosesAt *time.Time `json:"voting_closes_at"`
+}
+
+// ProblemSummary: 問題一覧の1行 (一覧に不要な牌・投票データは含めない)
+// キー名は models.Problem の JSON と揃えている
+type ProblemSummary struct {
+	ID        uint       `json:"ID"`
+	CreatedAt time.Time  `json:"CreatedAt"`
+	Wind      string     `json:"wind"`
+	Round     string     `json:"round"`
+	Score     int        `json:"score"`
+	Shanten   *int       `json:"shanten"`
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+
+	VoteCount  int64   `json:"vote_count"`   // 投票数
+	VoteStdDev float64 `json:"vote_std_dev"` // 評価のばらつき (大きいほど意見が割れている)
+	HasVoted   bool    `json:"has_voted"`    // ログイン中のユーザーが投票済みか
+}
+
+// TagSummary: タグ一覧の1行
+type TagSummary struct {
+	Tag
+	ProblemCount int64 `json:"problem_count"` // このタグが付いた問題の数
+}
+
+// CollectionSummary: 問題集一覧の1行
+type CollectionSummary struct {
+	ID           uint   `json:"ID"`
+	Title        string `json:"title"`
+	Slug         string `json:"slug"`
+	Description  string `json:"description"`
+	ProblemCount int64  `json:"problem_count"`
+}
+
+// CollectionProgress: 問題集の進み具合 (GET /collections/{id}/progress)
+type CollectionProgress struct {
+	CollectionID uint                     `json:"collection_id"`
+	Total        int                      `json:"total"`   // 問題数
+	Voted        int                      `json:"voted"`   // 投票済みの問題数
+	Percent      float64                  `json:"percent"` // 達成率 (%)
+	Items        []CollectionProgressItem `json:"items"`
+}
+
+type CollectionProgressItem struct {
+	ProblemID uint       `json:"problem_id"`
+	Position  int        `json:"position"`
+	Voted     bool       `json:"voted"`
+	Point     *int       `json:"point,omitempty"` // 自分の評価点 (最新の投票)
+	VotedAt   *time.Time `json:"voted_at,omitempty"`
+}
+
+// DuplicateCandidate: 重複の疑いがある問題 (GET /problems/{id}/duplicates)
+type DuplicateCandidate struct {
+	ID        uint   `json:"ID"`
+	HandTiles string `json:"hand_tiles"`
+	DoraTiles string `json:"dora_tiles"`
+	Wind      string `json:"wind"`
+	Round     string `json:"round"`
+	Score     int    `json:"score"`
+	Status    string `json:"status"`
+
+	Distance  int   `json:"distance"`   // 牌の違い (何枚入れ替えれば同じ手牌になるか)
+	Exact     bool  `json:"exact"`      // 指紋が一致する (状況も含めて同じ問題)
+	VoteCount int64 `json:"vote_count"` // 投票数
+}
+
+// ProblemMergeRequest: 重複した問題をまとめる (POST /problems/{id}/merge)
+type ProblemMergeRequest struct {
+	Into uint `json:"into"` // 残す方の問題のID
+}
+
+type ProblemMergeResult struct {
+	MergedInto    uint  `json:"merged_into"`
+	MovedVotes    int64 `json:"moved_votes"`    // 移した投票の数
+	DroppedVotes  int64 `json:"dropped_votes"`  // 両方に投票していたので消した投票の数
+	MovedComments int64 `json:"moved_comments"` // 移した議論のコメントの数
+}
+
+// ImportRound: 牌譜の局の一覧 (取り込む局面を選ぶ用)
+type ImportRound struct {
+	Index  int    `json:"index"`  // 牌譜の中の何局目か (0始まり、取り込むときの kyoku)
+	Name   string `json:"name"`   // 例: "東1"
+	Honba  int    `json:"honba"`  // 本場
+	Dealer int    `json:"dealer"` // 親の座席
+	Turns  [4]int `json:"turns"`  // 座席ごとの打牌の回数 (turn に指定できる上限)
+}
+
+// ProblemImportResult: 問題の一括取り込みの結果 (POST /admin/problems/import)
+type ProblemImportResult struct {
+	DryRun  bool                     `json:"dry_run"` // 検証だけで保存していない
+	Atomic  bool                     `json:"atomic"`  // 1件でもエラーがあれば何も保存しない
+	Total   int                      `json:"total"`
+	Valid   int                      `json:"valid"`   // 検証を通った件数
+	Created int                      `json:"created"` // 保存した件数
+	Rows    []ProblemImportRowResult `json:"rows"`
+}
+
+type ProblemImportRowResult struct {
+	Row            int    `json:"row"`                        // 何件目か (1始まり、CSV は見出しを除く)
+	ID             uint   `json:"id,omitempty"`               // 作成した問題のID
+	Error          string `json:"error,omitempty"`            // この行を取り込めない理由
+	DuplicateOf    *uint  `json:"duplicate_of,omitempty"`     // 同じ問題が既にある
+	DuplicateOfRow int    `json:"duplicate_of_row,omitempty"` // 同じ問題が取り込むデータの中にある
+}
+
+// Leaderboard: ランキング (GET /leaderboards)
+type Leaderboard struct {
+	Period     string             `json:"period"`               // week / month / all
+	From       *time.Time         `json:"from"`                 // 期間の始まり (all なら null)
+	To         *time.Time         `json:"to"`                   // 期間の終わり (この時刻は含まない)
+	Tags       string             `json:"tags,omitempty"`       // ?tags= で絞った場合
+	Collection uint               `json:"collection,omitempty"` // ?collection= で絞った場合
+	MinVotes   int                `json:"min_votes"`            // 載るのに必要な投票数
+	Total      int                `json:"total"`                // 載っている人数
+	Entries    []LeaderboardEntry `json:"entries"`
+}
+
+// LeaderboardEntry: ランキングの1行
+type LeaderboardEntry struct {
+	Rank         int       `json:"rank"`
+	UserID       uint      `json:"user_id"`
+	Name         string    `json:"name"`
+	Score        float64   `json:"score"`         // 合意との近さの平均 (0-100)
+	Votes        int       `json:"votes"`         // 期間中にレーティングに反映された投票数
+	RatingChange float64   `json:"rating_change"` // 期間中のレーティングの増減
+	LastSettled  time.Time `json:"-"`             // 最後に反映された問題の締め切り (同点のときに使う)
+}
+
+// LeaderboardStanding: 自分の順位 (GET /leaderboards/me)
+type LeaderboardStanding struct {
+	Leaderboard
+	Me        *LeaderboardEntry `json:"me"`         // 自分の行 (載っていなければ null)
+	MyVotes   int               `json:"my_votes"`   // 期間中の自分の投票数 (載っていなくても返す)
+	VotesLeft int               `json:"votes_left"` // 載るまでにあと何票必要か
+}
+
+// UserAnalytics: ユーザーの分析 (GET /users/{id}/analytics)
+// 合意との差は、締め切られてレーティングに反映された投票 (RatingHistory) だけで計算する
+type UserAnalytics struct {
+	UserID        uint     `json:"user_id"`
+	TotalVotes    int      `json:"total_votes"`
+	RatedVotes    int      `json:"rated_votes"`    // 合意と比べられた投票数
+	Score         *float64 `json:"score"`          // 合意との近さの平均 (0-100、比べられた投票がなければ null)
+	MeanDeviation *float64 `json:"mean_deviation"` // 合意との差 (絶対値) の平均
+	MeanBias      *float64 `json:"mean_bias"`      // 合意との差の平均 (プラスなら高めにつける = 強気)
+
+	// 期間ごとの推移
+	Interval string           `json:"interval"` // day / week / month
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"` // この時刻は含まない
+	Series   []AnalyticsPoint `json:"series"`
+
+	// タグごとの成績 (近さの平均が高い順)。得意・苦手は自分の平均と比べたタグ名
+	Tags       []TagPerformance `json:"tags"`
+	Strengths  []string         `json:"strengths"`
+	Weaknesses []string         `json:"weaknesses"`
+
+	Streak    Streak              `json:"streak"`
+	Community CommunityComparison `json:"community"`
+}
+
+// AnalyticsPoint: 推移の1区間
+type AnalyticsPoint struct {
+	Start         time.Time `json:"start"`
+	Votes         int       `json:"votes"`       // この区間に投票した数
+	RatedVotes    int       `json:"rated_votes"` // そのうち合意と比べられた数
+	MeanDeviation *float64  `json:"mean_deviation"`
+	MeanBias      *float64  `json:"mean_bias"`
+}
+
+// TagPerformance: タグ1つ分の成績
+type TagPerformance struct {
+	Slug          string  `json:"slug"`
+	Name          string  `json:"name"`
+	Votes         int     `json:"votes"`
+	Score         float64 `json:"score"`
+	MeanDeviation float64 `json:"mean_deviation"`
+	MeanBias      float64 `json:"mean_bias"`
+}
+
+// Streak: 連続して投票した日数 (アプリのタイムゾーンの日付で数える)
+type Streak struct {
+	Current      int    `json:"current"` // 今日か昨日まで続いている日数 (途切れていれば0)
+	Longest      int    `json:"longest"`
+	LastVoteDate string `json:"last_vote_date,omitempty"`
+}
+
+// CommunityComparison: 他のユーザーとの比較 (自分の値がなければ null)
+type CommunityComparison struct {
+	Rating *CommunityStanding `json:"rating"` // レーティング (反映された投票がある人の中で)
+	Score  *CommunityStanding `json:"score"`  // 合意との近さの平均
+	Bias   *CommunityStanding `json:"bias"`   // 合意との差の平均
+	Votes  *CommunityStanding `json:"votes"`  // 投票数 (1票以上の人の中で)
+}
+
+// CommunityStanding: 自分の値がユーザー全体の中でどのあたりか
+type CommunityStanding struct {
+	Value      float64 `json:"value"`
+	Mean       float64 `json:"mean"`       // ユーザー全体の平均
+	Percentile float64 `json:"percentile"` // パーセンタイル順位 (0-100、同じ値は半分だけ数える)
+	Users      int     `json:"users"`      // 比べた人数
+}
+
+// ProblemDiscussion: 問題の議論 (GET /problems/{id}/comments)
+type ProblemDiscussion struct {
+	Reasons  []VoteReason      `json:"reasons"`  // 理由つきの投票 (ユーザーごとに最新のもの、新しい順)
+	Comments []CommentResponse `json:"comments"` // スレッドの最初のコメント (返信は replies に入る)
+	Total    int               `json:"total"`    // 表示しているコメントの数 (返信も含む)
+}
+
+// VoteReason: 投票の点数と理由
+type VoteReason struct {
+	VoteID    uint      `json:"vote_id"`
+	UserID    *uint     `json:"user_id"` // 匿名化された投票なら null
+	Name      string    `json:"name"`
+	Point     int       `json:"point"`
+	Reason    string    `json:"reason"`
+	Revision  int       `json:"revision"` // 投票した問題の版 (手牌が変わる前の投票か見分ける用)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentResponse: 表示用のコメント
+// 削除・非表示のコメントは本文と書いた人を空にして返す (管理者には非表示のコメントの本文も返す)
+type CommentResponse struct {
+	ID           uint              `json:"id"`
+	ParentID     *uint             `json:"parent_id"`
+	UserID       *uint             `json:"user_id"`
+	Name         string            `json:"name"`
+	Body         string            `json:"body"`
+	CreatedAt    time.Time         `json:"created_at"`
+	EditedAt     *time.Time        `json:"edited_at"`
+	Deleted      bool              `json:"deleted"`
+	Hidden       bool              `json:"hidden"`
+	HiddenReason string            `json:"hidden_reason,omitempty"` // 管理者にだけ返す
+	LikeCount    int               `json:"like_count"`
+	LikedByMe    bool              `json:"liked_by_me"`
+	Replies      []CommentResponse `json:"replies"`
+}
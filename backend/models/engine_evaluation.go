@@ -0,0 +1,24 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 外部の麻雀AIによる問題の評価 (問題・エンジンごとに最新の1件)
+type EngineEvaluation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // 最後に評価した日時
+
+	ProblemID uint    `gorm:"not null;uniqueIndex:idx_engine_evaluations_engine" json:"problem_id"`
+	Problem   Problem `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	Engine    string  `gorm:"size:100;not null;uniqueIndex:idx_engine_evaluations_engine" json:"engine"`
+
+	// 評価したときの問題の版 (これより後に手牌が変わっていれば古い評価)
+	ProblemRevision int `gorm:"not null" json:"problem_revision"`
+	// AIが一番に選んだ牌 (0-33)
+	BestTile int `gorm:"not null" json:"best_tile"`
+	// 打牌の候補と確率 ([{"tile":0,"probability":0.7}, ...] 確率の高い順)
+	Candidates json.RawMessage `gorm:"type:jsonb;not null" json:"candidates"`
+}
@@ -1,6 +1,25 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"portfolio-backend/mahjong"
+	"portfolio-backend/mahjong/gamelog"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 問題の公開状態
+const (
+	ProblemStatusDraft     = "draft"     // 下書き (管理者だけが見られる)
+	ProblemStatusScheduled = "scheduled" // 予約公開 (PublishAt になったら公開)
+	ProblemStatusPublished = "published" // 公開中
+)
 
 type Problem struct {
 	gorm.Model
@@ -16,6 +35,185 @@ type Problem struct {
 	Round string `json:"round"` // 局 (例: "East-1")
 	Score int    `json:"score"` // 持ち点 (例: 25000)
 
+	// 他家からリーチを受けているか (押し引きの問題)
+	OpponentRiichi bool `json:"opponent_riichi"`
+
+	// 牌譜から作った問題の河・副露・点数: JSON形式の文字列 (空なら手牌だけの問題)
+	// 4人分を自分から見た順 (自分, 下家, 対面, 上家) に並べる。形式は mahjong.Rivers / mahjong.Melds
+	Rivers string `json:"rivers"`
+	Melds  string `json:"melds"`
+	Scores string `json:"scores"` // 例: "[25000,25000,25000,25000]"
+
+	// 版管理: 編集するたびに Revision が増える
+	// MaterialRevision は手牌の評価が変わる編集が最後に入った版
+	Revision         int `gorm:"not null;default:1" json:"revision"`
+	MaterialRevision int `gorm:"not null;default:1" json:"material_revision"`
+
+	// 手牌から自動で計算する項目 (作成・編集時に Analyze で更新)
+	Shanten  *int     `gorm:"index" json:"shanten"` // 向聴数 (0 で聴牌)
+	AutoTags []string `gorm:"-" json:"-"`           // 自動で付けるタグ (保存は problem_tags に)
+
+	// 重複チェック用の指紋 (色の入れ替えなどをならした手牌 + 状況のハッシュ)
+	Fingerprint string `gorm:"size:64;index" json:"fingerprint"`
+
+	// 公開状態と日時
+	Status         string     `gorm:"not null;default:published;index" json:"status"`
+	PublishAt      *time.Time `gorm:"index" json:"publish_at"` // 公開日時 (予約公開ならこの時刻に公開される)
+	VotingClosesAt *time.Time `json:"voting_closes_at"`        // 投票の締め切り (nil なら締め切りなし)
+	RatedAt        *time.Time `gorm:"index" json:"-"`          // 投票者のレーティングに反映した日時 (rating パッケージ)
+
+	// タグ (中間テーブル problem_tags)
+	Tags []Tag `gorm:"many2many:problem_tags;" json:"tags"`
+
 	// リレーション: この問題に対する投票データ
-	Votes []Vote `json:"votes"` 
-}
\ No newline at end of file
+	// 問題を物理削除したら投票も消す
+	Votes []Vote `gorm:"constraint:OnDelete:CASCADE;" json:"votes"` 
+}
+
+// 指定時刻に一般ユーザーから見える状態か
+func (p *Problem) IsPublished(now time.Time) bool {
+	switch p.Status {
+	case ProblemStatusPublished:
+		return true
+	case ProblemStatusScheduled:
+		return p.PublishAt != nil && !now.Before(*p.PublishAt)
+	default:
+		return false
+	}
+}
+
+// 指定時刻に投票を受け付けているか
+func (p *Problem) IsVotingOpen(now time.Time) bool {
+	return p.IsPublished(now) && (p.VotingClosesAt == nil || now.Before(*p.VotingClosesAt))
+}
+
+// 手牌・ドラ表示牌をチェックして、向聴数などの派生項目を計算する
+func (p *Problem) Analyze() error {
+	hand, err := mahjong.ParseTiles(p.HandTiles)
+	if err != nil {
+		return errors.New("hand_tiles: " + err.Error())
+	}
+	dora, err := mahjong.ParseTiles(p.DoraTiles)
+	if err != nil {
+		return errors.New("dora_tiles: " + err.Error())
+	}
+	rivers, err := mahjong.ParseRivers(p.Rivers)
+	if err != nil {
+		return err
+	}
+	melds, err := mahjong.ParseMelds(p.Melds)
+	if err != nil {
+		return err
+	}
+	if p.Scores != "" {
+		var scores []int
+		if err := json.Unmarshal([]byte(p.Scores), &scores); err != nil || len(scores) != 4 {
+			return errors.New("scores must be a JSON array of 4 numbers")
+		}
+	}
+
+	// 副露した分だけ手牌は少なくなる
+	called := len(melds[0])
+	if max := 14 - 3*called; len(hand) == 0 || len(hand) > max {
+		return fmt.Errorf("hand_tiles must have 1-%d tiles", max)
+	}
+	if err := mahjong.CheckCopies(hand, dora, mahjong.VisibleTiles(rivers, melds)); err != nil {
+		return err
+	}
+
+	shanten := mahjong.Shanten(hand, called)
+	p.Shanten = &shanten
+	p.AutoTags = mahjong.AutoTags(hand, dora, called, p.situation())
+	p.Fingerprint = fingerprint(mahjong.CanonicalPosition(hand, dora, rivers, melds), p.Wind, p.Round, p.Score, p.OpponentRiichi)
+	return nil
+}
+
+// 正規化した局面 (手牌・副露・河) と状況から指紋を作る
+// 手牌が同じでも副露・河や自風・局・点数・リーチの有無が違えば別の問題として扱う
+func fingerprint(canonical, wind, round string, score int, riichi bool) string {
+	key := fmt.Sprintf("%s|%s|%s|%d|%t", canonical, strings.ToLower(wind), strings.ToLower(round), score, riichi)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// 自風・局などから自動タグ用の状況を作る
+func (p *Problem) situation() mahjong.Situation {
+	round := strings.ToLower(p.Round)
+	return mahjong.Situation{
+		Dealer: strings.EqualFold(p.Wind, "East") || p.Wind == "東",
+		// 半荘戦の南4局 (例: "South-4", "南4")
+		AllLast:        (strings.HasPrefix(round, "south") || strings.HasPrefix(round, "南")) && strings.HasSuffix(round, "4"),
+		OpponentRiichi: p.OpponentRiichi,
+	}
+}
+
+// 風の表記 (フロントエンドは漢字、初期データは英語)
+var windNames = [][]string{
+	{"東", "east"},
+	{"南", "south"},
+	{"西", "west"},
+	{"北", "north"},
+}
+
+// "東" / "East" などを 0-3 にする
+func parseWind(s string) (int, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for i, names := range windNames {
+		for _, name := range names {
+			if strings.HasPrefix(s, name) {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// "東1" / "East-1" などを場風と局にする
+func parseRound(s string) (wind, number int, ok bool) {
+	wind, ok = parseWind(s)
+	if !ok {
+		return 0, 0, false
+	}
+	s = strings.TrimSpace(s)
+	n := s[len(s)-1]
+	if n < '1' || n > '4' {
+		return 0, 0, false
+	}
+	return wind, int(n - '0'), true
+}
+
+// 問題の局面を牌譜の形にする (MJAI への書き出しなどに使う)
+func (p *Problem) Position() (gamelog.Position, error) {
+	var pos gamelog.Position
+	var err error
+	if pos.Hand, err = mahjong.ParseTiles(p.HandTiles); err != nil {
+		return pos, errors.New("hand_tiles: " + err.Error())
+	}
+	if pos.Dora, err = mahjong.ParseTiles(p.DoraTiles); err != nil {
+		return pos, errors.New("dora_tiles: " + err.Error())
+	}
+	if pos.Rivers, err = mahjong.ParseRivers(p.Rivers); err != nil {
+		return pos, err
+	}
+	if pos.Melds, err = mahjong.ParseMelds(p.Melds); err != nil {
+		return pos, err
+	}
+
+	var ok bool
+	if pos.Wind, pos.Number, ok = parseRound(p.Round); !ok {
+		return pos, fmt.Errorf("unknown round %q", p.Round)
+	}
+	if pos.SeatWind, ok = parseWind(p.Wind); !ok {
+		return pos, fmt.Errorf("unknown wind %q", p.Wind)
+	}
+
+	// 点数が保存されていなければ自分の点数だけ入れる
+	pos.Scores = [4]int{p.Score, 25000, 25000, 25000}
+	if p.Scores != "" {
+		var scores []int
+		if err := json.Unmarshal([]byte(p.Scores), &scores); err == nil && len(scores) == 4 {
+			copy(pos.Scores[:], scores)
+		}
+	}
+	return pos, nil
+}
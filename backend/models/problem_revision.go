@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// 問題の過去のバージョン
+// 問題を編集するたびに、編集前の内容をここに1件保存する
+type ProblemRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"` // この版が置き換えられた日時
+
+	ProblemID uint `gorm:"not null;uniqueIndex:idx_problem_revisions_number" json:"problem_id"`
+	Revision  int  `gorm:"not null;uniqueIndex:idx_problem_revisions_number" json:"revision"`
+
+	// 当時の内容
+	HandTiles string `json:"hand_tiles"`
+	DoraTiles string `json:"dora_tiles"`
+	Wind      string `json:"wind"`
+	Round     string `json:"round"`
+	Score     int    `json:"score"`
+
+	OpponentRiichi bool `json:"opponent_riichi"`
+
+	Rivers string `json:"rivers,omitempty"`
+	Melds  string `json:"melds,omitempty"`
+	Scores string `json:"scores,omitempty"`
+
+	// 次の版への変更が手牌の評価を変えるものだったか
+	// (true なら、この版までの投票は最新の版の集計から外せる)
+	MaterialChange bool  `json:"material_change"`
+	EditorID       *uint `json:"editor_id"`
+}
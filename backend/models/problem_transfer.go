@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"portfolio-backend/mahjong"
+	"strings"
+	"time"
+)
+
+// 問題の一括取り込み・書き出しの1件 (JSON 配列の要素 / CSV の1行)
+// 牌は mpsz 表記 (例: "123m456p789s1122z")。JSON 配列 ("[0,1,2]") でも読み込める
+type ProblemTransferRow struct {
+	Hand           string     `json:"hand"`
+	Dora           string     `json:"dora"`
+	Wind           string     `json:"wind"`
+	Round          string     `json:"round"`
+	Score          int        `json:"score"`
+	OpponentRiichi bool       `json:"opponent_riichi"`
+	Status         string     `json:"status,omitempty"` // 省略したら公開
+	PublishAt      *time.Time `json:"publish_at,omitempty"`
+	VotingClosesAt *time.Time `json:"voting_closes_at,omitempty"`
+	Tags           []string   `json:"tags,omitempty"` // 手動で付けるタグ (自動タグは取り込み時に計算する)
+
+	// 牌譜から作った問題の河・副露・点数 (Problem と同じ JSON 文字列)
+	Rivers string `json:"rivers,omitempty"`
+	Melds  string `json:"melds,omitempty"`
+	Scores string `json:"scores,omitempty"`
+}
+
+// CSV の列 (1行目に見出しとして書く。取り込むときの列の順番は自由)
+var ProblemTransferColumns = []string{
+	"hand", "dora", "wind", "round", "score", "opponent_riichi",
+	"status", "publish_at", "voting_closes_at", "tags", "rivers", "melds", "scores",
+}
+
+// 問題を書き出し用の1件にする (tags は手動のタグ)
+func NewProblemTransferRow(p *Problem, tags []string) ProblemTransferRow {
+	row := ProblemTransferRow{
+		Hand:           p.HandTiles,
+		Dora:           p.DoraTiles,
+		Wind:           p.Wind,
+		Round:          p.Round,
+		Score:          p.Score,
+		OpponentRiichi: p.OpponentRiichi,
+		Status:         p.Status,
+		PublishAt:      p.PublishAt,
+		VotingClosesAt: p.VotingClosesAt,
+		Tags:           tags,
+		Rivers:         p.Rivers,
+		Melds:          p.Melds,
+		Scores:         p.Scores,
+	}
+	if hand, err := mahjong.ParseTiles(p.HandTiles); err == nil {
+		row.Hand = mahjong.FormatMPSZ(hand)
+	}
+	if dora, err := mahjong.ParseTiles(p.DoraTiles); err == nil {
+		row.Dora = mahjong.FormatMPSZ(dora)
+	}
+	return row
+}
+
+// 取り込む1件を問題にする (牌の表記を読むだけで、中身のチェックは Analyze で行う)
+func (row ProblemTransferRow) Problem() (*Problem, error) {
+	hand, err := parseTransferTiles(row.Hand)
+	if err != nil {
+		return nil, errors.New("hand: " + err.Error())
+	}
+	dora, err := parseTransferTiles(row.Dora)
+	if err != nil {
+		return nil, errors.New("dora: " + err.Error())
+	}
+	return &Problem{
+		HandTiles:      mahjong.FormatTiles(hand),
+		DoraTiles:      mahjong.FormatTiles(dora),
+		Wind:           strings.TrimSpace(row.Wind),
+		Round:          strings.TrimSpace(row.Round),
+		Score:          row.Score,
+		OpponentRiichi: row.OpponentRiichi,
+		Status:         strings.TrimSpace(row.Status),
+		PublishAt:      row.PublishAt,
+		VotingClosesAt: row.VotingClosesAt,
+		Rivers:         row.Rivers,
+		Melds:          row.Melds,
+		Scores:         row.Scores,
+	}, nil
+}
+
+// mpsz 表記か JSON 配列の牌を読む
+func parseTransferTiles(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("tiles are required")
+	}
+	if strings.HasPrefix(s, "[") {
+		return mahjong.ParseTiles(s)
+	}
+	return mahjong.ParseMPSZ(s)
+}
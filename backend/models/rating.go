@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// レーティングの変動履歴 (投票1件をレーティングに反映するたびに1行)
+type RatingHistory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint    `gorm:"not null;index:idx_rating_histories_user" json:"user_id"`
+	User      User    `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	ProblemID uint    `gorm:"not null;index" json:"problem_id"`
+	Problem   Problem `gorm:"constraint:OnDelete:CASCADE;" json:"-"`
+	VoteID    uint    `gorm:"not null" json:"vote_id"`
+
+	// 問題の投票が締め切られた (合意が決まった) 日時。履歴はこの順に並べる
+	SettledAt time.Time `gorm:"not null;index:idx_rating_histories_user" json:"settled_at"`
+
+	Point     int     `json:"point"`     // 投票した点数
+	Consensus float64 `json:"consensus"` // 合意 (投票の中央値)
+	Agreement float64 `json:"agreement"` // 合意との近さ (0-1、ぴったりなら1)
+	Expected  float64 `json:"expected"`  // レーティングから見込まれた近さ
+
+	Rating    float64 `json:"rating"`    // 反映後のレーティング
+	Deviation float64 `json:"deviation"` // 反映後の不確かさ
+	Change    float64 `json:"change"`    // 反映前からの増減
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// 問題のタグ (例: "defense", "early-riichi", "honitsu")
+type Tag struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `gorm:"uniqueIndex;not null" json:"name"` // 表示名
+	Slug      string    `gorm:"uniqueIndex;not null" json:"slug"` // 検索用の名前 (小文字・ハイフン区切り)
+}
+
+// タグの付け方
+const (
+	TagSourceManual = "manual" // 管理者が付けたもの
+	TagSourceAuto   = "auto"   // 手牌の解析から自動で付けたもの
+)
+
+// 問題とタグの中間テーブル
+type ProblemTag struct {
+	ProblemID uint      `gorm:"primaryKey" json:"problem_id"`
+	TagID     uint      `gorm:"primaryKey;index" json:"tag_id"`
+	Source    string    `gorm:"not null;default:manual;index" json:"source"`
+	CreatedAt time.Time `json:"created_at"`
+}
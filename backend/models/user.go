@@ -1,6 +1,17 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// アカウントの状態
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended" // 一時停止 (期限付き)
+	UserStatusBanned    = "banned"    // 利用禁止
+)
 
 type User struct {
 	gorm.Model
@@ -9,4 +20,35 @@ type User struct {
 	Password string `json:"password"` // 本来はハッシュ化すべきですが、まずは平文で進めます
 	Name     string `json:"name"`
 	Role     string `json:"role"`     // "admin" or "user"
-}
\ No newline at end of file
+
+	// モデレーション: 停止・BANの状態と理由、期限 (nil なら無期限)
+	Status       string     `gorm:"default:active;index" json:"status"`
+	StatusReason string     `json:"status_reason,omitempty"`
+	StatusUntil  *time.Time `json:"status_until,omitempty"`
+
+	// 段位 (自己申告、管理者が確認したら RankVerified)
+	// Cohort は段位から求めた実力の層 (結果を層ごとに集計するのに使う。mahjong.RankCohort)
+	RankPlatform string `json:"rank_platform,omitempty"` // 例: "tenhou", "majsoul"
+	Rank         string `json:"rank,omitempty"`          // 例: "4dan", "master2"
+	RankVerified bool   `json:"rank_verified"`
+	Cohort       string `gorm:"index" json:"cohort,omitempty"`
+
+	// 判断力のレーティング (Glicko 方式、投票が最終的な合意にどれだけ近かったかで上下する)
+	// RatingDeviation は不確かさ (投票するほど小さくなる)。計算は rating パッケージ
+	Rating          float64 `gorm:"not null;default:1500;index" json:"rating"`
+	RatingDeviation float64 `gorm:"not null;default:350" json:"rating_deviation"`
+	RatedVotes      int     `gorm:"not null;default:0" json:"rated_votes"` // レーティングに反映された投票数
+
+	// リレーション: このユーザーの投票
+	// ユーザーを物理削除したら投票は匿名化 (user_id = NULL) して残す
+	Votes []Vote `gorm:"constraint:OnDelete:SET NULL;" json:"-"`
+}
+
+// 指定時刻にログインや投票ができる状態かどうか
+// 停止・BANでも期限を過ぎていれば利用可能に戻る
+func (u *User) IsActive(now time.Time) bool {
+	if u.Status == "" || u.Status == UserStatusActive {
+		return true
+	}
+	return u.StatusUntil != nil && now.After(*u.StatusUntil)
+}
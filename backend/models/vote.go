@@ -5,11 +5,24 @@ import "gorm.io/gorm"
 type Vote struct {
 	gorm.Model
 	// 外部キー: どの問題に対する投票か
-	ProblemID uint `json:"problem_id"`
+	// 問題が物理削除されたら投票も一緒に消える (ON DELETE CASCADE)
+	ProblemID uint `gorm:"not null;index" json:"problem_id"`
 	
 	// 外部キー: どのユーザーが投票したか
-	UserID uint `json:"user_id"`
+	// 退会ユーザーの票を匿名化した場合は NULL になる (ON DELETE SET NULL)
+	UserID *uint `gorm:"index" json:"user_id"`
+	
+	// どの版の問題に対する投票か (投票時の Problem.Revision)
+	Revision int `gorm:"not null;default:1" json:"revision"`
 	
 	// 評価点 (0-100)
 	Point int `json:"point"`
-}
\ No newline at end of file
+	
+	// 点数の理由 (任意、議論の画面に表示する)
+	Reason string `gorm:"type:text" json:"reason,omitempty"`
+	
+	// 投票したときの投票者の段位の層と、管理者が確認した段位だったか (層ごとの集計に使う)
+	// あとで段位を変えても集計が変わらないように、投票時の値を残す
+	Cohort         string `gorm:"not null;default:''" json:"-"`
+	CohortVerified bool   `gorm:"not null;default:false" json:"-"`
+}
@@ -0,0 +1,66 @@
+package rating
+
+import "math"
+
+// レーティングの初期値と、不確かさの上下限
+const (
+	InitialRating    = 1500.0
+	InitialDeviation = 350.0
+	MinDeviation     = 30.0
+)
+
+// 合意からこれだけ離れると近さが約0.6になる点数 (正規分布の1σに当たる)
+const agreementTolerance = 10.0
+
+// 問題の難しさを求めるときの近さの平均の上下限
+// 全員ぴったり / 全員外れでも難しさが無限大にならないようにする
+const (
+	minMeanAgreement = 0.05
+	maxMeanAgreement = 0.95
+)
+
+// Glicko の q = ln(10) / 400
+var q = math.Ln10 / 400
+
+// レーティングと不確かさ
+type Rating struct {
+	Value     float64
+	Deviation float64
+}
+
+// 初めての人のレーティング
+func Initial() Rating {
+	return Rating{Value: InitialRating, Deviation: InitialDeviation}
+}
+
+// 投票した点数が合意にどれだけ近いか (0-1)
+// 差が0なら1、agreementTolerance 離れると約0.61、その2倍で約0.14
+func Agreement(point int, consensus float64) float64 {
+	z := (float64(point) - consensus) / agreementTolerance
+	return math.Exp(-z * z / 2)
+}
+
+// 問題の難しさをレーティングで表す
+// 投票者の近さの平均が meanAgreement なら、1500 の人がちょうどその近さを見込まれるように決める
+// (評価が割れている問題ほど近さの平均が下がり、難しい = レーティングが高い問題になる)
+func ProblemRating(meanAgreement float64) float64 {
+	m := math.Min(math.Max(meanAgreement, minMeanAgreement), maxMeanAgreement)
+	return InitialRating + 400*math.Log10(1/m-1)
+}
+
+// レーティング r の人が、難しさ problem の問題で見込まれる近さ (0-1)
+func Expected(r Rating, problem float64) float64 {
+	return 1 / (1 + math.Pow(10, -(r.Value-problem)/400))
+}
+
+// 投票1件 (近さ agreement) を反映したレーティング
+// Glicko の1対局分の更新で、問題側の不確かさは0 (合意は確定したもの) として扱う
+func Update(r Rating, problem, agreement float64) Rating {
+	e := Expected(r, problem)
+	d2 := 1 / (q * q * e * (1 - e))
+	v := 1 / (1/(r.Deviation*r.Deviation) + 1/d2)
+	return Rating{
+		Value:     r.Value + q*v*(agreement-e),
+		Deviation: math.Max(math.Sqrt(v), MinDeviation),
+	}
+}
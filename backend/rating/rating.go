@@ -0,0 +1,294 @@
+// Package rating は投票者の判断力をレーティングにする処理です。
+//
+// 問題の投票が締め切られたら (合意が決まったら)、各投票者の最後の投票が合意 (投票の中央値) に
+// どれだけ近かったかを Glicko 方式でレーティングに反映する。
+// 麻雀AIの評価は打牌の候補で点数ではないので、比べる相手は投票の合意だけにしている。
+//
+// 反映はバックグラウンドで少しずつ行い (Start)、計算方法を変えたときは Recompute で全部やり直す。
+package rating
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 既定値 (環境変数で変更可)
+const (
+	defaultSettleAfter = 7 * 24 * time.Hour // RATING_SETTLE_AFTER: 締め切りのない問題は公開からこの期間で合意が決まったとみなす
+	defaultMinVotes    = 10                 // RATING_MIN_VOTES: これより票が少ない問題はレーティングに使わない
+	defaultInterval    = 10 * time.Minute   // RATING_INTERVAL: 締め切られた問題を探す間隔
+)
+
+// 同じプロセスで反映とやり直しが重ならないようにする
+// (別のインスタンスとは問題の行ロックで二重に反映しないようにしている)
+var mu sync.Mutex
+
+func settleAfter() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("RATING_SETTLE_AFTER")); err == nil && d > 0 {
+		return d
+	}
+	return defaultSettleAfter
+}
+
+func minVotes() int {
+	if n, err := strconv.Atoi(os.Getenv("RATING_MIN_VOTES")); err == nil && n > 0 {
+		return n
+	}
+	return defaultMinVotes
+}
+
+// 問題の合意が決まる日時
+// 締め切りのある問題はその時刻、ない問題は公開から after 後。下書きなど公開日時が決まっていなければ false
+func SettledAt(p models.Problem, after time.Duration) (time.Time, bool) {
+	if p.VotingClosesAt != nil {
+		return *p.VotingClosesAt, p.Status != models.ProblemStatusDraft
+	}
+	switch p.Status {
+	case models.ProblemStatusPublished:
+		if p.PublishAt != nil {
+			return p.PublishAt.Add(after), true
+		}
+		return p.CreatedAt.Add(after), true
+	case models.ProblemStatusScheduled:
+		if p.PublishAt != nil {
+			return p.PublishAt.Add(after), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// 点数の中央値 (points は並べ替える)
+func median(points []int) float64 {
+	sort.Ints(points)
+	n := len(points)
+	if n%2 == 1 {
+		return float64(points[n/2])
+	}
+	return float64(points[n/2-1]+points[n/2]) / 2
+}
+
+// 合意が決まった、まだ反映していない問題
+type settledProblem struct {
+	ID        uint
+	SettledAt time.Time
+}
+
+// now までに合意が決まった未反映の問題 (決まった順)
+func settledProblems(db *gorm.DB, now time.Time) ([]settledProblem, error) {
+	var problems []models.Problem
+	err := db.Select("id", "created_at", "status", "publish_at", "voting_closes_at").
+		Where("rated_at IS NULL AND status <> ?", models.ProblemStatusDraft).
+		Find(&problems).Error
+	if err != nil {
+		return nil, err
+	}
+	after := settleAfter()
+	var settled []settledProblem
+	for _, p := range problems {
+		if at, ok := SettledAt(p, after); ok && !at.After(now) {
+			settled = append(settled, settledProblem{ID: p.ID, SettledAt: at})
+		}
+	}
+	sort.Slice(settled, func(i, j int) bool {
+		if !settled[i].SettledAt.Equal(settled[j].SettledAt) {
+			return settled[i].SettledAt.Before(settled[j].SettledAt)
+		}
+		return settled[i].ID < settled[j].ID
+	})
+	return settled, nil
+}
+
+// 1問分の投票をレーティングに反映して、反映した投票数を返す
+// 集計対象は結果画面の ?revision=current と同じ (退会ユーザーの票と、手牌が変わる前の票は除く) で、締め切り後の票も除く
+func rateProblem(tx *gorm.DB, sp settledProblem) (int, error) {
+	var problem models.Problem
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND rated_at IS NULL", sp.ID).
+		First(&problem).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 別のインスタンスが先に反映した
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	markRated := func() error {
+		return tx.Model(&problem).UpdateColumn("rated_at", time.Now()).Error
+	}
+
+	var votes []models.Vote
+	err = tx.Model(&models.Vote{}).Select("votes.*").
+		Joins("JOIN problems ON problems.id = votes.problem_id").
+		Joins("LEFT JOIN users ON users.id = votes.user_id").
+		Where("votes.problem_id = ?", problem.ID).
+		Where("votes.user_id IS NULL OR users.deleted_at IS NULL").
+		Where("votes.revision >= problems.material_revision").
+		Where("votes.created_at <= ?", sp.SettledAt).
+		Order("votes.created_at, votes.id").
+		Find(&votes).Error
+	if err != nil {
+		return 0, err
+	}
+	if len(votes) < minVotes() {
+		return 0, markRated()
+	}
+
+	points := make([]int, len(votes))
+	latest := map[uint]models.Vote{} // ユーザーごとの最後の投票
+	for i, v := range votes {
+		points[i] = v.Point
+		if v.UserID != nil {
+			latest[*v.UserID] = v
+		}
+	}
+	consensus := median(points)
+	var sum float64
+	for _, v := range votes {
+		sum += Agreement(v.Point, consensus)
+	}
+	difficulty := ProblemRating(sum / float64(len(votes)))
+
+	userIDs := make([]uint, 0, len(latest))
+	for id := range latest {
+		userIDs = append(userIDs, id)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", userIDs).
+			Order("id").
+			Find(&users).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, user := range users {
+		vote := latest[user.ID]
+		before := Rating{Value: user.Rating, Deviation: user.RatingDeviation}
+		agreement := Agreement(vote.Point, consensus)
+		after := Update(before, difficulty, agreement)
+
+		err := tx.Model(&user).UpdateColumns(map[string]interface{}{
+			"rating":           after.Value,
+			"rating_deviation": after.Deviation,
+			"rated_votes":      gorm.Expr("rated_votes + 1"),
+		}).Error
+		if err != nil {
+			return 0, err
+		}
+		err = tx.Create(&models.RatingHistory{
+			UserID:    user.ID,
+			ProblemID: problem.ID,
+			VoteID:    vote.ID,
+			SettledAt: sp.SettledAt,
+			Point:     vote.Point,
+			Consensus: consensus,
+			Agreement: agreement,
+			Expected:  Expected(before, difficulty),
+			Rating:    after.Value,
+			Deviation: after.Deviation,
+			Change:    after.Value - before.Value,
+		}).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(users), markRated()
+}
+
+// now までに合意が決まった問題を反映する (1問ずつ別のトランザクション)
+// 反映した問題数と投票数を返す
+func RateSettled(now time.Time) (problems, votes int, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	settled, err := settledProblems(database.DB, now)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, sp := range settled {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			n, err := rateProblem(tx, sp)
+			votes += n
+			return err
+		})
+		if err != nil {
+			return problems, votes, fmt.Errorf("problem %d: %w", sp.ID, err)
+		}
+		problems++
+	}
+	return problems, votes, nil
+}
+
+// 全員のレーティングを初期値に戻し、合意が決まった全ての問題を決まった順に反映し直す
+// 途中で失敗したら何も変えない (1つのトランザクション)
+func Recompute(now time.Time) (problems, votes int, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		problems, votes = 0, 0
+		all := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped()
+		if err := all.Model(&models.User{}).UpdateColumns(map[string]interface{}{
+			"rating":           InitialRating,
+			"rating_deviation": InitialDeviation,
+			"rated_votes":      0,
+		}).Error; err != nil {
+			return err
+		}
+		if err := all.Delete(&models.RatingHistory{}).Error; err != nil {
+			return err
+		}
+		if err := all.Model(&models.Problem{}).UpdateColumn("rated_at", nil).Error; err != nil {
+			return err
+		}
+
+		settled, err := settledProblems(tx, now)
+		if err != nil {
+			return err
+		}
+		for _, sp := range settled {
+			n, err := rateProblem(tx, sp)
+			if err != nil {
+				return fmt.Errorf("problem %d: %w", sp.ID, err)
+			}
+			problems++
+			votes += n
+		}
+		return nil
+	})
+	return problems, votes, err
+}
+
+// 合意が決まった問題の反映をバックグラウンドで開始する (起動時と RATING_INTERVAL ごと)
+func Start() {
+	interval := defaultInterval
+	if d, err := time.ParseDuration(os.Getenv("RATING_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+
+	go func() {
+		for {
+			problems, votes, err := RateSettled(time.Now())
+			if err != nil {
+				log.Printf("Failed to update ratings: %v", err)
+			} else if problems > 0 {
+				fmt.Printf("📈 Rated %d votes on %d settled problems\n", votes, problems)
+			}
+			time.Sleep(interval)
+		}
+	}()
+	fmt.Printf("📈 Rating updater started (every %s)\n", interval)
+}
@@ -0,0 +1,99 @@
+package rating
+
+import (
+	"math"
+	"portfolio-backend/models"
+	"testing"
+	"time"
+)
+
+func TestAgreement(t *testing.T) {
+	if got := Agreement(50, 50); got != 1 {
+		t.Errorf("Agreement(50, 50) = %v, want 1", got)
+	}
+	if got := Agreement(60, 50); math.Abs(got-0.6065) > 0.001 {
+		t.Errorf("Agreement(60, 50) = %v, want about 0.607", got)
+	}
+	if Agreement(30, 50) >= Agreement(40, 50) {
+		t.Error("agreement should decrease with distance from the consensus")
+	}
+}
+
+func TestProblemRating(t *testing.T) {
+	if got := ProblemRating(0.5); got != InitialRating {
+		t.Errorf("ProblemRating(0.5) = %v, want %v", got, InitialRating)
+	}
+	// 近さの平均が低い (評価が割れている) ほど難しい
+	if ProblemRating(0.3) <= ProblemRating(0.7) {
+		t.Error("a contentious problem should be rated harder")
+	}
+	if math.IsInf(ProblemRating(0), 0) || math.IsInf(ProblemRating(1), 0) {
+		t.Error("ProblemRating should be finite at the extremes")
+	}
+	// 1500 の人にはちょうど近さの平均が見込まれる
+	if got := Expected(Initial(), ProblemRating(0.8)); math.Abs(got-0.8) > 1e-9 {
+		t.Errorf("Expected = %v, want 0.8", got)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	r := Initial()
+	up := Update(r, InitialRating, 1)
+	down := Update(r, InitialRating, 0)
+	if up.Value <= r.Value || down.Value >= r.Value {
+		t.Errorf("Update: up %v, down %v from %v", up.Value, down.Value, r.Value)
+	}
+	// 見込みどおりなら変わらない
+	if same := Update(r, InitialRating, 0.5); same.Value != r.Value {
+		t.Errorf("Update with the expected agreement = %v, want %v", same.Value, r.Value)
+	}
+	if up.Deviation >= r.Deviation {
+		t.Errorf("deviation should shrink: %v -> %v", r.Deviation, up.Deviation)
+	}
+
+	// 何度反映しても不確かさは下限より小さくならない
+	for i := 0; i < 1000; i++ {
+		r = Update(r, InitialRating, 0.9)
+	}
+	if r.Deviation != MinDeviation {
+		t.Errorf("deviation = %v, want %v", r.Deviation, MinDeviation)
+	}
+}
+
+func TestSettledAt(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	publish := created.Add(24 * time.Hour)
+	closes := created.Add(72 * time.Hour)
+	week := 7 * 24 * time.Hour
+
+	tests := []struct {
+		name    string
+		problem models.Problem
+		want    time.Time
+		wantOK  bool
+	}{
+		{"closing time", models.Problem{Status: models.ProblemStatusPublished, VotingClosesAt: &closes}, closes, true},
+		{"published", models.Problem{Status: models.ProblemStatusPublished, PublishAt: &publish}, publish.Add(week), true},
+		{"published without publish_at", models.Problem{Status: models.ProblemStatusPublished}, created.Add(week), true},
+		{"scheduled", models.Problem{Status: models.ProblemStatusScheduled, PublishAt: &publish}, publish.Add(week), true},
+		{"draft", models.Problem{Status: models.ProblemStatusDraft, VotingClosesAt: &closes}, closes, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.problem.CreatedAt = created
+			got, ok := SettledAt(tt.problem, week)
+			if ok != tt.wantOK || (ok && !got.Equal(tt.want)) {
+				t.Errorf("SettledAt = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMedian(t *testing.T) {
+	if got := median([]int{70, 10, 50}); got != 50 {
+		t.Errorf("median = %v, want 50", got)
+	}
+	if got := median([]int{80, 10, 40, 50}); got != 45 {
+		t.Errorf("median = %v, want 45", got)
+	}
+}
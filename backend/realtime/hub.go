@@ -0,0 +1,78 @@
+// Package realtime は投票の更新を結果画面にすぐ届けるための、プロセス内の pub/sub です。
+//
+// 通知は「更新があった」ことだけを伝え、中身は受け取った側が集計し直す。
+// 購読者ごとに未処理の通知は1つまでしか溜めないので、投票が続いても1回にまとまる。
+// サーバーを複数台で動かす場合、同じインスタンスに来た投票しか通知されない。
+package realtime
+
+import "sync"
+
+// 問題ごとの購読を管理する
+type Hub struct {
+	mu   sync.Mutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+// 購読1つ分 (C に通知が届く。使い終わったら Close する)
+type Subscription struct {
+	C <-chan struct{}
+
+	c    chan struct{}
+	hub  *Hub
+	key  uint
+	once sync.Once
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[uint]map[*Subscription]struct{}{}}
+}
+
+// 集計結果の更新 (キーは問題ID、CastVote が投票のたびに Publish する)
+var Results = NewHub()
+
+// key の更新を購読する
+func (h *Hub) Subscribe(key uint) *Subscription {
+	c := make(chan struct{}, 1)
+	s := &Subscription{C: c, c: c, hub: h, key: key}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[key] == nil {
+		h.subs[key] = map[*Subscription]struct{}{}
+	}
+	h.subs[key][s] = struct{}{}
+	return s
+}
+
+// 購読をやめる (何回呼んでもよい)
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subs[s.key], s)
+		if len(h.subs[s.key]) == 0 {
+			delete(h.subs, s.key)
+		}
+	})
+}
+
+// key の購読者に更新を知らせる
+// 受け取り側が前の通知をまだ処理していなければ、その通知にまとめる (ブロックしない)
+func (h *Hub) Publish(key uint) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[key] {
+		select {
+		case s.c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// key の購読者数
+func (h *Hub) Subscribers(key uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[key])
+}
@@ -0,0 +1,58 @@
+package realtime
+
+import "testing"
+
+func TestPublishCoalesces(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(1)
+	defer s.Close()
+
+	// 受け取る前に何回通知しても、溜まるのは1回分
+	for i := 0; i < 5; i++ {
+		h.Publish(1)
+	}
+	<-s.C
+	select {
+	case <-s.C:
+		t.Error("notifications were not coalesced")
+	default:
+	}
+}
+
+func TestPublishOnlyToKey(t *testing.T) {
+	h := NewHub()
+	a, b := h.Subscribe(1), h.Subscribe(2)
+	defer a.Close()
+	defer b.Close()
+
+	h.Publish(1)
+	select {
+	case <-a.C:
+	default:
+		t.Error("subscriber of key 1 was not notified")
+	}
+	select {
+	case <-b.C:
+		t.Error("subscriber of key 2 was notified")
+	default:
+	}
+}
+
+func TestClose(t *testing.T) {
+	h := NewHub()
+	a, b := h.Subscribe(1), h.Subscribe(1)
+	if n := h.Subscribers(1); n != 2 {
+		t.Fatalf("subscribers = %d, want 2", n)
+	}
+	a.Close()
+	a.Close() // 2回目は何もしない
+	if n := h.Subscribers(1); n != 1 {
+		t.Errorf("subscribers = %d, want 1", n)
+	}
+	b.Close()
+	if n := h.Subscribers(1); n != 0 {
+		t.Errorf("subscribers = %d, want 0", n)
+	}
+	// 購読者がいなくても Publish できる
+	h.Publish(1)
+}
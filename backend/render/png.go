@@ -0,0 +1,105 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// 局面を PNG にして書き出す (SNS のプレビューなど SVG を表示できないところ向け)
+// 配置は SVG と同じ。文字は組み込みのビットマップフォントを拡大して描く
+func PNG(w io.Writer, s Scene) error {
+	l := s.layout()
+	img := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(hexColor(tableColor, 0xff)), image.Point{}, draw.Src)
+	panel := image.Rect(24, 24, Width-24, 156)
+	draw.Draw(img, panel, image.NewUniform(hexColor(panelColor, 0x99)), image.Point{}, draw.Over)
+
+	for _, t := range l.Tiles {
+		tile, err := rasterizeTile(t)
+		if err != nil {
+			return err
+		}
+		at := image.Pt(int(t.X+0.5), int(t.Y+0.5))
+		draw.Draw(img, tile.Bounds().Add(at), tile, image.Point{}, draw.Over)
+	}
+	for _, t := range l.Texts {
+		drawText(img, t)
+	}
+	return png.Encode(w, img)
+}
+
+// 牌1枚を描く (横向きなら左に90度回す)
+func rasterizeTile(t placedTile) (*image.RGBA, error) {
+	w, h := int(t.W+0.5), int(t.H+0.5)
+	if t.Sideways {
+		w, h = h, w
+	}
+	tile := image.NewRGBA(image.Rect(0, 0, w, h))
+	base, face := tileArt(t.Tile)
+	for _, art := range [][]byte{base, face} {
+		if art == nil {
+			continue
+		}
+		// 牌の絵は Inkscape の独自要素を含むので、読めない要素は無視する
+		icon, err := oksvg.ReadIconStream(bytes.NewReader(art), oksvg.IgnoreErrorMode)
+		if err != nil {
+			return nil, err
+		}
+		icon.SetTarget(0, 0, float64(w), float64(h))
+		scanner := rasterx.NewScannerGV(w, h, tile, tile.Bounds())
+		icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+	}
+	if !t.Sideways {
+		return tile, nil
+	}
+
+	// 左に90度回す: (x, y) -> (y, w-1-x)
+	rotated := image.NewRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			rotated.SetRGBA(y, w-1-x, tile.RGBAAt(x, y))
+		}
+	}
+	return rotated, nil
+}
+
+// 1行の文字を描く (Y はベースライン)
+func drawText(img *image.RGBA, t placedText) {
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face, Src: image.Opaque}
+	width := d.MeasureString(t.Text).Ceil()
+	if width == 0 {
+		return
+	}
+	small := image.NewAlpha(image.Rect(0, 0, width, face.Height))
+	d.Dst = small
+	d.Dot = fixed.P(0, face.Ascent)
+	d.DrawString(t.Text)
+
+	// フォントの高さが Size になるように拡大する
+	scale := t.Size / float64(face.Height)
+	top := int(t.Y - float64(face.Ascent)*scale + 0.5)
+	dst := image.Rect(int(t.X), top, int(t.X+float64(width)*scale+0.5), top+int(t.Size+0.5))
+	mask := image.NewAlpha(image.Rect(0, 0, dst.Dx(), dst.Dy()))
+	xdraw.ApproxBiLinear.Scale(mask, mask.Bounds(), small, small.Bounds(), draw.Src, nil)
+	draw.DrawMask(img, dst, image.NewUniform(hexColor(t.Color, 0xff)), image.Point{}, mask, image.Point{}, draw.Over)
+}
+
+// "#rrggbb" を色にする
+func hexColor(s string, alpha uint8) color.Color {
+	v, _ := strconv.ParseUint(s[1:], 16, 32)
+	c := color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: alpha}
+	return c
+}
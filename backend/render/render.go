@@ -0,0 +1,260 @@
+// Package render は問題の局面 (手牌・副露・ドラ表示牌・状況) を画像にする処理です。
+//
+// 牌の絵はフロントエンドの /tiles と同じものを tiles/ に埋め込んで使う。
+// 配置は layout でまとめて決め、SVG と PNG はそれを描くだけにする。
+// 大きさは Open Graph の推奨サイズ (1200x630) に合わせる。
+package render
+
+import (
+	"embed"
+	"fmt"
+	"portfolio-backend/mahjong"
+	"strings"
+)
+
+//go:embed tiles/*.svg
+var tileFS embed.FS
+
+// 画像の大きさ
+const (
+	Width  = 1200
+	Height = 630
+)
+
+// 描く局面
+type Scene struct {
+	Hand     []int          // 手牌
+	Melds    []mahjong.Meld // 自分の副露 (From は自分から見た位置)
+	Dora     []int          // ドラ表示牌
+	Wind     int            // 場風 (0 東, 1 南, ...)
+	Number   int            // 何局目か (1-4)
+	SeatWind int            // 自風
+	Score    int            // 持ち点
+	Riichi   bool           // 他家からリーチを受けているか
+}
+
+// 裏向きの牌
+const back = -1
+
+// 風の名前 (PNG の文字は ASCII しか出せないので英語で書く)
+var windNames = []string{"East", "South", "West", "North"}
+
+// 字牌の絵のファイル名 (東南西北白發中、フロントエンドの getTileImage と同じ)
+var honorFiles = []string{"Ton", "Nan", "Shaa", "Pei", "Haku", "Hatsu", "Chun"}
+
+// 牌の絵のファイル名 (裏向きは Back.svg)
+func tileFile(tile int) string {
+	switch {
+	case tile == back || tile < 0 || tile >= mahjong.NumTileKinds:
+		return "tiles/Back.svg"
+	case tile < 9:
+		return fmt.Sprintf("tiles/Man%d.svg", tile+1)
+	case tile < 18:
+		return fmt.Sprintf("tiles/Pin%d.svg", tile-9+1)
+	case tile < 27:
+		return fmt.Sprintf("tiles/Sou%d.svg", tile-18+1)
+	default:
+		return "tiles/" + honorFiles[tile-mahjong.East] + ".svg"
+	}
+}
+
+// 牌の絵を読む
+// 表向きの牌は絵柄だけのファイルなので、下地 (Front.svg) と2枚重ねて描く
+func tileArt(tile int) (base, face []byte) {
+	if tile == back || tile < 0 || tile >= mahjong.NumTileKinds {
+		face, _ = tileFS.ReadFile("tiles/Back.svg")
+		return nil, face
+	}
+	base, _ = tileFS.ReadFile("tiles/Front.svg")
+	face, _ = tileFS.ReadFile(tileFile(tile))
+	return base, face
+}
+
+// 配置した牌1枚分
+// Sideways は横向き (鳴いた牌)。X, Y, W, H は横向きにした後の外形
+type placedTile struct {
+	Tile       int
+	X, Y, W, H float64
+	Sideways   bool
+}
+
+// 配置した文字1行分 (Y はベースライン)
+type placedText struct {
+	Text  string
+	X, Y  float64
+	Size  float64
+	Color string
+}
+
+// 画像全体の配置
+type layout struct {
+	Tiles []placedTile
+	Texts []placedText
+}
+
+// 牌の縦横比 (絵は 300x400)
+const tileAspect = 4.0 / 3.0
+
+// ドラ表示牌の枠の数 (足りない分は裏向きで埋める)
+const doraSlots = 5
+
+// 色
+const (
+	tableColor  = "#15803d"
+	panelColor  = "#0b3d1e"
+	textColor   = "#ffffff"
+	doraColor   = "#facc15"
+	riichiColor = "#f87171"
+)
+
+const margin = 48.0
+
+// 局面の配置を決める
+func (s Scene) layout() layout {
+	var l layout
+
+	// 状況 (例: "East 1 / Seat: South / 25,000 pts")
+	l.Texts = append(l.Texts, placedText{Text: s.situation(), X: margin, Y: 84, Size: 36, Color: textColor})
+	if s.Riichi {
+		l.Texts = append(l.Texts, placedText{Text: "Opponent riichi", X: margin, Y: 128, Size: 28, Color: riichiColor})
+	}
+
+	// ドラ表示牌 (槓ドラがなければ裏向きで埋める)
+	l.Texts = append(l.Texts, placedText{Text: "DORA", X: margin, Y: 222, Size: 28, Color: doraColor})
+	doraW := 48.0
+	x := margin + 100
+	for i := 0; i < doraSlots || i < len(s.Dora); i++ {
+		tile := back
+		if i < len(s.Dora) {
+			tile = s.Dora[i]
+		}
+		l.Tiles = append(l.Tiles, placedTile{Tile: tile, X: x, Y: 172, W: doraW, H: doraW * tileAspect})
+		x += doraW + 4
+	}
+
+	// 手牌と副露は下にそろえて横一列に並べる
+	// 全部が収まるように牌の大きさを決める (副露が多いと小さくなる)
+	const gap = 32.0
+	units := float64(len(s.Hand))
+	for _, m := range s.Melds {
+		units += float64(len(meldOrder(m))) + (tileAspect-1)*float64(sidewaysCount(m))
+	}
+	tileW := 72.0
+	if available := Width - 2*margin - gap*float64(len(s.Melds)); units*tileW > available {
+		tileW = available / units
+	}
+	tileH := tileW * tileAspect
+	bottom := 520.0
+
+	x = margin
+	for _, t := range s.Hand {
+		l.Tiles = append(l.Tiles, placedTile{Tile: t, X: x, Y: bottom - tileH, W: tileW, H: tileH})
+		x += tileW
+	}
+	for _, m := range s.Melds {
+		x += gap
+		for _, p := range meldOrder(m) {
+			if !p.sideways {
+				l.Tiles = append(l.Tiles, placedTile{Tile: p.tile, X: x, Y: bottom - tileH, W: tileW, H: tileH})
+				x += tileW
+				continue
+			}
+			// 横向きの牌は下にそろえ、加槓の牌はその上に重ねる
+			l.Tiles = append(l.Tiles, placedTile{Tile: p.tile, X: x, Y: bottom - tileW, W: tileH, H: tileW, Sideways: true})
+			if p.added >= 0 {
+				l.Tiles = append(l.Tiles, placedTile{Tile: p.added, X: x, Y: bottom - 2*tileW, W: tileH, H: tileW, Sideways: true})
+			}
+			x += tileH
+		}
+	}
+	return l
+}
+
+// 状況の1行
+func (s Scene) situation() string {
+	round := fmt.Sprintf("%s %d", windName(s.Wind), s.Number)
+	return fmt.Sprintf("%s / Seat: %s / %s pts", round, windName(s.SeatWind), formatScore(s.Score))
+}
+
+func windName(w int) string {
+	if w < 0 || w >= len(windNames) {
+		return "?"
+	}
+	return windNames[w]
+}
+
+// 3桁ごとにカンマを入れる (例: 25000 -> "25,000")
+func formatScore(n int) string {
+	s := fmt.Sprint(n)
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + s
+}
+
+// 副露の牌1枚分の描き方
+type meldTile struct {
+	tile     int
+	sideways bool
+	added    int // 加槓で横向きの牌に重ねる牌 (なければ -1)
+}
+
+// 副露の牌を並べる順にする
+// 鳴いた牌は鳴いた相手の方向 (上家なら左、対面なら真ん中、下家なら右) に横向きで置く。
+// 暗槓は両端を裏向きにする
+func meldOrder(m mahjong.Meld) []meldTile {
+	if m.Type == mahjong.MeldAnkan {
+		order := make([]meldTile, len(m.Tiles))
+		for i, t := range m.Tiles {
+			order[i] = meldTile{tile: t, added: -1}
+		}
+		order[0].tile, order[len(order)-1].tile = back, back
+		return order
+	}
+
+	// 鳴いた牌 (加槓なら元のポンで鳴いた牌) を1枚取り除く
+	rest := append([]int(nil), m.Tiles...)
+	called, added := m.Called, -1
+	if m.Type == mahjong.MeldKakan {
+		added = m.Called
+	}
+	for i, t := range rest {
+		if t == called {
+			rest = append(rest[:i], rest[i+1:]...)
+			break
+		}
+	}
+	if added >= 0 && len(rest) > 0 {
+		// 加槓は同じ牌4枚なので、もう1枚を重ねる方に回す
+		rest = rest[1:]
+	}
+
+	var order []meldTile
+	for _, t := range rest {
+		order = append(order, meldTile{tile: t, added: -1})
+	}
+	at := 0 // 上家
+	switch m.From {
+	case 2: // 対面
+		at = 1
+	case 1: // 下家
+		at = len(order)
+	}
+	if at > len(order) {
+		at = len(order)
+	}
+	order = append(order[:at], append([]meldTile{{tile: called, sideways: true, added: added}}, order[at:]...)...)
+	return order
+}
+
+// 副露のうち横向きに置く牌の数
+func sidewaysCount(m mahjong.Meld) int {
+	if m.Type == mahjong.MeldAnkan {
+		return 0
+	}
+	return 1
+}
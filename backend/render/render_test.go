@@ -0,0 +1,136 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"portfolio-backend/mahjong"
+	"strings"
+	"testing"
+)
+
+func sampleScene() Scene {
+	return Scene{
+		Hand: []int{0, 1, 2, 9, 10, 11, 18, 19, 20, 27, 27},
+		Melds: []mahjong.Meld{
+			{Type: mahjong.MeldPon, Tiles: []int{33, 33, 33}, Called: 33, From: 2},
+		},
+		Dora:     []int{4},
+		Wind:     0,
+		Number:   1,
+		SeatWind: 1,
+		Score:    25000,
+		Riichi:   true,
+	}
+}
+
+func TestTileFile(t *testing.T) {
+	tests := map[int]string{
+		0:    "tiles/Man1.svg",
+		17:   "tiles/Pin9.svg",
+		22:   "tiles/Sou5.svg",
+		29:   "tiles/Shaa.svg",
+		33:   "tiles/Chun.svg",
+		back: "tiles/Back.svg",
+	}
+	for tile, want := range tests {
+		if got := tileFile(tile); got != want {
+			t.Errorf("tileFile(%d) = %s, want %s", tile, got, want)
+		}
+	}
+	// 全ての牌の絵が埋め込まれているか
+	for tile := back; tile < mahjong.NumTileKinds; tile++ {
+		if _, err := tileFS.ReadFile(tileFile(tile)); err != nil {
+			t.Errorf("tile %d: %v", tile, err)
+		}
+	}
+}
+
+func TestMeldOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		meld     mahjong.Meld
+		tiles    []int
+		sideways int // 横向きの牌の位置
+	}{
+		{"chi from left", mahjong.Meld{Type: mahjong.MeldChi, Tiles: []int{2, 3, 4}, Called: 3, From: 3}, []int{3, 2, 4}, 0},
+		{"pon from across", mahjong.Meld{Type: mahjong.MeldPon, Tiles: []int{5, 5, 5}, Called: 5, From: 2}, []int{5, 5, 5}, 1},
+		{"daiminkan from right", mahjong.Meld{Type: mahjong.MeldDaiminkan, Tiles: []int{9, 9, 9, 9}, Called: 9, From: 1}, []int{9, 9, 9, 9}, 3},
+		{"kakan", mahjong.Meld{Type: mahjong.MeldKakan, Tiles: []int{27, 27, 27, 27}, Called: 27, From: 3}, []int{27, 27, 27}, 0},
+		{"ankan", mahjong.Meld{Type: mahjong.MeldAnkan, Tiles: []int{31, 31, 31, 31}, Called: -1}, []int{back, 31, 31, back}, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := meldOrder(tt.meld)
+			if len(order) != len(tt.tiles) {
+				t.Fatalf("got %d tiles, want %d", len(order), len(tt.tiles))
+			}
+			for i, p := range order {
+				if p.tile != tt.tiles[i] {
+					t.Errorf("tile %d = %d, want %d", i, p.tile, tt.tiles[i])
+				}
+				if p.sideways != (i == tt.sideways) {
+					t.Errorf("tile %d sideways = %v", i, p.sideways)
+				}
+			}
+			if tt.meld.Type == mahjong.MeldKakan && order[tt.sideways].added != 27 {
+				t.Errorf("kakan added tile = %d, want 27", order[tt.sideways].added)
+			}
+		})
+	}
+}
+
+func TestLayoutFitsImage(t *testing.T) {
+	// 槓を4回した手牌でもはみ出さない
+	kan := func(tile, from int) mahjong.Meld {
+		return mahjong.Meld{Type: mahjong.MeldDaiminkan, Tiles: []int{tile, tile, tile, tile}, Called: tile, From: from}
+	}
+	s := Scene{
+		Hand:  []int{33, 33},
+		Melds: []mahjong.Meld{kan(0, 1), kan(9, 2), kan(18, 3), kan(27, 1)},
+		Dora:  []int{1, 2, 3, 4, 5},
+	}
+	for _, p := range s.layout().Tiles {
+		if p.X < 0 || p.Y < 0 || p.X+p.W > Width || p.Y+p.H > Height {
+			t.Errorf("tile %d is outside the image: %+v", p.Tile, p)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	svg := string(SVG(sampleScene()))
+	for _, want := range []string{
+		`width="1200" height="630"`,
+		"East 1 / Seat: South / 25,000 pts",
+		"Opponent riichi",
+		`id="tile-33"`,
+		`id="tile-back"`,
+		"rotate(-90)",
+	} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("SVG does not contain %q", want)
+		}
+	}
+	// 同じ牌の絵は1回だけ埋め込む
+	if n := strings.Count(svg, `id="tile-27"`); n != 1 {
+		t.Errorf("tile 27 is defined %d times", n)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := PNG(&buf, sampleScene()); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != Width || b.Dy() != Height {
+		t.Errorf("size = %dx%d, want %dx%d", b.Dx(), b.Dy(), Width, Height)
+	}
+	// 手牌のあたりは卓の色ではなく牌が描かれている
+	r, g, b, _ := img.At(int(margin)+10, 500).RGBA()
+	if r>>8 == 0x15 && g>>8 == 0x80 && b>>8 == 0x3d {
+		t.Error("hand tiles are not drawn")
+	}
+}
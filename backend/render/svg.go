@@ -0,0 +1,62 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+)
+
+// 局面を SVG にする
+// 牌の絵は data URI で埋め込むので、この1ファイルだけで表示できる
+func SVG(s Scene) []byte {
+	l := s.layout()
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", Width, Height, Width, Height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", Width, Height, tableColor)
+	fmt.Fprintf(&b, `<rect x="24" y="24" width="%d" height="132" rx="12" fill="%s" fill-opacity="0.6"/>`+"\n", Width-48, panelColor)
+
+	// 同じ牌の絵は <defs> に1回だけ入れて <use> で使い回す
+	b.WriteString("<defs>\n")
+	defined := map[int]bool{}
+	for _, t := range l.Tiles {
+		if defined[t.Tile] {
+			continue
+		}
+		defined[t.Tile] = true
+		base, face := tileArt(t.Tile)
+		fmt.Fprintf(&b, `<g id="%s">`, tileID(t.Tile))
+		for _, art := range [][]byte{base, face} {
+			if art != nil {
+				fmt.Fprintf(&b, `<image width="300" height="400" href="data:image/svg+xml;base64,%s"/>`, base64.StdEncoding.EncodeToString(art))
+			}
+		}
+		b.WriteString("</g>\n")
+	}
+	b.WriteString("</defs>\n")
+
+	for _, t := range l.Tiles {
+		// 絵は 300x400 なので縮めて置く。横向きは左に90度回して下にずらす
+		if t.Sideways {
+			scale := t.H / 300
+			fmt.Fprintf(&b, `<use href="#%s" transform="translate(%.2f %.2f) rotate(-90) scale(%.4f)"/>`+"\n", tileID(t.Tile), t.X, t.Y+t.H, scale)
+		} else {
+			scale := t.W / 300
+			fmt.Fprintf(&b, `<use href="#%s" transform="translate(%.2f %.2f) scale(%.4f)"/>`+"\n", tileID(t.Tile), t.X, t.Y, scale)
+		}
+	}
+	for _, t := range l.Texts {
+		fmt.Fprintf(&b, `<text x="%.2f" y="%.2f" font-family="sans-serif" font-weight="bold" font-size="%.0f" fill="%s">%s</text>`+"\n",
+			t.X, t.Y, t.Size, t.Color, html.EscapeString(t.Text))
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+// <defs> に入れる牌の絵の id
+func tileID(tile int) string {
+	if tile == back {
+		return "tile-back"
+	}
+	return fmt.Sprintf("tile-%d", tile)
+}
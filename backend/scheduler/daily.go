@@ -0,0 +1,168 @@
+// Package scheduler は「今日の一問」を毎日決まった時刻に公開するバックグラウンド処理です。
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"portfolio-backend/database"
+	"portfolio-backend/models"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Alpine などタイムゾーン情報のない環境でも Asia/Tokyo を使えるように
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 日付の表記 (DailyProblem.Date)
+const DateLayout = "2006-01-02"
+
+// 公開時刻の既定値 (環境変数 DAILY_PUBLISH_TIME で変更可)
+const defaultPublishTime = "07:00"
+
+// 公開待ちのキューが空
+var ErrQueueEmpty = errors.New("daily queue is empty")
+
+// 時刻 (時:分)
+type Clock struct {
+	Hour, Minute int
+}
+
+// "07:00" のような文字列を読む
+func ParseClock(s string) (Clock, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return Clock{}, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return Clock{}, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return Clock{Hour: hour, Minute: minute}, nil
+}
+
+// now より後で、最初に at になる時刻 (now のタイムゾーンで判定)
+func NextRun(now time.Time, at Clock) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour, at.Minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = time.Date(now.Year(), now.Month(), now.Day()+1, at.Hour, at.Minute, 0, 0, now.Location())
+	}
+	return next
+}
+
+// 今日の公開時刻を過ぎているか
+func IsDue(now time.Time, at Clock) bool {
+	today := time.Date(now.Year(), now.Month(), now.Day(), at.Hour, at.Minute, 0, 0, now.Location())
+	return !now.Before(today)
+}
+
+// アプリのタイムゾーン (database.TimeZone)
+func Location() *time.Location {
+	loc, err := time.LoadLocation(database.TimeZone)
+	if err != nil {
+		log.Printf("Failed to load time zone %s: %v", database.TimeZone, err)
+		return time.Local
+	}
+	return loc
+}
+
+// アプリのタイムゾーンでの今日の日付
+func Today() string {
+	return time.Now().In(Location()).Format(DateLayout)
+}
+
+// 毎日の公開処理をバックグラウンドで開始する
+// 起動時点で今日の公開時刻を過ぎていれば、取りこぼし分をすぐに公開する
+func StartDaily() {
+	setting := os.Getenv("DAILY_PUBLISH_TIME")
+	if setting == "" {
+		setting = defaultPublishTime
+	}
+	at, err := ParseClock(setting)
+	if err != nil {
+		log.Printf("Daily scheduler disabled: %v", err)
+		return
+	}
+	loc := Location()
+
+	go func() {
+		now := time.Now().In(loc)
+		if IsDue(now, at) {
+			runDaily(now)
+		}
+		for {
+			next := NextRun(time.Now().In(loc), at)
+			time.Sleep(time.Until(next))
+			runDaily(next)
+		}
+	}()
+	fmt.Printf("📅 Daily problem scheduler started (%02d:%02d %s)\n", at.Hour, at.Minute, loc)
+}
+
+func runDaily(now time.Time) {
+	daily, err := PublishNext(now.Format(DateLayout))
+	switch {
+	case errors.Is(err, ErrQueueEmpty):
+		log.Printf("Daily problem for %s: queue is empty", now.Format(DateLayout))
+	case err != nil:
+		log.Printf("Failed to publish daily problem: %v", err)
+	case daily != nil:
+		fmt.Printf("📅 Published daily problem #%d for %s\n", daily.ProblemID, daily.Date)
+	}
+}
+
+// 指定日の一問をキューから公開する
+// 既にその日の一問があれば何もしない (nil, nil)。複数台で動いても二重に公開しない
+func PublishNext(date string) (*models.DailyProblem, error) {
+	var daily *models.DailyProblem
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.DailyProblem{}).Where("date = ?", date).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+
+		for {
+			// 承認済みの先頭を取る (他のインスタンスが処理中の行は飛ばす)
+			var entry models.DailyQueueEntry
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("approved_at IS NOT NULL").
+				Order("position, id").
+				First(&entry).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrQueueEmpty
+			}
+			if err != nil {
+				return err
+			}
+
+			published := tx.Model(&models.Problem{}).Where("id = ?", entry.ProblemID).Updates(map[string]interface{}{
+				"status":     models.ProblemStatusPublished,
+				"publish_at": time.Now(),
+			})
+			if published.Error != nil {
+				return published.Error
+			}
+			if published.RowsAffected == 0 {
+				// キューに入れたあとで問題が削除された (マージされたなど)。外して次の問題にする
+				if err := tx.Delete(&entry).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			daily = &models.DailyProblem{Date: date, ProblemID: entry.ProblemID}
+			if err := tx.Create(daily).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&entry).Error
+		}
+	})
+	return daily, err
+}
@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseClock(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    Clock
+		wantErr bool
+	}{
+		{input: "07:00", want: Clock{Hour: 7, Minute: 0}},
+		{input: "23:59", want: Clock{Hour: 23, Minute: 59}},
+		{input: " 9:05 ", want: Clock{Hour: 9, Minute: 5}},
+		{input: "24:00", wantErr: true},
+		{input: "07:60", wantErr: true},
+		{input: "0700", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseClock(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("Failed to load Asia/Tokyo: %v", err)
+	}
+	at := Clock{Hour: 7, Minute: 0}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "before publish time",
+			now:  time.Date(2026, 3, 1, 6, 59, 0, 0, tokyo),
+			want: time.Date(2026, 3, 1, 7, 0, 0, 0, tokyo),
+		},
+		{
+			name: "exactly at publish time runs tomorrow",
+			now:  time.Date(2026, 3, 1, 7, 0, 0, 0, tokyo),
+			want: time.Date(2026, 3, 2, 7, 0, 0, 0, tokyo),
+		},
+		{
+			name: "end of month",
+			now:  time.Date(2026, 3, 31, 22, 0, 0, 0, tokyo),
+			want: time.Date(2026, 4, 1, 7, 0, 0, 0, tokyo),
+		},
+		{
+			name: "UTC input is judged in its own zone",
+			now:  time.Date(2026, 3, 1, 21, 30, 0, 0, time.UTC).In(tokyo), // 3/2 06:30 JST
+			want: time.Date(2026, 3, 2, 7, 0, 0, 0, tokyo),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NextRun(tt.now, at)
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	at := Clock{Hour: 7, Minute: 0}
+
+	if IsDue(time.Date(2026, 3, 1, 6, 59, 59, 0, tokyo), at) {
+		t.Error("Expected not due before 07:00")
+	}
+	if !IsDue(time.Date(2026, 3, 1, 7, 0, 0, 0, tokyo), at) {
+		t.Error("Expected due at 07:00")
+	}
+	if !IsDue(time.Date(2026, 3, 1, 23, 0, 0, 0, tokyo), at) {
+		t.Error("Expected due later in the day")
+	}
+}
@@ -3,6 +3,7 @@
 import { useState } from 'react';
 import { useRouter } from 'next/navigation';
 import { getTileImage } from '@/utils/mahjong';
+import { authHeaders } from '@/utils/auth';
 
 export default function CreateProblem() {
   const router = useRouter();
@@ -65,7 +66,7 @@ export default function CreateProblem() {
     try {
       const res = await fetch('http://localhost:8080/problems', {
         method: 'POST',
-        headers: { 'Content-Type': 'application/json' },
+        headers: { 'Content-Type': 'application/json', ...authHeaders() },
         body: JSON.stringify(payload),
       });
 
@@ -3,6 +3,7 @@
 import { useEffect, useState } from 'react';
 import Link from 'next/link';
 import { useRouter } from 'next/navigation';
+import { authHeaders } from '@/utils/auth';
 
 type Problem = {
   ID: number;
@@ -34,7 +35,10 @@ export default function AdminDashboard() {
   }, [router]);
 
   const fetchProblems = async () => {
-    const res = await fetch('http://localhost:8080/problems');
+    // 管理者は下書き・予約公開の問題も含めて取得する
+    const res = await fetch('http://localhost:8080/problems', {
+      headers: authHeaders(),
+    });
     if (res.ok) setProblems(await res.json());
   };
 
@@ -43,6 +47,7 @@ export default function AdminDashboard() {
     
     await fetch(`http://localhost:8080/problems/${id}`, {
       method: 'DELETE',
+      headers: authHeaders(),
     });
     // 画面から消す
     setProblems(problems.filter(p => p.ID !== id));
@@ -4,6 +4,7 @@ import { useEffect, useState } from 'react';
 import Link from 'next/link';
 import { useParams } from 'next/navigation';
 import { useRouter } from 'next/navigation';
+import { authHeaders } from '@/utils/auth';
 
 type Vote = {
   ID: number;
@@ -48,7 +49,9 @@ export default function UserDetailPage() {
 
   const fetchUserVotes = async () => {
     try {
-      const res = await fetch(`http://localhost:8080/users/${userId}/votes`);
+      const res = await fetch(`http://localhost:8080/users/${userId}/votes`, {
+        headers: authHeaders(),
+      });
       if (res.ok) {
         setStats(await res.json());
       }
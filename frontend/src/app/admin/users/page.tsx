@@ -3,6 +3,7 @@
 import { useEffect, useState } from 'react';
 import Link from 'next/link';
 import { useRouter } from 'next/navigation';
+import { authHeaders } from '@/utils/auth';
 
 type User = {
   ID: number;
@@ -37,7 +38,9 @@ export default function AdminUsersPage() {
 
   const fetchUsers = async () => {
     try {
-      const res = await fetch('http://localhost:8080/users');
+      const res = await fetch('http://localhost:8080/users', {
+        headers: authHeaders(),
+      });
       if (res.ok) {
         setUsers(await res.json());
       }
@@ -54,6 +57,7 @@ export default function AdminUsersPage() {
     try {
       await fetch(`http://localhost:8080/users/${id}`, {
         method: 'DELETE',
+        headers: authHeaders(),
       });
       setUsers(users.filter(u => u.ID !== id));
     } catch (error) {
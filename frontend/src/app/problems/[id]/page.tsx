@@ -2,6 +2,7 @@
 
 import { useEffect, useState } from 'react';
 import { getTileImage } from '@/utils/mahjong';
+import { authHeaders } from '@/utils/auth';
 import { useParams } from 'next/navigation';
 import { useRouter } from 'next/navigation';
 
@@ -26,6 +27,8 @@ export default function ProblemDetail() {
   
   // 評価点のstate
   const [rating, setRating] = useState<number>(50);
+  // 点数の理由 (任意)
+  const [reason, setReason] = useState<string>('');
 
   useEffect(() => {
     if (params.id) {
@@ -53,28 +56,32 @@ export default function ProblemDetail() {
   };
 
   const handleVote = async () => {
-      // ログインユーザーIDを取得
-      const userStr = localStorage.getItem('user');
-      if (!userStr) {
+      // 投票者はサーバー側でトークンから決まる
+      if (!localStorage.getItem('user')) {
         alert('ログインしてください');
         router.push('/login');
         return;
       }
-      const user = JSON.parse(userStr);
-      
+
       const voteData = {
         problem_id: problem?.ID,
-        user_id: user.ID,
         point: rating,
+        reason: reason,
       };
 
       try {
         const res = await fetch('http://localhost:8080/votes', {
           method: 'POST',
-          headers: { 'Content-Type': 'application/json' },
+          headers: { 'Content-Type': 'application/json', ...authHeaders() },
           body: JSON.stringify(voteData),
         });
 
+        // 投票済み (409) なら投票は変えられないので、そのまま結果を見る
+        if (res.status === 409) {
+          alert('この問題には投票済みです');
+          router.push(`/problems/${params.id}/result`);
+          return;
+        }
         if (!res.ok) throw new Error('Vote failed');
 
         // 結果ページへ遷移 (自分のスコアをクエリで渡す)
@@ -163,6 +170,16 @@ export default function ProblemDetail() {
             <span>100 (神配牌)</span>
           </div>
 
+          {/* 理由 (任意) */}
+          <textarea
+            value={reason}
+            onChange={(e) => setReason(e.target.value)}
+            maxLength={1000}
+            rows={3}
+            placeholder="点数の理由 (任意)"
+            className="mt-4 w-full p-2 rounded-lg bg-gray-800 text-white text-sm placeholder-gray-400"
+          />
+
           {/* 投票ボタン */}
           <button
             className="mt-6 bg-yellow-500 hover:bg-yellow-400 ..."
@@ -1,8 +1,9 @@
 'use client';
 
 import { useEffect, useState } from 'react';
-import { useParams, useSearchParams } from 'next/navigation';
+import { useParams } from 'next/navigation';
 import Link from 'next/link';
+import { authHeaders } from '@/utils/auth';
 // グラフ描画用コンポーネント
 import {
   BarChart, Bar, XAxis, YAxis, CartesianGrid, Tooltip, ResponsiveContainer, ReferenceLine
@@ -19,21 +20,21 @@ type ResultData = {
 
 export default function ResultPage() {
   const params = useParams(); // URLのID (problems/1/result の "1")
-  const searchParams = useSearchParams(); // クエリ (?score=80)
-  const myScore = searchParams.get('score');
 
   const [data, setData] = useState<ResultData | null>(null);
 
   useEffect(() => {
-    if (params.id && myScore) {
+    if (params.id) {
       fetchResult();
     }
-  }, [params.id, myScore]);
+  }, [params.id]);
 
   const fetchResult = async () => {
     try {
-      // BackendのAPIを叩く
-      const res = await fetch(`http://localhost:8080/results?problem_id=${params.id}&my_score=${myScore}`);
+      // BackendのAPIを叩く (自分の点数はサーバー側で実際の投票から取る)
+      const res = await fetch(`http://localhost:8080/problems/${params.id}/result`, {
+        headers: authHeaders(),
+      });
       if (!res.ok) throw new Error('Failed to fetch results');
       const json = await res.json();
       setData(json);
@@ -0,0 +1,7 @@
+// ログイン時に保存したトークンを Authorization ヘッダーにする
+export const authHeaders = (): Record<string, string> => {
+  const storedUser = localStorage.getItem('user');
+  if (!storedUser) return {};
+  const user = JSON.parse(storedUser);
+  return user.token ? { Authorization: `Bearer ${user.token}` } : {};
+};